@@ -0,0 +1,80 @@
+package completion
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/root"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdLong = templates.LongDesc(`
+		Generates the shell completion script for the given shell
+`)
+
+	cmdExample = templates.Examples(`
+		# load completions in the current bash shell
+		source <(%s completion bash)
+
+		# load completions for each new zsh session
+		%s completion zsh > "${fpath[1]}/_%s"
+	`)
+
+	shells = []string{"bash", "zsh", "fish", "powershell"}
+)
+
+// Options the options for the command
+type Options struct {
+	Shell string
+	Root  *cobra.Command
+	Out   io.Writer
+}
+
+// NewCmdCompletion creates a command object for the command
+func NewCmdCompletion() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:       "completion [bash|zsh|fish|powershell]",
+		Short:     "Generates the shell completion script",
+		Long:      cmdLong,
+		Example:   fmt.Sprintf(cmdExample, root.BinaryName, root.BinaryName, root.BinaryName),
+		ValidArgs: shells,
+		Args:      cobra.ExactValidArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			o.Shell = args[0]
+			o.Root = cmd.Root()
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+	return cmd, o
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	if o.Root == nil {
+		return errors.Errorf("no root command to generate the completion for")
+	}
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	switch o.Shell {
+	case "bash":
+		return o.Root.GenBashCompletion(o.Out)
+	case "zsh":
+		return o.Root.GenZshCompletion(o.Out)
+	case "fish":
+		return o.Root.GenFishCompletion(o.Out, true)
+	case "powershell":
+		return o.Root.GenPowerShellCompletion(o.Out)
+	default:
+		return options.InvalidOption("shell", o.Shell, shells)
+	}
+}
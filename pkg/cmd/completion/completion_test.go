@@ -0,0 +1,41 @@
+package completion_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/completion"
+	"github.com/jenkins-x-plugins/jx-test/pkg/root"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletion(t *testing.T) {
+	rootCmd := &cobra.Command{
+		Use: root.TopLevelCommand,
+	}
+	cmd, _ := completion.NewCmdCompletion()
+	rootCmd.AddCommand(cmd)
+
+	for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+		out := &bytes.Buffer{}
+		_, o := completion.NewCmdCompletion()
+		o.Shell = shell
+		o.Root = rootCmd
+		o.Out = out
+
+		err := o.Run()
+		require.NoError(t, err, "failed to generate %s completion", shell)
+		assert.Contains(t, out.String(), root.BinaryName, "%s completion should mention the binary name", shell)
+	}
+}
+
+func TestCompletionInvalidShell(t *testing.T) {
+	_, o := completion.NewCmdCompletion()
+	o.Shell = "tcsh"
+	o.Root = &cobra.Command{Use: root.TopLevelCommand}
+
+	err := o.Run()
+	assert.Error(t, err, "should fail for an unsupported shell")
+}
@@ -0,0 +1,44 @@
+package gc
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// HumanizeDuration formats the duration using its two most significant units such as 45s, 5m30s, 3h20m or 3d4h
+// so that ages are easier to read in the logs than a full timestamp. Negative durations are formatted as 0s
+func HumanizeDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	seconds := int64(d / time.Second)
+	days := seconds / (24 * 60 * 60)
+	hours := seconds / (60 * 60) % 24
+	minutes := seconds / 60 % 60
+	seconds %= 60
+
+	switch {
+	case days > 0:
+		return humanizeUnits(days, "d", hours, "h")
+	case hours > 0:
+		return humanizeUnits(hours, "h", minutes, "m")
+	case minutes > 0:
+		return humanizeUnits(minutes, "m", seconds, "s")
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
+func humanizeUnits(major int64, majorUnit string, minor int64, minorUnit string) string {
+	if minor == 0 {
+		return fmt.Sprintf("%d%s", major, majorUnit)
+	}
+	return fmt.Sprintf("%d%s%d%s", major, majorUnit, minor, minorUnit)
+}
+
+// resourceAge returns the humanized age of the resource at the given time
+func resourceAge(r *unstructured.Unstructured, now time.Time) string {
+	return HumanizeDuration(now.Sub(r.GetCreationTimestamp().Time))
+}
@@ -0,0 +1,32 @@
+package gc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHumanizeDuration(t *testing.T) {
+	testCases := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{duration: 0, expected: "0s"},
+		{duration: -5 * time.Second, expected: "0s"},
+		{duration: 1500 * time.Millisecond, expected: "1s"},
+		{duration: 45 * time.Second, expected: "45s"},
+		{duration: 5 * time.Minute, expected: "5m"},
+		{duration: 5*time.Minute + 30*time.Second, expected: "5m30s"},
+		{duration: 3 * time.Hour, expected: "3h"},
+		{duration: 3*time.Hour + 20*time.Minute + 10*time.Second, expected: "3h20m"},
+		{duration: 72 * time.Hour, expected: "3d"},
+		{duration: 76*time.Hour + 59*time.Minute, expected: "3d4h"},
+		{duration: 400 * 24 * time.Hour, expected: "400d"},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, gc.HumanizeDuration(tc.duration), "for duration %s", tc.duration.String())
+	}
+}
@@ -0,0 +1,36 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// annotateBeforeDelete records why and when the resource was selected for deletion on the resource itself so that
+// the audit information survives if the deletion fails or is blocked by a finalizer
+func (o *Options) annotateBeforeDelete(ctx context.Context, gvr schema.GroupVersionResource, r *unstructured.Unstructured, now time.Time) error {
+	client := dynkube.DynamicResource(o.DynamicClient, o.resourceNamespace(r), gvr)
+	return dynkube.SetAnnotations(ctx, client, r.GetName(), map[string]string{
+		terraforms.AnnotationGCReason: o.gcReason(r, now),
+		terraforms.AnnotationGCTime:   now.UTC().Format(time.RFC3339),
+	})
+}
+
+// gcReason returns a description of why the resource was selected for deletion
+func (o *Options) gcReason(r *unstructured.Unstructured, now time.Time) string {
+	if o.Sweep {
+		marked, ok := markedTime(r)
+		if ok {
+			return fmt.Sprintf("marked for garbage collection at %s", marked.UTC().Format(time.RFC3339))
+		}
+	}
+	created := r.GetCreationTimestamp().Time
+	age := now.Sub(created).Round(time.Second)
+	cutoff := terraforms.ResourceCutoff(r, o.cutoff(now), now)
+	return fmt.Sprintf("age %s created before the cutoff %s", age.String(), cutoff.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,139 @@
+package gc
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// checkpoint records the resources processed by a run in a file with a line per namespace/name so that an
+// interrupted run can be resumed via --resume without processing them again
+type checkpoint struct {
+	path      string
+	processed map[string]bool
+	file      *os.File
+	lock      sync.Mutex
+}
+
+// openCheckpoint opens the checkpoint file loading the previously processed resources if resuming or
+// truncating the file otherwise
+func openCheckpoint(path string, resume bool) (*checkpoint, error) {
+	c := &checkpoint{path: path, processed: map[string]bool{}}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		err := c.load()
+		if err != nil {
+			return nil, err
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	err := os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create the directory for the checkpoint file %s", path)
+	}
+	c.file, err = os.OpenFile(path, flags, files.DefaultFileWritePermissions)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open the checkpoint file %s", path)
+	}
+	return c, nil
+}
+
+func (c *checkpoint) load() error {
+	f, err := os.Open(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Logger().Infof("no checkpoint file %s found so processing all resources", info(c.path))
+			return nil
+		}
+		return errors.Wrapf(err, "failed to open the checkpoint file %s", c.path)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key := strings.TrimSpace(scanner.Text())
+		if key != "" {
+			c.processed[key] = true
+		}
+	}
+	err = scanner.Err()
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the checkpoint file %s", c.path)
+	}
+	log.Logger().Infof("resuming from the checkpoint file %s which has %d processed resources", info(c.path), len(c.processed))
+	return nil
+}
+
+// isProcessed returns true if the resource was processed by a previous run
+func (c *checkpoint) isProcessed(key string) bool {
+	return c.processed[key]
+}
+
+// record appends the processed resource to the checkpoint file. Failures are only logged as the resource would
+// just be processed again if the run is resumed
+func (c *checkpoint) record(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, err := fmt.Fprintln(c.file, key)
+	if err != nil {
+		log.Logger().Warnf("failed to write %s to the checkpoint file %s: %s", key, c.path, err.Error())
+	}
+}
+
+// close closes the checkpoint file removing it if the run completed
+func (c *checkpoint) close(completed bool) {
+	err := c.file.Close()
+	if err != nil {
+		log.Logger().Warnf("failed to close the checkpoint file %s: %s", c.path, err.Error())
+	}
+	if !completed {
+		log.Logger().Infof("the run did not complete so it can be resumed from the checkpoint file %s via --resume", info(c.path))
+		return
+	}
+	err = os.Remove(c.path)
+	if err != nil && !os.IsNotExist(err) {
+		log.Logger().Warnf("failed to remove the checkpoint file %s: %s", c.path, err.Error())
+	}
+}
+
+// checkpointKey returns the key of the resource in the checkpoint file which is prefixed with the resource name
+// if garbage collecting several kinds of resource
+func (o *Options) checkpointKey(gvr schema.GroupVersionResource, r *unstructured.Unstructured) string {
+	key := o.resourceNamespace(r) + "/" + r.GetName()
+	if len(o.Resources) > 1 {
+		key = gvr.Resource + "/" + key
+	}
+	return key
+}
+
+// deleteCheckpointedResource deletes the resource unless it was processed by a previous run being resumed
+// recording it in the checkpoint file once it has been processed
+func (o *Options) deleteCheckpointedResource(ctx context.Context, gvr schema.GroupVersionResource, kind string, r *unstructured.Unstructured, now time.Time) error {
+	if o.checkpoint == nil {
+		return o.deleteResource(ctx, gvr, kind, r, now)
+	}
+	key := o.checkpointKey(gvr, r)
+	if o.checkpoint.isProcessed(key) {
+		terraforms.Logger(ctx).Infof("skipping %s %s as it was processed by the run being resumed", kind, info(key))
+		return nil
+	}
+	err := o.deleteResource(ctx, gvr, kind, r, now)
+	if err != nil {
+		return err
+	}
+	o.checkpoint.record(key)
+	return nil
+}
@@ -0,0 +1,57 @@
+package gc
+
+import (
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LazyCreateClients creates the kube and dynamic clients if they are not injected along with the namespace if it
+// is required. If --kubeconfig or --context are specified they are used rather than the ambient kube config
+func (o *FilterOptions) LazyCreateClients(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface) (kubernetes.Interface, dynamic.Interface, error) {
+	var err error
+	if o.KubeConfig == "" && o.KubeContext == "" {
+		// lets avoid loading the kube config if the clients are injected and we don't need the current namespace
+		if kubeClient == nil || (o.Namespace == "" && !o.multiNamespace()) {
+			kubeClient, o.Namespace, err = kube.LazyCreateKubeClientAndNamespace(kubeClient, o.Namespace)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "failed to create kube client")
+			}
+		}
+		dynamicClient, err = kube.LazyCreateDynamicClient(dynamicClient)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to create dynamic client")
+		}
+		return kubeClient, dynamicClient, nil
+	}
+
+	config := dynkube.NewClientConfig(o.KubeConfig, o.KubeContext)
+	if o.Namespace == "" && !o.multiNamespace() {
+		o.Namespace, _, err = config.Namespace()
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to find the namespace of the kube config %s context %s", o.KubeConfig, o.KubeContext)
+		}
+	}
+	if kubeClient != nil && dynamicClient != nil {
+		return kubeClient, dynamicClient, nil
+	}
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to load the kube config %s context %s", o.KubeConfig, o.KubeContext)
+	}
+	if kubeClient == nil {
+		kubeClient, err = kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to create kube client")
+		}
+	}
+	if dynamicClient == nil {
+		dynamicClient, err = dynamic.NewForConfig(restConfig)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to create dynamic client")
+		}
+	}
+	return kubeClient, dynamicClient, nil
+}
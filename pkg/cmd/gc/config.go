@@ -0,0 +1,115 @@
+package gc
+
+import (
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// Config the defaults for the gc commands loaded from a YAML file via the --config option.
+// Any flags specified on the command line override the values in the file
+type Config struct {
+	// Namespace the namespace to query the Terraform resources
+	Namespace string `json:"namespace,omitempty"`
+
+	// Selectors the selectors to find the Terraform resources which must all match
+	Selectors []string `json:"selectors,omitempty"`
+
+	// ExcludeSelectors excludes any Terraform resources matching any of the selectors
+	ExcludeSelectors []string `json:"excludeSelectors,omitempty"`
+
+	// NameRegexp only resources whose name matches the regular expression are garbage collected
+	NameRegexp string `json:"nameRegexp,omitempty"`
+
+	// Duration the maximum age of a Terraform resource such as 2h
+	Duration string `json:"duration,omitempty"`
+
+	// KeepLabel the label key used to prevent a Terraform resource being garbage collected
+	KeepLabel string `json:"keepLabel,omitempty"`
+
+	// Concurrency the number of Terraform resources to delete concurrently
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// LoadConfig loads the configuration from the given YAML file failing if it contains any unknown fields or
+// invalid values so that typos are not silently ignored
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read config file %s", path)
+	}
+	config := &Config{}
+	err = yaml.UnmarshalStrict(data, config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse config file %s", path)
+	}
+	err = config.Validate()
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid config file %s", path)
+	}
+	return config, nil
+}
+
+// Validate returns an error if any of the values in the config are invalid
+func (c *Config) Validate() error {
+	for _, selector := range append(append([]string{}, c.Selectors...), c.ExcludeSelectors...) {
+		_, err := labels.Parse(selector)
+		if err != nil {
+			return errors.Wrapf(err, "invalid selector %s", selector)
+		}
+	}
+	if c.NameRegexp != "" {
+		_, err := regexp.Compile(c.NameRegexp)
+		if err != nil {
+			return errors.Wrapf(err, "invalid nameRegexp %s", c.NameRegexp)
+		}
+	}
+	if c.Duration != "" {
+		_, err := time.ParseDuration(c.Duration)
+		if err != nil {
+			return errors.Wrapf(err, "invalid duration %s", c.Duration)
+		}
+	}
+	if c.Concurrency < 0 {
+		return errors.Errorf("invalid concurrency %d which cannot be negative", c.Concurrency)
+	}
+	return nil
+}
+
+// applyConfig loads the config file if one is specified and applies its values to any filter options which were
+// not specified on the command line
+func (o *FilterOptions) applyConfig() error {
+	if o.ConfigFile == "" {
+		return nil
+	}
+	config, err := LoadConfig(o.ConfigFile)
+	if err != nil {
+		return err
+	}
+	o.config = config
+
+	if config.Namespace != "" && !o.flagChanged("ns") {
+		o.Namespace = config.Namespace
+	}
+	if len(config.Selectors) > 0 && !o.flagChanged("selector") {
+		o.Selectors = config.Selectors
+	}
+	if len(config.ExcludeSelectors) > 0 && !o.flagChanged("exclude-selector") {
+		o.ExcludeSelectors = config.ExcludeSelectors
+	}
+	if config.NameRegexp != "" && !o.flagChanged("name-regexp") {
+		o.NameRegexp = config.NameRegexp
+	}
+	if config.Duration != "" && !o.flagChanged("duration") {
+		// the duration was validated when loading the config
+		o.Duration, _ = time.ParseDuration(config.Duration)
+	}
+	if config.KeepLabel != "" && !o.flagChanged("keep-label") {
+		o.KeepLabel = config.KeepLabel
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package gc_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func TestLoadConfigRoundTrip(t *testing.T) {
+	config := &gc.Config{
+		Namespace:        "tests",
+		Selectors:        []string{"kind=jx-test", "repo=myrepo"},
+		ExcludeSelectors: []string{"pr=pr-999"},
+		NameRegexp:       "^tf-myrepo-",
+		Duration:         "6h",
+		KeepLabel:        "jx-test/keep",
+		Concurrency:      4,
+	}
+	data, err := yaml.Marshal(config)
+	require.NoError(t, err, "failed to marshal config")
+
+	path := filepath.Join(t.TempDir(), "gc.yaml")
+	err = os.WriteFile(path, data, 0600)
+	require.NoError(t, err, "failed to write config file")
+
+	loaded, err := gc.LoadConfig(path)
+	require.NoError(t, err, "failed to load config file")
+	assert.Equal(t, config, loaded, "loaded config")
+}
+
+func TestLoadConfigInvalidDuration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gc.yaml")
+	err := os.WriteFile(path, []byte("duration: forever\n"), 0600)
+	require.NoError(t, err, "failed to write config file")
+
+	_, err = gc.LoadConfig(path)
+	assert.Error(t, err, "should fail to load an invalid duration")
+}
+
+func TestGCConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gc.yaml")
+	err := os.WriteFile(path, []byte(`namespace: fromfile
+selectors:
+- kind=jx-test
+- repo=myrepo
+duration: 6h
+keepLabel: jx-test/keep
+concurrency: 4
+`), 0600)
+	require.NoError(t, err, "failed to write config file")
+
+	cmd, o := gc.NewCmdGC()
+	err = cmd.ParseFlags([]string{"--config", path, "--ns", "jx", "--duration", "3h"})
+	require.NoError(t, err, "failed to parse flags")
+
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err = o.Validate()
+	require.NoError(t, err, "failed to validate")
+
+	assert.Equal(t, "jx", o.Namespace, "namespace flag should override the config file")
+	assert.Equal(t, 3*time.Hour, o.Duration, "duration flag should override the config file")
+	assert.Equal(t, []string{"kind=jx-test", "repo=myrepo"}, o.Selectors, "selectors from the config file")
+	assert.Equal(t, "jx-test/keep", o.KeepLabel, "keep label from the config file")
+	assert.Equal(t, 4, o.Concurrency, "concurrency from the config file")
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{name: "unknown field", config: "namespace: jx\nselector: kind=jx-test\n", expected: `unknown field "selector"`},
+		{name: "invalid selector", config: "selectors:\n- kind in (a\n", expected: "invalid selector"},
+		{name: "invalid regexp", config: "nameRegexp: '[tf'\n", expected: "invalid nameRegexp"},
+		{name: "negative concurrency", config: "concurrency: -1\n", expected: "invalid concurrency"},
+	}
+	for _, tc := range testCases {
+		path := filepath.Join(t.TempDir(), "gc.yaml")
+		err := os.WriteFile(path, []byte(tc.config), 0600)
+		require.NoError(t, err, "failed to write config file")
+
+		_, err = gc.LoadConfig(path)
+		require.Error(t, err, "should fail to load a config with an %s", tc.name)
+		assert.Contains(t, err.Error(), tc.expected, "error for %s", tc.name)
+		assert.Contains(t, err.Error(), path, "error for %s should include the file", tc.name)
+	}
+}
+
+func TestGCValidateConfig(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	err := os.WriteFile(valid, []byte("namespace: jx\nduration: 6h\n"), 0600)
+	require.NoError(t, err, "failed to write config file")
+	invalid := filepath.Join(dir, "invalid.yaml")
+	err = os.WriteFile(invalid, []byte("namespace: jx\ndurations: 6h\n"), 0600)
+	require.NoError(t, err, "failed to write config file")
+
+	for _, path := range []string{valid, invalid, ""} {
+		_, o := gc.NewCmdGC()
+		o.ValidateConfig = true
+		o.ConfigFile = path
+		// lets check the resources are not garbage collected
+		o.DynamicClient = &panicDynClient{}
+
+		err = o.Run()
+		if path == valid {
+			assert.NoError(t, err, "should validate config file %s", path)
+		} else {
+			assert.Error(t, err, "should fail to validate config file %q", path)
+		}
+	}
+}
+
+// panicDynClient fails the test if the dynamic client is used
+type panicDynClient struct {
+	dynamic.Interface
+}
+
+func TestGCInvalidSelector(t *testing.T) {
+	testCases := []struct {
+		flag  string
+		value string
+		setup func(o *gc.Options, value string)
+	}{
+		{flag: "selector", value: "kind=!!", setup: func(o *gc.Options, value string) { o.Selectors = []string{value} }},
+		{flag: "exclude-selector", value: "pr in (", setup: func(o *gc.Options, value string) { o.ExcludeSelectors = []string{value} }},
+		{flag: "namespace-selector", value: "purpose=!!", setup: func(o *gc.Options, value string) { o.NamespaceSelector = value }},
+		{flag: "field-selector", value: "metadata.name", setup: func(o *gc.Options, value string) { o.FieldSelector = value }},
+	}
+	for _, tc := range testCases {
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		tc.setup(o, tc.value)
+		// lets fail if the API server is queried before validating the selector
+		o.DynamicClient = &panicDynClient{}
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.Error(t, err, "should fail with an invalid --%s", tc.flag)
+		assert.Contains(t, err.Error(), "--"+tc.flag, "error should mention the flag")
+		assert.Contains(t, err.Error(), "\""+tc.value+"\" is not valid", "error should mention the invalid selector")
+	}
+}
@@ -0,0 +1,226 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/jenkins-x-plugins/jx-test/pkg/root"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// VerdictNotSelected the resource does not match the selector so is never garbage collected
+	VerdictNotSelected = "not-selected"
+
+	// VerdictExcluded the resource is excluded by the namespace, exclude selector, name or creation window filters
+	VerdictExcluded = "excluded"
+)
+
+var (
+	describeLong = templates.LongDesc(`
+		Describes why a test resource would or would not be garbage collected
+`)
+
+	describeExample = templates.Examples(`
+		%s gc describe tf-myrepo-pr456-myctx-1
+	`)
+)
+
+// DescribeOptions the options for the describe command
+type DescribeOptions struct {
+	FilterOptions
+	Name          string
+	Verdict       string
+	KubeClient    kubernetes.Interface
+	DynamicClient dynamic.Interface
+	Ctx           context.Context
+	Out           io.Writer
+}
+
+// NewCmdDescribe creates a command object for the command
+func NewCmdDescribe() (*cobra.Command, *DescribeOptions) {
+	o := &DescribeOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "describe <name>",
+		Short:   "Describes why a test resource would or would not be garbage collected",
+		Long:    describeLong,
+		Example: fmt.Sprintf(describeExample, root.BinaryName),
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			o.Name = args[0]
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+
+	if o.Ctx == nil {
+		o.Ctx = cmd.Context()
+	}
+
+	o.FilterOptions.AddFlags(cmd)
+	return cmd, o
+}
+
+// Run implements the command
+func (o *DescribeOptions) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate setup")
+	}
+
+	ctx := o.GetContext()
+	r, gvr, kind, err := o.getResource(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	created := r.GetCreationTimestamp()
+	resourceLabels := r.GetLabels()
+	selector := o.Selector()
+
+	selected := true
+	if selector != "" {
+		sel, err := labels.Parse(selector)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse selector %s", selector)
+		}
+		selected = sel.Matches(labels.Set(resourceLabels))
+	}
+	excludes, err := o.excludes()
+	if err != nil {
+		return err
+	}
+	excluded := o.excludedReason(r, excludes)
+
+	c, err := o.candidate(ctx, gvr, kind, r, now)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !selected:
+		o.Verdict = VerdictNotSelected
+	case excluded != "":
+		o.Verdict = VerdictExcluded + ": " + excluded
+	case isTerminating(r):
+		o.Verdict = ActionSkippedTerminating
+	case c.ShouldDelete:
+		o.Verdict = ActionWouldDelete
+	default:
+		o.Verdict = c.Reason
+	}
+
+	keep, err := terraforms.IsKeptWithKeys(resourceLabels, r.GetAnnotations(), o.keepLabel(), o.ProtectAnnotation)
+	keepStatus := fmt.Sprintf("%s=%s %s=%s (kept: %v)", o.keepLabel(), resourceLabels[o.keepLabel()], o.ProtectAnnotation, r.GetAnnotations()[o.ProtectAnnotation], keep)
+	if err != nil {
+		keepStatus += ": " + err.Error()
+	}
+
+	t := table.CreateTable(o.Out)
+	t.AddRow("Name:", r.GetName())
+	t.AddRow("Kind:", kind)
+	t.AddRow("Namespace:", o.Namespace)
+	t.AddRow("Created:", created.Format(time.RFC3339))
+	t.AddRow("Age:", now.Sub(created.Time).Round(time.Second).String())
+	t.AddRow("Cutoff:", terraforms.ResourceCutoff(r, o.cutoff(now), now).Format(time.RFC3339))
+	t.AddRow("TTL:", r.GetAnnotations()[terraforms.AnnotationTTL])
+	t.AddRow("Keep:", keepStatus)
+	t.AddRow("Selector:", fmt.Sprintf("%s (matched: %v)", selector, selected))
+	t.AddRow("Verdict:", o.Verdict)
+	t.Render()
+	return nil
+}
+
+// getResource gets the named resource returning its resource and kind. If several kinds of resource are specified
+// via --resource the first kind with a resource of that name is used
+func (o *DescribeOptions) getResource(ctx context.Context) (*unstructured.Unstructured, schema.GroupVersionResource, string, error) {
+	gvrs, err := o.GroupVersionResources()
+	if err != nil {
+		return nil, schema.GroupVersionResource{}, "", err
+	}
+	var kinds []string
+	for _, gvr := range gvrs {
+		kind := o.ResourceKindName(o.KubeClient, gvr)
+		client := dynkube.DynamicResource(o.DynamicClient, o.Namespace, gvr)
+		r, err := client.Get(ctx, o.Name, metav1.GetOptions{})
+		if err == nil {
+			return r, gvr, kind, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return nil, gvr, "", errors.Wrapf(err, "failed to get %s %s in namespace %s", kind, o.Name, o.Namespace)
+		}
+		kinds = append(kinds, kind)
+	}
+	return nil, schema.GroupVersionResource{}, "", errors.Errorf("%s %s does not exist in namespace %s", strings.Join(kinds, " or "), o.Name, o.Namespace)
+}
+
+// candidate evaluates the resource as gc would by listing the resources in the queried namespaces so that
+// --keep-last and --retention, which compare the resources with each other, are applied. A resource which is not
+// listed, such as one which does not match the selector, is evaluated on its own
+func (o *DescribeOptions) candidate(ctx context.Context, gvr schema.GroupVersionResource, kind string, r *unstructured.Unstructured, now time.Time) (*Candidate, error) {
+	namespaces, err := o.ListNamespaces(ctx, o.KubeClient)
+	if err != nil {
+		return nil, err
+	}
+	var answer *Candidate
+	err = o.forEachCandidatePage(ctx, o.DynamicClient, namespaces, gvr, kind, now, func(candidates []*Candidate) error {
+		for _, c := range candidates {
+			if c.Resource.GetNamespace() == r.GetNamespace() && c.Resource.GetName() == r.GetName() {
+				answer = c
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if answer == nil {
+		answer = o.Evaluate(r, now)
+	}
+	return answer, nil
+}
+
+// Validate validates the options
+func (o *DescribeOptions) Validate() error {
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	if o.Name == "" {
+		return errors.Errorf("missing the name of the resource to describe")
+	}
+	err := o.FilterOptions.Validate()
+	if err != nil {
+		return err
+	}
+	o.KubeClient, o.DynamicClient, err = o.LazyCreateClients(o.KubeClient, o.DynamicClient)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetContext lazily creates a context if it doesn't exist already
+func (o *DescribeOptions) GetContext() context.Context {
+	if o.Ctx == nil {
+		o.Ctx = context.TODO()
+	}
+	return o.Ctx
+}
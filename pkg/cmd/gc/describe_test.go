@@ -0,0 +1,150 @@
+package gc_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDescribe(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		if idx == 0 {
+			u.SetLabels(map[string]string{"kind": "jx-test", "keep": "yes"})
+		}
+		if idx == 2 {
+			u.SetLabels(map[string]string{"kind": "something-else"})
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-5 * time.Hour),
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	testCases := []struct {
+		name    string
+		verdict string
+	}{
+		{name: "tf-myrepo-pr456-myctx-1", verdict: gc.ActionKeptLabel},
+		{name: "tf-myrepo-pr456-myctx-2", verdict: gc.ActionWouldDelete},
+		{name: "tf-myrepo-pr999-myctx-3", verdict: gc.VerdictNotSelected},
+	}
+	for _, tc := range testCases {
+		out := &bytes.Buffer{}
+		_, o := gc.NewCmdDescribe()
+		o.Name = tc.name
+		o.Namespace = "jx"
+		o.Out = out
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to describe %s", tc.name)
+
+		t.Logf("%s\n", out.String())
+
+		assert.Equal(t, tc.verdict, o.Verdict, "verdict for %s", tc.name)
+		assert.Contains(t, out.String(), tc.verdict, "output for %s", tc.name)
+		assert.Contains(t, out.String(), "Age:", "output for %s", tc.name)
+	}
+}
+
+func TestDescribeMatchesGCSelection(t *testing.T) {
+	now := time.Now()
+	ages := []time.Duration{5 * time.Hour, 4 * time.Hour, 5 * time.Hour}
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-ages[idx]),
+		})
+		if idx == 2 {
+			u.SetDeletionTimestamp(&metav1.Time{Time: now.Add(-time.Minute)})
+			u.SetFinalizers([]string{"example.com/finalizer"})
+		}
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	// the newest resource of pr-456 is kept by --keep-last and the terminating resource is skipped
+	testCases := []struct {
+		name    string
+		verdict string
+	}{
+		{name: "tf-myrepo-pr456-myctx-1", verdict: gc.ActionWouldDelete},
+		{name: "tf-myrepo-pr456-myctx-2", verdict: gc.ActionKeptLast},
+		{name: "tf-myrepo-pr999-myctx-3", verdict: gc.ActionSkippedTerminating},
+	}
+	for _, tc := range testCases {
+		_, o := gc.NewCmdDescribe()
+		o.Name = tc.name
+		o.Namespace = "jx"
+		o.KeepLast = 1
+		o.KeepLastLabel = "pr"
+		o.Out = &bytes.Buffer{}
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to describe %s", tc.name)
+		assert.Equal(t, tc.verdict, o.Verdict, "verdict for %s", tc.name)
+	}
+}
+
+func TestDescribeMissing(t *testing.T) {
+	_, o := gc.NewCmdDescribe()
+	o.Name = "does-not-exist"
+	o.Namespace = "jx"
+	o.Out = &bytes.Buffer{}
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail for a missing resource")
+	assert.Contains(t, err.Error(), "does-not-exist", "error should mention the resource")
+}
+
+func TestDescribeMultipleResources(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "tf.isaaguilar.com", Version: "v1alpha1", Resource: "tfworkspaces"}
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetKind("TFWorkspace")
+		u.SetCreationTimestamp(metav1.Time{
+			Time: time.Now().Add(-5 * time.Hour),
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[2:])
+
+	out := &bytes.Buffer{}
+	_, o := gc.NewCmdDescribe()
+	o.Name = "tf-myrepo-pr999-myctx-3"
+	o.Namespace = "jx"
+	o.Resources = []string{terraforms.TerraformResource.Resource, gvr.Group + "/" + gvr.Version + "/" + gvr.Resource}
+	o.Out = out
+	o.DynamicClient = dynfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		terraforms.TerraformResource: "TerraformList",
+		gvr:                          "TFWorkspaceList",
+	}, dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to describe the resource of the second kind")
+
+	t.Logf("%s\n", out.String())
+
+	assert.Equal(t, gc.ActionWouldDelete, o.Verdict, "verdict")
+	assert.Contains(t, out.String(), "Tfworkspace", "output should include the kind")
+
+	o.Name = "does-not-exist"
+	err = o.Run()
+	require.Error(t, err, "should fail for a missing resource")
+	assert.Contains(t, err.Error(), "Terraform or Tfworkspace does-not-exist does not exist", "error should mention each kind")
+}
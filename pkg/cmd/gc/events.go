@@ -0,0 +1,53 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/root"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// EventReasonGarbageCollected the reason of the Event recorded when a resource is garbage collected
+	EventReasonGarbageCollected = "GarbageCollected"
+)
+
+// emitEvent records a Kubernetes Event on the deleted resource so that there is an audit trail in the cluster.
+// Failures are only logged
+func (o *Options) emitEvent(ctx context.Context, kind string, r *unstructured.Unstructured, now time.Time) {
+	name := r.GetName()
+	ns := o.resourceNamespace(r)
+	created := r.GetCreationTimestamp()
+	age := now.Sub(created.Time).Round(time.Second)
+	t := metav1.NewTime(now)
+
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", name, now.UnixNano()),
+			Namespace: ns,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: r.GetAPIVersion(),
+			Kind:       r.GetKind(),
+			Name:       name,
+			Namespace:  ns,
+			UID:        r.GetUID(),
+		},
+		Reason:         EventReasonGarbageCollected,
+		Message:        fmt.Sprintf("deleted by %s gc as it was %s old", root.BinaryName, age.String()),
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: root.BinaryName},
+		FirstTimestamp: t,
+		LastTimestamp:  t,
+		Count:          1,
+	}
+	_, err := o.KubeClient.CoreV1().Events(ns).Create(ctx, event, metav1.CreateOptions{})
+	if err != nil {
+		terraforms.Logger(ctx).Warnf("failed to create Event for %s %s in namespace %s: %s", kind, info(name), ns, err.Error())
+	}
+}
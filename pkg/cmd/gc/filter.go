@@ -0,0 +1,776 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// FilterOptions the options for finding the resources to garbage collect which are shared by the gc commands
+// so that they always agree on which resources would be removed
+type FilterOptions struct {
+	Selectors          []string
+	KindLabelValue     string
+	ExcludeSelectors   []string
+	FieldSelector      string
+	RequireLabels      []string
+	Namespace          string
+	KubeConfig         string
+	KubeContext        string
+	AllNamespaces      bool
+	NamespaceSelector  string
+	ExcludeNamespaces  []string
+	NamespaceConfigMap string
+	Duration           time.Duration
+	OlderThan          string
+	AllAges            bool
+	MinAge             time.Duration
+	NameRegexp         string
+	KeepLabel          string
+	ProtectAnnotation  string
+	KeepLast           int
+	KeepLastLabel      string
+	Retention          string
+	PageSize           int64
+	ConfigFile         string
+	CreatedAfter       string
+	CreatedBefore      string
+	Group              string
+	Version            string
+	Resources          []string
+	Kind               string
+	JobLabel           string
+
+	cmd            *cobra.Command
+	requiredLabels map[string]string
+	nameRegexp     *regexp.Regexp
+	config         *Config
+	createdAfter   time.Time
+	createdBefore  time.Time
+	retention      *RetentionPolicy
+	gvr            schema.GroupVersionResource
+	listers        map[schema.GroupVersionResource]cache.GenericLister
+	// keptSelector an additional label selector used when listing to leave out the resources which are always kept
+	// such as those kept by a keep label in the last run
+	keptSelector string
+}
+
+// Candidate a resource matching the selector along with whether it should be garbage collected
+type Candidate struct {
+	terraforms.Candidate
+	Resource *unstructured.Unstructured
+}
+
+// AddFlags adds the filter flags to the command
+func (o *FilterOptions) AddFlags(cmd *cobra.Command) {
+	o.AddClientFlags(cmd)
+	o.AddResourceFlags(cmd)
+	o.AddKeepFlags(cmd)
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "queries the Terraform resources in all namespaces")
+	cmd.Flags().StringVarP(&o.NamespaceSelector, "namespace-selector", "", "", "only queries the Terraform resources in the namespaces matching the label selector such as purpose=test")
+	cmd.Flags().StringVarP(&o.NamespaceConfigMap, "namespace-configmap", "", "", "restricts --all-namespaces or --namespace-selector to the newline or comma separated namespaces in a ConfigMap specified as name/key in the --ns namespace (or jx if not specified) or namespace/name/key. The ConfigMap is read at the start of each run")
+	cmd.Flags().StringArrayVarP(&o.ExcludeNamespaces, "exclude-namespace", "", nil, "never garbage collects resources in the namespace even if it matches --namespace-selector or --all-namespaces is used. Can be specified multiple times")
+	cmd.Flags().StringArrayVarP(&o.Selectors, "selector", "l", []string{terraforms.KindSelector(terraforms.LabelValueKindTest)}, "the selector to find the Terraform resources to remove. Can be specified multiple times in which case resources must match all of the selectors")
+	cmd.Flags().StringVarP(&o.KindLabelValue, "kind-label-value", "", terraforms.LabelValueKindTest, "the value of the kind label used in the default selector. Ignored if --selector is specified")
+	cmd.Flags().StringVarP(&o.FieldSelector, "field-selector", "", "", "the field selector to find the Terraform resources to remove such as metadata.name=tf-myrepo-pr456-myctx-1. Custom resources only support metadata.name and metadata.namespace unless the CRD declares selectableFields")
+	cmd.Flags().StringArrayVarP(&o.RequireLabels, "require-label", "", nil, "a label of the form key=value which resources must also have to be garbage collected such as app.kubernetes.io/managed-by=jx-test. Can be specified multiple times")
+	cmd.Flags().StringArrayVarP(&o.ExcludeSelectors, "exclude-selector", "", nil, "excludes any Terraform resources matching the selector from being removed. Can be specified multiple times")
+	cmd.Flags().DurationVarP(&o.Duration, "duration", "d", 2*time.Hour, "The maximum age of a Terraform resource before it is garbage collected")
+	cmd.Flags().BoolVarP(&o.AllAges, "all-ages", "", false, "garbage collects resources regardless of their age, ignoring --duration and any TTL annotations. Resources with a keep label are still kept")
+	cmd.Flags().DurationVarP(&o.MinAge, "min-age", "", 0, "never garbage collects resources younger than the given age such as 30m regardless of any other option, such as to avoid racing with a resource being created. Use 0 for no minimum age")
+	cmd.Flags().StringVarP(&o.OlderThan, "older-than", "", "", "garbage collects resources older than a duration such as 48h or created before a time such as 2021-01-02T15:04:05Z or 2021-01-02. Cannot be used with --duration")
+	cmd.Flags().Int64VarP(&o.PageSize, "page-size", "", 500, "the maximum number of Terraform resources to fetch in each list request. Each page is evaluated before the next is fetched so only the resources to delete are held in memory unless --keep-last or --retention are used. Use 0 to fetch them all at once")
+	cmd.Flags().IntVarP(&o.KeepLast, "keep-last", "", 0, "always keeps the given number of most recently created resources for each value of the --keep-last-label label regardless of their age")
+	cmd.Flags().StringVarP(&o.KeepLastLabel, "keep-last-label", "", "context", "the label used to group resources when using --keep-last such as context or branch")
+	cmd.Flags().StringVarP(&o.Retention, "retention", "", "", "keeps the newest resource in each hour, day or week regardless of its age such as hourly=24,daily=7. Supported periods: hourly, daily, weekly")
+	cmd.Flags().StringVarP(&o.NameRegexp, "name-regexp", "", "", "only garbage collects resources whose name matches the regular expression such as ^tf-myrepo-pr")
+	cmd.Flags().StringVarP(&o.CreatedAfter, "created-after", "", "", "only garbage collects resources created after the RFC3339 timestamp such as 2021-01-02T15:04:05Z regardless of their age")
+	cmd.Flags().StringVarP(&o.CreatedBefore, "created-before", "", "", "only garbage collects resources created before the RFC3339 timestamp such as 2021-01-02T15:04:05Z regardless of their age")
+	cmd.Flags().StringVarP(&o.JobLabel, "job-label", "", "", "the label key on Jobs whose value is the name of the Terraform resource used to find its Jobs. If not specified the Job with the same name as the Terraform resource is used")
+	cmd.Flags().StringVarP(&o.ConfigFile, "config", "", "", "a YAML file containing the default namespace, selectors, duration, keep label, concurrency and exclusions. Flags specified on the command line override the values in the file")
+	o.cmd = cmd
+}
+
+// AddClientFlags adds the flags for the namespace and kube config used to create the clients
+func (o *FilterOptions) AddClientFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.Namespace, "ns", "n", "", "the namespace to query the Terraform resources")
+	cmd.Flags().StringVarP(&o.KubeConfig, "kubeconfig", "", "", "the kube config file to use rather than $KUBECONFIG or ~/.kube/config")
+	cmd.Flags().StringVarP(&o.KubeContext, "context", "", "", "the kube config context to use rather than the current context")
+}
+
+// AddResourceFlags adds the flags for the kinds of custom resource to query
+func (o *FilterOptions) AddResourceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.Group, "group", "", terraforms.TerraformResource.Group, "the API group of the custom resource to garbage collect")
+	cmd.Flags().StringVarP(&o.Version, "version", "", terraforms.TerraformResource.Version, "the API version of the custom resource to garbage collect")
+	cmd.Flags().StringArrayVarP(&o.Resources, "resource", "", []string{terraforms.TerraformResource.Resource}, "the custom resource to garbage collect as either the plural resource name in the --group and --version or group/version/resource such as tf.isaaguilar.com/v1alpha1/terraforms. Can be specified multiple times to garbage collect several kinds of resource")
+	cmd.Flags().StringVarP(&o.Kind, "kind", "", "", "the kind of the custom resource to garbage collect. Defaults to the singular of the resource name. Cannot be used with multiple --resource values")
+}
+
+// AddKeepFlags adds the flags for the label and annotation used to keep resources
+func (o *FilterOptions) AddKeepFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&o.KeepLabel, "keep-label", "", terraforms.LabelKeep, "the label key used to prevent a Terraform resource being garbage collected")
+	cmd.Flags().StringVarP(&o.ProtectAnnotation, "protect-annotation", "", terraforms.AnnotationKeep, "the annotation key used to prevent a Terraform resource being garbage collected in addition to the --keep-label label")
+}
+
+// Validate validates the filter options
+func (o *FilterOptions) Validate() error {
+	err := o.applyConfig()
+	if err != nil {
+		return options.InvalidOptionf("config", o.ConfigFile, err.Error())
+	}
+	o.applyKindLabelValue()
+	for _, s := range o.Selectors {
+		_, err := labels.Parse(s)
+		if err != nil {
+			return invalidSelector("selector", s, err)
+		}
+	}
+	for _, s := range o.ExcludeSelectors {
+		_, err := labels.Parse(s)
+		if err != nil {
+			return invalidSelector("exclude-selector", s, err)
+		}
+	}
+	o.requiredLabels = map[string]string{}
+	for _, l := range o.RequireLabels {
+		key, value, err := parseRequiredLabel(l)
+		if err != nil {
+			return options.InvalidOptionf("require-label", l, err.Error())
+		}
+		o.requiredLabels[key] = value
+	}
+	if o.FieldSelector != "" {
+		_, err = fields.ParseSelector(o.FieldSelector)
+		if err != nil {
+			return options.InvalidOptionf("field-selector", o.FieldSelector, "the field selector %q is not valid: %s. Field selectors look like metadata.name=value or metadata.namespace!=value", o.FieldSelector, err.Error())
+		}
+	}
+	if o.NameRegexp != "" {
+		re, err := regexp.Compile(o.NameRegexp)
+		if err != nil {
+			return options.InvalidOptionf("name-regexp", o.NameRegexp, "invalid regular expression: %s", err.Error())
+		}
+		o.nameRegexp = re
+	}
+	err = o.validateWindow()
+	if err != nil {
+		return err
+	}
+	if o.NamespaceSelector != "" {
+		_, err = labels.Parse(o.NamespaceSelector)
+		if err != nil {
+			return invalidSelector("namespace-selector", o.NamespaceSelector, err)
+		}
+	}
+	_, err = o.GroupVersionResources()
+	if err != nil {
+		return err
+	}
+	if o.Kind != "" && len(o.Resources) > 1 {
+		return options.InvalidOptionf("kind", o.Kind, "cannot be used with multiple --resource values")
+	}
+	if o.NamespaceConfigMap != "" {
+		if !o.multiNamespace() {
+			return options.InvalidOptionf("namespace-configmap", o.NamespaceConfigMap, "requires --all-namespaces or --namespace-selector")
+		}
+		_, _, _, err = o.namespaceConfigMapRef()
+		if err != nil {
+			return err
+		}
+	}
+	if o.KeepLast < 0 {
+		return options.InvalidOptionf("keep-last", o.KeepLast, "must not be negative")
+	}
+	o.retention = nil
+	if o.MinAge < 0 {
+		return options.InvalidOptionf("min-age", o.MinAge, "the minimum age cannot be negative")
+	}
+	if o.Retention != "" {
+		o.retention, err = ParseRetention(o.Retention)
+		if err != nil {
+			return options.InvalidOptionf("retention", o.Retention, err.Error())
+		}
+	}
+	if o.OlderThan == "" {
+		return nil
+	}
+	if o.AllAges {
+		return options.InvalidOptionf("older-than", o.OlderThan, "cannot be used with the --all-ages option")
+	}
+	if o.flagChanged("duration") {
+		return options.InvalidOptionf("older-than", o.OlderThan, "cannot be used with the --duration option")
+	}
+	_, err = ParseOlderThan(o.OlderThan, time.Now())
+	if err != nil {
+		return options.InvalidOptionf("older-than", o.OlderThan, err.Error())
+	}
+	return nil
+}
+
+// invalidSelector returns a friendly error for an invalid label selector so that it fails before querying the
+// API server which would return a more confusing error
+func invalidSelector(flag, selector string, err error) error {
+	return options.InvalidOptionf(flag, selector, "the label selector %q is not valid: %s. Label selectors look like key=value, key!=value, key in (a,b) or !key", selector, err.Error())
+}
+
+// applyKindLabelValue replaces the kind label value in the default selector with --kind-label-value
+// unless the selectors were specified via the --selector flag or the config file
+func (o *FilterOptions) applyKindLabelValue() {
+	if o.KindLabelValue == "" || o.flagChanged("selector") {
+		return
+	}
+	if len(o.Selectors) == 1 && o.Selectors[0] == terraforms.KindSelector(terraforms.LabelValueKindTest) {
+		o.Selectors = []string{terraforms.KindSelector(o.KindLabelValue)}
+	}
+}
+
+// validateWindow parses the --created-after and --created-before timestamps
+func (o *FilterOptions) validateWindow() error {
+	var err error
+	o.createdAfter = time.Time{}
+	o.createdBefore = time.Time{}
+	if o.CreatedAfter != "" {
+		o.createdAfter, err = time.Parse(time.RFC3339, o.CreatedAfter)
+		if err != nil {
+			return options.InvalidOptionf("created-after", o.CreatedAfter, "must be an RFC3339 timestamp such as 2021-01-02T15:04:05Z")
+		}
+	}
+	if o.CreatedBefore != "" {
+		o.createdBefore, err = time.Parse(time.RFC3339, o.CreatedBefore)
+		if err != nil {
+			return options.InvalidOptionf("created-before", o.CreatedBefore, "must be an RFC3339 timestamp such as 2021-01-02T15:04:05Z")
+		}
+	}
+	if !o.createdAfter.IsZero() && !o.createdBefore.IsZero() && !o.createdAfter.Before(o.createdBefore) {
+		return options.InvalidOptionf("created-after", o.CreatedAfter, "must be before --created-before %s", o.CreatedBefore)
+	}
+	return nil
+}
+
+// hasWindow returns true if the resources are filtered by a creation time window
+func (o *FilterOptions) hasWindow() bool {
+	return !o.createdAfter.IsZero() || !o.createdBefore.IsZero()
+}
+
+// inWindow returns true if the creation time is within the --created-after and --created-before window
+func (o *FilterOptions) inWindow(created time.Time) bool {
+	if !o.createdAfter.IsZero() && !created.After(o.createdAfter) {
+		return false
+	}
+	if !o.createdBefore.IsZero() && !created.Before(o.createdBefore) {
+		return false
+	}
+	return true
+}
+
+// ParseOlderThan parses the given value which is either a duration such as 48h or a timestamp in RFC3339 format or
+// a date of the form 2006-01-02 returning the time before which resources should be garbage collected
+func ParseOlderThan(value string, now time.Time) (time.Time, error) {
+	d, err := time.ParseDuration(value)
+	if err == nil {
+		return now.Add(d * -1), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("could not parse %s as a duration such as 48h or a timestamp such as 2021-01-02T15:04:05Z or 2021-01-02", value)
+}
+
+// keepLabel returns the label key used to keep resources
+func (o *FilterOptions) keepLabel() string {
+	if o.KeepLabel == "" {
+		return terraforms.LabelKeep
+	}
+	return o.KeepLabel
+}
+
+// keepValue returns the value of the keep label or if there is none the protect annotation of the resource
+func (o *FilterOptions) keepValue(r *unstructured.Unstructured) string {
+	value := r.GetLabels()[o.keepLabel()]
+	if value == "" && o.ProtectAnnotation != "" {
+		value = r.GetAnnotations()[o.ProtectAnnotation]
+	}
+	return value
+}
+
+// Selector returns the label selector used to list the resources which combines all of the selectors
+func (o *FilterOptions) Selector() string {
+	return strings.Join(o.Selectors, ",")
+}
+
+// listSelector returns the label selector used to list the resources which also leaves out any resources which
+// are always kept
+func (o *FilterOptions) listSelector() string {
+	selector := o.Selector()
+	if o.keptSelector == "" {
+		return selector
+	}
+	if selector == "" {
+		return o.keptSelector
+	}
+	return selector + "," + o.keptSelector
+}
+
+// excludes returns the parsed exclude selectors
+func (o *FilterOptions) excludes() ([]labels.Selector, error) {
+	var answer []labels.Selector
+	for _, s := range o.ExcludeSelectors {
+		sel, err := labels.Parse(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse exclude selector %s", s)
+		}
+		answer = append(answer, sel)
+	}
+	return answer, nil
+}
+
+// cutoff returns the time before which resources must have been created to be garbage collected
+func (o *FilterOptions) cutoff(now time.Time) time.Time {
+	if o.OlderThan != "" {
+		t, err := ParseOlderThan(o.OlderThan, now)
+		if err == nil {
+			return t
+		}
+	}
+	return now.Add(o.Duration * -1)
+}
+
+// flagChanged returns true if the given flag was specified on the command line
+func (o *FilterOptions) flagChanged(name string) bool {
+	if o.cmd == nil {
+		return false
+	}
+	f := o.cmd.Flags().Lookup(name)
+	return f != nil && f.Changed
+}
+
+// ListCandidates lists the resources matching the selector and evaluates whether each one should be garbage collected
+func (o *FilterOptions) ListCandidates(ctx context.Context, client dynamic.ResourceInterface, kind string, now time.Time) ([]*Candidate, error) {
+	var answer []*Candidate
+	err := o.ListCandidatePages(ctx, client, kind, now, func(candidates []*Candidate) error {
+		answer = append(answer, candidates...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
+
+// ListCandidatePages lists the resources matching the selector a page of --page-size at a time invoking the callback
+// with the evaluated candidates of each page before the next page is fetched
+func (o *FilterOptions) ListCandidatePages(ctx context.Context, client dynamic.ResourceInterface, kind string, now time.Time, fn func(candidates []*Candidate) error) error {
+	excludes, err := o.excludes()
+	if err != nil {
+		return err
+	}
+	selector := o.listSelector()
+	err = dynkube.ListPages(ctx, client, metav1.ListOptions{LabelSelector: selector, FieldSelector: o.FieldSelector}, o.PageSize, func(list *unstructured.UnstructuredList) error {
+		var candidates []*Candidate
+		for i := range list.Items {
+			// lets copy the item so that the candidates do not keep the whole page in memory
+			r := list.Items[i]
+			candidates = o.appendCandidate(candidates, &r, excludes, kind, now)
+		}
+		return fn(candidates)
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Logger().Infof("no %s resources found with selector %s", kind, selector)
+			return nil
+		}
+		return errors.Wrapf(err, "failed to list %s resources with selector %s", kind, selector)
+	}
+	return nil
+}
+
+// listCachedCandidates lists the candidates in the namespace from the informer cache used by --watch which is
+// already filtered by the selector
+func (o *FilterOptions) listCachedCandidates(lister cache.GenericLister, ns, kind string, now time.Time) ([]*Candidate, error) {
+	excludes, err := o.excludes()
+	if err != nil {
+		return nil, err
+	}
+	var objects []runtime.Object
+	if ns == "" {
+		objects, err = lister.List(labels.Everything())
+	} else {
+		objects, err = lister.ByNamespace(ns).List(labels.Everything())
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list cached %s resources in namespace %s", kind, ns)
+	}
+	var resources []*unstructured.Unstructured
+	for _, obj := range objects {
+		u, ok := obj.(*unstructured.Unstructured)
+		if ok {
+			// lets not modify the objects in the cache
+			resources = append(resources, u.DeepCopy())
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].GetNamespace() != resources[j].GetNamespace() {
+			return resources[i].GetNamespace() < resources[j].GetNamespace()
+		}
+		return resources[i].GetName() < resources[j].GetName()
+	})
+	var answer []*Candidate
+	for _, r := range resources {
+		answer = o.appendCandidate(answer, r, excludes, kind, now)
+	}
+	return answer, nil
+}
+
+// appendCandidate evaluates the resource and appends it to the candidates unless it is excluded
+func (o *FilterOptions) appendCandidate(candidates []*Candidate, r *unstructured.Unstructured, excludes []labels.Selector, kind string, now time.Time) []*Candidate {
+	reason := o.excludedReason(r, excludes)
+	if reason != "" {
+		log.Logger().Debugf("excluding %s %s as %s", kind, info(r.GetName()), reason)
+		return candidates
+	}
+	return append(candidates, o.Evaluate(r, now))
+}
+
+// excludedReason returns the reason the resource is excluded from garbage collection by the namespace, exclude
+// selector, name or creation window filters or an empty string if it is not excluded
+func (o *FilterOptions) excludedReason(r *unstructured.Unstructured, excludes []labels.Selector) string {
+	if o.isExcludedNamespace(r.GetNamespace()) {
+		return fmt.Sprintf("its namespace %s is excluded", r.GetNamespace())
+	}
+	resourceLabels := r.GetLabels()
+	for k, v := range o.requiredLabels {
+		if resourceLabels[k] != v {
+			return fmt.Sprintf("it does not have the required label %s=%s", k, v)
+		}
+	}
+	if matchesAny(excludes, r) {
+		return "it matches an exclude selector"
+	}
+	if o.nameRegexp != nil && !o.nameRegexp.MatchString(r.GetName()) {
+		return fmt.Sprintf("it does not match the name regexp %s", o.NameRegexp)
+	}
+	if !o.inWindow(r.GetCreationTimestamp().Time) {
+		return "it was not created within the --created-after and --created-before window"
+	}
+	return ""
+}
+
+// listAllCandidates lists the candidates in each of the given namespaces
+func (o *FilterOptions) listAllCandidates(ctx context.Context, dynamicClient dynamic.Interface, namespaces []string, gvr schema.GroupVersionResource, kind string, now time.Time) ([]*Candidate, error) {
+	var answer []*Candidate
+	err := o.forEachCandidatePage(ctx, dynamicClient, namespaces, gvr, kind, now, func(candidates []*Candidate) error {
+		answer = append(answer, candidates...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return answer, nil
+}
+
+// forEachCandidatePage invokes the callback with each page of candidates in the given namespaces as it is listed so
+// that only a page of resources needs to be held in memory. If --keep-last or --retention is used the candidates
+// are compared with each other so the callback is invoked once with all of them instead
+func (o *FilterOptions) forEachCandidatePage(ctx context.Context, dynamicClient dynamic.Interface, namespaces []string, gvr schema.GroupVersionResource, kind string, now time.Time, fn func(candidates []*Candidate) error) error {
+	compare := (o.KeepLast > 0 && o.KeepLastLabel != "") || o.retention != nil
+	var all []*Candidate
+	pageFn := func(candidates []*Candidate) error {
+		if compare {
+			all = append(all, candidates...)
+			return nil
+		}
+		return fn(candidates)
+	}
+	for _, ns := range namespaces {
+		lister := o.listers[gvr]
+		if lister != nil {
+			candidates, err := o.listCachedCandidates(lister, ns, kind, now)
+			if err != nil {
+				return err
+			}
+			err = pageFn(candidates)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		client := dynkube.DynamicResource(dynamicClient, ns, gvr)
+		err := o.ListCandidatePages(ctx, client, kind, now, pageFn)
+		if err != nil {
+			return err
+		}
+	}
+	if !compare {
+		return nil
+	}
+	o.keepLast(all)
+	o.applyRetention(all, now)
+	return fn(all)
+}
+
+// ListResources lists the resources of each --resource in the queried namespaces which match the selectors and
+// filters so that other commands report on the same resources which gc garbage collects
+func (o *FilterOptions) ListResources(ctx context.Context, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface) ([]*unstructured.Unstructured, error) {
+	gvrs, err := o.GroupVersionResources()
+	if err != nil {
+		return nil, err
+	}
+	namespaces, err := o.ListNamespaces(ctx, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		o.gvr = schema.GroupVersionResource{}
+	}()
+
+	now := time.Now()
+	var answer []*unstructured.Unstructured
+	for _, gvr := range gvrs {
+		o.gvr = gvr
+		candidates, err := o.listAllCandidates(ctx, dynamicClient, namespaces, gvr, o.ResourceKindName(kubeClient, gvr), now)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range candidates {
+			answer = append(answer, c.Resource)
+		}
+	}
+	return answer, nil
+}
+
+// keepLast keeps the --keep-last most recently created candidates for each value of the --keep-last-label label.
+// Candidates without the label are not affected
+func (o *FilterOptions) keepLast(candidates []*Candidate) {
+	if o.KeepLast <= 0 || o.KeepLastLabel == "" {
+		return
+	}
+	groups := map[string][]*Candidate{}
+	for _, c := range candidates {
+		value := c.Resource.GetLabels()[o.KeepLastLabel]
+		if value == "" {
+			continue
+		}
+		key := c.Resource.GetNamespace() + "/" + value
+		groups[key] = append(groups[key], c)
+	}
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			t1 := group[i].Resource.GetCreationTimestamp()
+			t2 := group[j].Resource.GetCreationTimestamp()
+			return t2.Before(&t1)
+		})
+		for i := 0; i < o.KeepLast && i < len(group); i++ {
+			c := group[i]
+			if c.ShouldDelete {
+				c.ShouldDelete = false
+				c.Reason = ActionKeptLast
+			}
+		}
+	}
+}
+
+// getNamedCandidates gets the named resources in the namespace which should be garbage collected regardless of
+// the selectors and their age. Resources with a keep label are still kept
+func (o *FilterOptions) getNamedCandidates(ctx context.Context, dynamicClient dynamic.Interface, names []string, gvr schema.GroupVersionResource, kind string, now time.Time) ([]*Candidate, error) {
+	ns := o.Namespace
+	if o.isExcludedNamespace(ns) {
+		return nil, errors.Errorf("cannot delete %s resources in namespace %s as it is excluded", kind, ns)
+	}
+	client := dynkube.DynamicResource(dynamicClient, ns, gvr)
+	var answer []*Candidate
+	for _, name := range names {
+		r, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, errors.Errorf("%s %s does not exist in namespace %s", kind, name, ns)
+			}
+			return nil, errors.Wrapf(err, "failed to get %s %s in namespace %s", kind, name, ns)
+		}
+		answer = append(answer, o.evaluate(r, now, true))
+	}
+	return answer, nil
+}
+
+// Evaluate returns whether the given resource should be garbage collected
+func (o *FilterOptions) Evaluate(r *unstructured.Unstructured, now time.Time) *Candidate {
+	// resources within an explicit creation window are garbage collected regardless of their age
+	return o.evaluate(r, now, o.AllAges || o.hasWindow())
+}
+
+// evaluate returns whether the given resource should be garbage collected. If anyAge is true the resource is not
+// kept for being too young though --min-age still applies
+func (o *FilterOptions) evaluate(r *unstructured.Unstructured, now time.Time, anyAge bool) *Candidate {
+	c := &Candidate{
+		Candidate: terraforms.EvaluateCandidate(r, o.keepLabel(), o.ProtectAnnotation, o.cutoff(now), now),
+		Resource:  r,
+	}
+	if c.Reason == terraforms.ReasonKeptTooYoung && anyAge {
+		c.ShouldDelete = true
+		c.Reason = ""
+	}
+	o.applyMinAge(c, now)
+	return c
+}
+
+// applyMinAge keeps the candidate if it is younger than --min-age regardless of why it would be deleted
+func (o *FilterOptions) applyMinAge(c *Candidate, now time.Time) {
+	if o.MinAge <= 0 || !c.ShouldDelete {
+		return
+	}
+	if now.Sub(c.Created) < o.MinAge {
+		c.ShouldDelete = false
+		c.Reason = ActionKeptMinAge
+	}
+}
+
+// jobOptions returns the options used to find the Terraform Jobs of a resource
+func (o *FilterOptions) jobOptions() terraforms.JobOptions {
+	return terraforms.JobOptions{JobLabel: o.JobLabel}
+}
+
+// listNamespace returns the namespace to query resources in which is empty if querying all namespaces
+func (o *FilterOptions) listNamespace() string {
+	if o.multiNamespace() {
+		return ""
+	}
+	return o.Namespace
+}
+
+// multiNamespace returns true if resources are queried in more than one namespace
+func (o *FilterOptions) multiNamespace() bool {
+	return o.AllNamespaces || o.NamespaceSelector != ""
+}
+
+// isExcludedNamespace returns true if the namespace is excluded via --exclude-namespace
+func (o *FilterOptions) isExcludedNamespace(ns string) bool {
+	return ns != "" && stringhelpers.StringArrayIndex(o.ExcludeNamespaces, ns) >= 0
+}
+
+// resourceNamespace returns the namespace to delete the given resource from
+func (o *FilterOptions) resourceNamespace(r *unstructured.Unstructured) string {
+	ns := r.GetNamespace()
+	if !o.multiNamespace() || ns == "" {
+		return o.Namespace
+	}
+	return ns
+}
+
+// parseRequiredLabel parses a label of the form key=value
+func parseRequiredLabel(text string) (string, string, error) {
+	parts := strings.SplitN(text, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", errors.Errorf("must be of the form key=value")
+	}
+	_, err := labels.Parse(text)
+	if err != nil {
+		return "", "", err
+	}
+	return parts[0], parts[1], nil
+}
+
+// matchesAny returns true if the resource labels match any of the selectors
+func matchesAny(selectors []labels.Selector, r *unstructured.Unstructured) bool {
+	set := labels.Set(r.GetLabels())
+	for _, sel := range selectors {
+		if sel.Matches(set) {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupVersionResource returns the resource currently being garbage collected or the first of the resources to
+// garbage collect which defaults to the Terraform resource
+func (o *FilterOptions) GroupVersionResource() schema.GroupVersionResource {
+	if !o.gvr.Empty() {
+		return o.gvr
+	}
+	gvrs, err := o.GroupVersionResources()
+	if err != nil || len(gvrs) == 0 {
+		return o.defaultGroupVersionResource()
+	}
+	return gvrs[0]
+}
+
+// GroupVersionResources returns the resources to garbage collect specified via --resource as either the plural
+// resource name in the --group and --version or as group/version/resource
+func (o *FilterOptions) GroupVersionResources() ([]schema.GroupVersionResource, error) {
+	if len(o.Resources) == 0 {
+		return []schema.GroupVersionResource{o.defaultGroupVersionResource()}, nil
+	}
+	var answer []schema.GroupVersionResource
+	for _, text := range o.Resources {
+		gvr := o.defaultGroupVersionResource()
+		paths := strings.Split(text, "/")
+		switch len(paths) {
+		case 1:
+			gvr.Resource = paths[0]
+		case 3:
+			gvr = schema.GroupVersionResource{Group: paths[0], Version: paths[1], Resource: paths[2]}
+		default:
+			return nil, options.InvalidOptionf("resource", text, "must be a resource name or group/version/resource")
+		}
+		if gvr.Version == "" || gvr.Resource == "" {
+			return nil, options.InvalidOptionf("resource", text, "the version and resource name must not be empty")
+		}
+		for _, g := range answer {
+			if g == gvr {
+				return nil, options.InvalidOptionf("resource", text, "the resource %s is specified more than once", gvr.String())
+			}
+		}
+		answer = append(answer, gvr)
+	}
+	return answer, nil
+}
+
+// defaultGroupVersionResource returns the Terraform resource in the --group and --version
+func (o *FilterOptions) defaultGroupVersionResource() schema.GroupVersionResource {
+	gvr := terraforms.TerraformResource
+	if o.Group != "" {
+		gvr.Group = o.Group
+	}
+	if o.Version != "" {
+		gvr.Version = o.Version
+	}
+	return gvr
+}
+
+// ResourceKindName returns the kind of the resource to garbage collect using the --kind option, the discovery API
+// or guessing from the resource name in that order
+func (o *FilterOptions) ResourceKindName(kubeClient kubernetes.Interface, gvr schema.GroupVersionResource) string {
+	if o.Kind != "" {
+		return o.Kind
+	}
+	if kubeClient != nil {
+		kind, err := dynkube.DiscoverKind(kubeClient.Discovery(), gvr)
+		if err == nil && kind != "" {
+			return kind
+		}
+		log.Logger().Debugf("guessing the kind of %s as could not discover it: %v", gvr.String(), err)
+	}
+	return dynkube.KindForResource(gvr.Resource)
+}
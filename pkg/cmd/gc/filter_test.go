@@ -0,0 +1,177 @@
+package gc_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+func TestParseOlderThan(t *testing.T) {
+	now := time.Date(2021, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		value    string
+		expected time.Time
+	}{
+		{value: "48h", expected: time.Date(2021, 3, 2, 12, 0, 0, 0, time.UTC)},
+		{value: "2021-01-02T15:04:05Z", expected: time.Date(2021, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{value: "2021-01-02", expected: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range testCases {
+		got, err := gc.ParseOlderThan(tc.value, now)
+		require.NoError(t, err, "failed to parse %s", tc.value)
+		assert.True(t, tc.expected.Equal(got), "for %s expected %s but got %s", tc.value, tc.expected.String(), got.String())
+	}
+
+	_, err := gc.ParseOlderThan("yesterday", now)
+	assert.Error(t, err, "should fail to parse an invalid value")
+}
+
+func TestOlderThanAndDurationAreExclusive(t *testing.T) {
+	cmd, o := gc.NewCmdGC()
+	o.OlderThan = "48h"
+	err := o.FilterOptions.Validate()
+	require.NoError(t, err, "should allow --older-than on its own")
+
+	err = cmd.Flags().Set("duration", "1h")
+	require.NoError(t, err, "failed to set duration flag")
+	err = o.FilterOptions.Validate()
+	require.Error(t, err, "should not allow both --older-than and --duration")
+}
+
+func TestFilterInvalidExcludeSelector(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.ExcludeSelectors = []string{"pr in ("}
+
+	err := o.FilterOptions.Validate()
+	assert.Error(t, err, "should fail with an invalid exclude selector")
+}
+
+func TestFilterInvalidNameRegexp(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.NameRegexp = "tf-(["
+
+	err := o.FilterOptions.Validate()
+	require.Error(t, err, "should fail with an invalid name regexp")
+	assert.Contains(t, err.Error(), "name-regexp", "error should mention the flag")
+}
+
+func TestFilterCreatedWindowValidation(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.CreatedAfter = "2021-01-03T00:00:00Z"
+	o.CreatedBefore = "2021-01-02T00:00:00Z"
+
+	err := o.FilterOptions.Validate()
+	require.Error(t, err, "should fail when --created-after is not before --created-before")
+	assert.Contains(t, err.Error(), "created-after", "error should mention the flag")
+
+	o.CreatedAfter = "yesterday"
+	o.CreatedBefore = ""
+	err = o.FilterOptions.Validate()
+	assert.Error(t, err, "should fail with an invalid timestamp")
+
+	o.CreatedAfter = "2021-01-01T00:00:00Z"
+	o.CreatedBefore = "2021-01-02T00:00:00Z"
+	err = o.FilterOptions.Validate()
+	assert.NoError(t, err, "should accept a valid window")
+}
+
+func TestFilterAllAgesWithOlderThan(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.AllAges = true
+	o.OlderThan = "48h"
+
+	err := o.FilterOptions.Validate()
+	assert.Error(t, err, "should fail when combining --all-ages with --older-than")
+}
+
+func TestFilterKindLabelValue(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		expected string
+	}{
+		{args: nil, expected: "kind=jx-test"},
+		{args: []string{"--kind-label-value", "bdd"}, expected: "kind=bdd"},
+		{args: []string{"--kind-label-value", "bdd", "--selector", "kind=e2e", "--selector", "pr=pr-456"}, expected: "kind=e2e,pr=pr-456"},
+	}
+	for _, tc := range testCases {
+		cmd, o := gc.NewCmdGC()
+		err := cmd.ParseFlags(tc.args)
+		require.NoError(t, err, "failed to parse flags %v", tc.args)
+
+		err = o.FilterOptions.Validate()
+		require.NoError(t, err, "failed to validate for flags %v", tc.args)
+		assert.Equal(t, tc.expected, o.Selector(), "selector for flags %v", tc.args)
+	}
+}
+
+func TestFilterInvalidRequireLabel(t *testing.T) {
+	for _, l := range []string{"managed-by", "=jx-test", "managed by=jx-test"} {
+		_, o := gc.NewCmdGC()
+		o.RequireLabels = []string{l}
+
+		err := o.FilterOptions.Validate()
+		assert.Error(t, err, "should fail with an invalid required label %s", l)
+	}
+}
+
+// pagedResource returns the resources a page at a time using the Limit and Continue list options
+type pagedResource struct {
+	dynamic.ResourceInterface
+	resources []unstructured.Unstructured
+	calls     int
+}
+
+func (c *pagedResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	c.calls++
+	start := 0
+	if opts.Continue != "" {
+		start, _ = strconv.Atoi(opts.Continue)
+	}
+	end := len(c.resources)
+	if opts.Limit > 0 && start+int(opts.Limit) < end {
+		end = start + int(opts.Limit)
+	}
+	list := &unstructured.UnstructuredList{Items: append([]unstructured.Unstructured{}, c.resources[start:end]...)}
+	if end < len(c.resources) {
+		list.SetContinue(strconv.Itoa(end))
+	}
+	return list, nil
+}
+
+func TestFilterListCandidatePages(t *testing.T) {
+	now := time.Now()
+	client := &pagedResource{}
+	for i := 0; i < 5; i++ {
+		u := unstructured.Unstructured{}
+		u.SetName(fmt.Sprintf("tf-%d", i))
+		u.SetCreationTimestamp(metav1.Time{Time: now.Add(-5 * time.Hour)})
+		client.resources = append(client.resources, u)
+	}
+
+	_, o := gc.NewCmdGC()
+	o.PageSize = 2
+	err := o.FilterOptions.Validate()
+	require.NoError(t, err, "failed to validate")
+
+	var pages []int
+	err = o.ListCandidatePages(context.Background(), client, "Terraform", now, func(candidates []*gc.Candidate) error {
+		assert.Equal(t, len(pages)+1, client.calls, "each page should be evaluated before the next page is listed")
+		for _, c := range candidates {
+			assert.True(t, c.ShouldDelete, "should delete %s", c.Resource.GetName())
+		}
+		pages = append(pages, len(candidates))
+		return nil
+	})
+	require.NoError(t, err, "failed to list candidate pages")
+	assert.Equal(t, []int{2, 2, 1}, pages, "candidates per page")
+}
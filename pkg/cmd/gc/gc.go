@@ -3,24 +3,39 @@ package gc
 import (
 	"context"
 	"fmt"
-	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
-	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
-	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
-	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
-	"k8s.io/client-go/kubernetes"
+	"io"
+	"os"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
 	"github.com/jenkins-x-plugins/jx-test/pkg/root"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
-	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/input/survey"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"golang.org/x/time/rate"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
 )
 
 var (
@@ -28,28 +43,144 @@ var (
 
 	cmdLong = templates.LongDesc(`
 		Garbage collects test resources
+
+		If resource names are specified only those resources are deleted regardless of the selector and their age.
+		As list and describe are subcommands, resources with those names must be specified via --name
+
+		The command exits with 0 if the run succeeds even if there was nothing to delete and 1 if it fails. Use
+		--fail-if-none to also fail if no resources matched the selector
 `)
 
 	cmdExample = templates.Examples(`
 		%s gc
+
+		# deletes the given Terraform resources
+		%s gc tf-myrepo-pr456-myctx-1 tf-myrepo-pr456-myctx-2
+
+		# deletes a Terraform resource whose name is the same as a subcommand
+		%s gc --name list
 	`)
 
 	terraformStateSelector = "tfstate=true"
 
 	defaultTerraformConfigMapPrefix = "tf-jx3-versions-"
+
+	// slackWebhookEnvVar the environment variable used for the Slack webhook if not specified via a flag
+	slackWebhookEnvVar = "SLACK_WEBHOOK_URL"
+
+	propagationPolicies = []string{
+		string(metav1.DeletePropagationBackground),
+		string(metav1.DeletePropagationForeground),
+		string(metav1.DeletePropagationOrphan),
+	}
 )
 
 // Options the options for the command
 type Options struct {
-	Selector                 string
-	Namespace                string
+	FilterOptions
+	Names                    []string
 	TerraformConfigMapPrefix string
-	Duration                 time.Duration
+	DryRun                   bool
+	Quiet                    bool
+	ReportOnlyErrors         bool
+	Verbose                  bool
+	FailFast                 bool
+	Yes                      bool
+	UseKubectl               bool
+	CascadeOwned             bool
+	CascadeNamespaces        bool
+	GCOrphanJobs             bool
+	OwnedLabel               string
+	NamespaceFromLabel       string
+	PropagationPolicy        string
+	GracePeriodSeconds       int64
+	WaitForJobs              bool
+	SkipActive               bool
+	ProtectIfReferenced      bool
+	ReferenceResource        string
+	ReferenceLabel           string
+	OnlyFailed               bool
+	SkipCRDCheck             bool
+	FailIfNone               bool
+	ValidateConfig           bool
+	StateConfigMap           string
+	SinceLastRun             bool
+	CheckPermissions         bool
+	Watch                    bool
+	Interval                 time.Duration
+	DeleteEmptyNamespace     bool
+	EmptyNamespaceSelector   string
+	RecheckEmptyNamespaces   bool
+	Mark                     bool
+	Sweep                    bool
+	SweepGracePeriod         time.Duration
+	WaitForJobsTimeout       time.Duration
+	VerifyDeleted            bool
+	ShowTerraformPlan        bool
+	EmitEvents               bool
+	AnnotateBeforeDelete     bool
+	ForceRemoveFinalizers    bool
+	IncludeTerminating       bool
+	DeleteHelmRelease        bool
+	PreDeleteHook            string
+	PostDeleteHook           string
+	FinalizerGracePeriod     time.Duration
+	VerifyDeletedTimeout     time.Duration
+	Concurrency              int
+	Retries                  int
+	MaxDelete                int
+	RetryBackoff             time.Duration
+	DelayBetweenDeletes      time.Duration
+	QPS                      float64
+	Burst                    int
+	Timeout                  time.Duration
+	Output                   string
+	Template                 string
+	Sort                     string
+	ReportFile               string
+	CheckpointFile           string
+	Resume                   bool
+	Strict                   bool
+	LogFormat                string
+	MetricsAddress           string
+	MetricsFile              string
+	LabelsAsMetrics          []string
+	Metrics                  *Metrics
+	Notifiers                []Notifier
+	SlackWebhook             string
+	SlackNotifyEmpty         bool
+	GitHubToken              string
+	GitHubURL                string
+	Deleted                  int
+	Result                   *RunResult
+	Out                      io.Writer
+	LogOut                   io.Writer
 	KubeClient               kubernetes.Interface
+	Clock                    clock.Clock
 	DynamicClient            dynamic.Interface
 	Ctx                      context.Context
 	Client                   dynamic.ResourceInterface
 	CommandRunner            cmdrunner.CommandRunner
+	Input                    input.Interface
+	Commenter                PullRequestCommenter
+	Planner                  terraforms.DestroyPlanner
+	References               terraforms.ReferenceChecker
+
+	resultLock sync.Mutex
+	limiter    *rate.Limiter
+	checkpoint *checkpoint
+	state      *runState
+	template   *template.Template
+}
+
+// NewOptions creates the options with the default flag values and the given clients so that gc can be embedded
+// or tested without parsing any command line arguments. The Namespace should be specified unless querying all
+// namespaces otherwise the current namespace is resolved from the kube config
+func NewOptions(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface) *Options {
+	_, o := NewCmdGC()
+	o.KubeClient = kubeClient
+	o.DynamicClient = dynamicClient
+	return o
 }
 
 // NewCmdGC creates a command object for the command
@@ -57,11 +188,12 @@ func NewCmdGC() (*cobra.Command, *Options) {
 	o := &Options{}
 
 	cmd := &cobra.Command{
-		Use:     "gc",
+		Use:     "gc [names...]",
 		Short:   "Garbage collects test resources",
 		Long:    cmdLong,
-		Example: fmt.Sprintf(cmdExample, root.BinaryName),
+		Example: fmt.Sprintf(cmdExample, root.BinaryName, root.BinaryName, root.BinaryName),
 		Run: func(cmd *cobra.Command, args []string) {
+			o.Names = append(o.Names, args...)
 			err := o.Run()
 			helper.CheckErr(err)
 		},
@@ -71,97 +203,907 @@ func NewCmdGC() (*cobra.Command, *Options) {
 		o.Ctx = cmd.Context()
 	}
 
-	cmd.Flags().StringVarP(&o.Namespace, "ns", "n", "", "the namespace to query the Terraform resources")
-	cmd.Flags().StringVarP(&o.Selector, "selector", "l", "kind="+terraforms.LabelValueKindTest, "the selector to find the Terraform resources to remove")
+	o.FilterOptions.AddFlags(cmd)
+	cmd.Flags().StringArrayVarP(&o.Names, "name", "", nil, "the name of a resource to delete regardless of the selector and its age. Use this rather than an argument for resources named list or describe. Can be specified multiple times")
 	cmd.Flags().StringVarP(&o.TerraformConfigMapPrefix, "tf-cm-prefix", "t", defaultTerraformConfigMapPrefix, "the ConfigMap name prefix of the Terraform state")
-	cmd.Flags().DurationVarP(&o.Duration, "duration", "d", 2*time.Hour, "The maximum age of a Terraform resource before it is garbage collected")
+	cmd.Flags().BoolVarP(&o.UseKubectl, "use-kubectl", "", false, "deletes the Terraform resources via kubectl rather than the kubernetes API")
+	cmd.Flags().BoolVarP(&o.CascadeNamespaces, "cascade-namespaces", "", false, "also deletes the namespaces which have an owner reference to each deleted Terraform resource as Kubernetes does not garbage collect namespaces owned by a namespaced resource")
+	cmd.Flags().BoolVarP(&o.CascadeOwned, "cascade-owned", "", false, "also deletes the Secrets, ConfigMaps and PersistentVolumeClaims labelled with the name of each deleted Terraform resource")
+	cmd.Flags().BoolVarP(&o.GCOrphanJobs, "gc-orphan-jobs", "", false, "also deletes Terraform Jobs older than the cutoff whose Terraform resource no longer exists")
+	cmd.Flags().StringVarP(&o.OwnedLabel, "owned-label", "", terraforms.LabelTerraform, "the label key whose value is the Terraform resource name used to find owned resources with --cascade-owned")
+	cmd.Flags().StringVarP(&o.NamespaceFromLabel, "namespace-from-label", "", "", "the label key on each Terraform resource whose value is the namespace of its workload. The resources owned by the Terraform resource in that namespace are also deleted with --cascade-owned")
+	cmd.Flags().StringVarP(&o.PropagationPolicy, "propagation-policy", "", string(metav1.DeletePropagationBackground), "the deletion propagation policy used when deleting via the kubernetes API. Supported values: "+strings.Join(propagationPolicies, ", "))
+	cmd.Flags().Int64VarP(&o.GracePeriodSeconds, "grace-period-seconds", "", -1, "the termination grace period in seconds of the deleted resources and their Terraform Jobs and Pods. Use -1 for the API default")
+	cmd.Flags().BoolVarP(&o.WaitForJobs, "wait-for-jobs", "", false, "waits for any active Terraform Jobs to finish rather than deleting them. Resources whose Jobs do not finish within --wait-for-jobs-timeout are skipped")
+	cmd.Flags().BoolVarP(&o.Mark, "mark", "", false, "labels the resources which would be deleted with "+terraforms.LabelMarkedForGC+" rather than deleting them so that a later --sweep can remove them")
+	cmd.Flags().BoolVarP(&o.Sweep, "sweep", "", false, "only deletes resources which were marked by --mark longer than --sweep-grace-period ago")
+	cmd.Flags().DurationVarP(&o.SweepGracePeriod, "sweep-grace-period", "", 24*time.Hour, "how long a resource must have been marked before --sweep deletes it")
+	cmd.Flags().BoolVarP(&o.DeleteEmptyNamespace, "delete-empty-namespace", "", false, "deletes the namespaces matching --empty-namespace-selector which no longer contain any resources after they have been garbage collected")
+	cmd.Flags().StringVarP(&o.EmptyNamespaceSelector, "empty-namespace-selector", "", "jx-test/delete-when-empty=true", "the label selector a namespace must match to be deleted by --delete-empty-namespace")
+	cmd.Flags().BoolVarP(&o.RecheckEmptyNamespaces, "recheck-empty-namespaces", "", false, "also deletes the namespaces matching --empty-namespace-selector older than --duration which no longer contain any resources even if nothing was deleted from them in this run such as when their last resource was still terminating on a previous run. Requires --delete-empty-namespace")
+	cmd.Flags().BoolVarP(&o.SkipCRDCheck, "skip-crd-check", "", false, "skips checking that the CRD of the resource is installed before running such as if discovery is not permitted")
+	cmd.Flags().BoolVarP(&o.Watch, "watch", "w", false, "runs continuously as a controller garbage collecting the resources every --interval until it is stopped rather than running once. Resources are deleted without prompting for confirmation")
+	cmd.Flags().DurationVarP(&o.Interval, "interval", "", 5*time.Minute, "how often resources are garbage collected when using --watch")
+	cmd.Flags().StringVarP(&o.StateConfigMap, "state-configmap", "", "", "the ConfigMap, as name in the --ns namespace or namespace/name, used to store the time of the last run and the keep label values which kept resources")
+	cmd.Flags().BoolVarP(&o.SinceLastRun, "since-last-run", "", false, "does not list the resources whose keep label has a value which kept resources in the last run recorded in the --state-configmap so they are not fetched or evaluated")
+	cmd.Flags().BoolVarP(&o.CheckPermissions, "check-permissions", "", false, "checks the RBAC permissions to list and delete the resources and their Jobs in each namespace, reporting any which are missing, and exits without garbage collecting")
+	cmd.Flags().BoolVarP(&o.ValidateConfig, "validate-config", "", false, "validates the --config file, reporting any unknown fields or invalid values, and exits without garbage collecting")
+	cmd.Flags().BoolVarP(&o.FailIfNone, "fail-if-none", "", false, "fails the run if no resources matched the selector, regardless of their age, to detect a misconfigured selector")
+	cmd.Flags().BoolVarP(&o.OnlyFailed, "only-failed", "", false, "only garbage collects resources which have at least one failed Terraform Job")
+	cmd.Flags().BoolVarP(&o.ProtectIfReferenced, "protect-if-referenced", "", false, "skips resources which are referenced by an active resource such as a running Tekton PipelineRun via the --reference-label label")
+	cmd.Flags().StringVarP(&o.ReferenceResource, "reference-resource", "", "tekton.dev/v1beta1/pipelineruns", "the group/version/resource of the resources which reference the test resources for --protect-if-referenced")
+	cmd.Flags().StringVarP(&o.ReferenceLabel, "reference-label", "", terraforms.LabelTerraform, "the label on the --reference-resource resources whose value is the name of the referenced test resource")
+	cmd.Flags().BoolVarP(&o.SkipActive, "skip-active", "", false, "skips resources which have an active Terraform Job on this run rather than deleting the Job which could corrupt the cloud state")
+	cmd.Flags().DurationVarP(&o.WaitForJobsTimeout, "wait-for-jobs-timeout", "", 10*time.Minute, "the maximum time to wait for an active Terraform Job to finish when using --wait-for-jobs")
+	cmd.Flags().BoolVarP(&o.VerifyDeleted, "verify-deleted", "", false, "waits for each deleted resource to be removed, such as after any finalizers complete, logging whether it fully terminated")
+	cmd.Flags().DurationVarP(&o.VerifyDeletedTimeout, "verify-deleted-timeout", "", time.Minute, "the maximum time to wait for each deleted resource to be removed when using --verify-deleted")
+	cmd.Flags().BoolVarP(&o.DeleteHelmRelease, "delete-helm-release", "", false, "uninstalls the Helm release with the same name as each deleted resource in its namespace via helm uninstall. Failures are logged but do not fail the run")
+	cmd.Flags().StringVarP(&o.PreDeleteHook, "pre-delete-hook", "", "", "a command run via the shell before deleting each resource such as 'dnsctl deregister {{.Name}}'. The template can use {{.Name}}, {{.Namespace}} and {{.Kind}}. If the command fails the resource is not deleted")
+	cmd.Flags().StringVarP(&o.PostDeleteHook, "post-delete-hook", "", "", "a command run via the shell after deleting each resource. The template can use {{.Name}}, {{.Namespace}} and {{.Kind}}. Failures are logged but do not fail the run")
+	cmd.Flags().BoolVarP(&o.EmitEvents, "emit-events", "", false, "records a Kubernetes Event for each deleted resource so there is an audit trail visible via kubectl get events")
+	cmd.Flags().BoolVarP(&o.AnnotateBeforeDelete, "annotate-before-delete", "", false, "annotates each resource with "+terraforms.AnnotationGCReason+" and "+terraforms.AnnotationGCTime+" just before deleting it so the audit information survives if the deletion does not complete")
+	cmd.Flags().BoolVarP(&o.ShowTerraformPlan, "show-terraform-plan", "", false, "logs a summary of the cloud resources in the stored Terraform state of each resource which would be destroyed when it is deleted")
+	cmd.Flags().BoolVarP(&o.ForceRemoveFinalizers, "force-remove-finalizers", "", false, "DANGEROUS: removes the finalizers from resources which have been terminating for longer than --finalizer-grace-period. Any cleanup the finalizers perform, such as destroying cloud infrastructure, will not happen")
+	cmd.Flags().BoolVarP(&o.IncludeTerminating, "include-terminating", "", false, "processes resources which are already terminating rather than skipping them. This is implied by --force-remove-finalizers")
+	cmd.Flags().DurationVarP(&o.FinalizerGracePeriod, "finalizer-grace-period", "", time.Hour, "how long a resource must have been terminating before its finalizers are removed when using --force-remove-finalizers")
+	cmd.Flags().IntVarP(&o.Concurrency, "concurrency", "", 1, "the number of Terraform resources to delete concurrently")
+	cmd.Flags().StringVarP(&o.Sort, "sort", "", SortOldestFirst, "the order in which the resources are considered and deleted. Supported values: "+strings.Join(sortOrders, ", "))
+	cmd.Flags().IntVarP(&o.MaxDelete, "max-delete", "", 50, "the maximum number of Terraform resources to delete in a run. If more resources are eligible nothing is deleted. Use 0 for no limit")
+	cmd.Flags().IntVarP(&o.Retries, "retries", "", 3, "the number of times to retry a failed deletion")
+	cmd.Flags().DurationVarP(&o.RetryBackoff, "retry-backoff", "", time.Second, "the initial delay before retrying a failed deletion which doubles on each retry")
+	cmd.Flags().DurationVarP(&o.DelayBetweenDeletes, "delay-between-deletes", "", 0, "how long to wait after deleting each resource such as 30s to limit the load on the cloud APIs used to destroy the infrastructure. With --concurrency each worker waits after its own deletions")
+	cmd.Flags().Float64VarP(&o.QPS, "qps", "", 5, "the maximum number of delete requests per second to avoid overwhelming the API server. Use 0 for no limit")
+	cmd.Flags().IntVarP(&o.Burst, "burst", "", 10, "the maximum number of delete requests which can be made at once before being limited by --qps")
+	cmd.Flags().DurationVarP(&o.Timeout, "timeout", "", 0, "the maximum time the whole run can take before it is aborted such as 10m. Use 0 for no timeout")
+	cmd.Flags().StringVarP(&o.ReportFile, "report-file", "", "", "writes the result of the run to the given file as YAML if it has a .yaml or .yml extension or JSON otherwise")
+	cmd.Flags().StringVarP(&o.CheckpointFile, "checkpoint-file", "", "", "records each processed resource in the given file so that an interrupted run can be resumed via --resume. The file is removed once the run completes")
+	cmd.Flags().BoolVarP(&o.Resume, "resume", "", false, "skips the resources recorded in the --checkpoint-file by a previous run which did not complete")
+	cmd.Flags().BoolVarP(&o.Strict, "strict", "", false, "fails the run if the --report-file cannot be written rather than logging a warning")
+	cmd.Flags().StringVarP(&o.Template, "template", "", "", "a Go text/template to write the result of the run with such as '{{range .DeletedResources}}{{.Namespace}}/{{.Name}}{{\"\\n\"}}{{end}}'. The template can use the fields of the JSON output and the DeletedResources, KeptResources and ErrorResources")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "", "the output format of the run. Supported values: json for a summary once the run completes, jsonl to stream a JSON object per line for each resource as it is processed or name to print the name of each deleted resource per line")
+	cmd.Flags().StringVarP(&o.LogFormat, "log-format", "", LogFormatText, "the log format. If json is used each action taken on a resource is also logged as a JSON line. Supported values: "+strings.Join(logFormats, ", "))
+	cmd.Flags().StringVarP(&o.MetricsAddress, "metrics-address", "", "", "the address to serve Prometheus metrics on such as :8080. The metrics are served until the process exits so for one-shot runs from a CronJob use --metrics-file instead. If not specified no metrics are served")
+	cmd.Flags().StringVarP(&o.MetricsFile, "metrics-file", "", "", "the file to write the Prometheus metrics to at the end of each run in the text format such as for the node exporter textfile collector or pushing to a Pushgateway")
+	cmd.Flags().StringArrayVarP(&o.LabelsAsMetrics, "labels-as-metrics", "", nil, "a resource label such as repository or owner whose value is added as a label on the deleted and kept metrics. Invalid characters are replaced with _ and at most 100 values of each label are exported. Can be specified multiple times")
+	cmd.Flags().StringVarP(&o.SlackWebhook, "slack-webhook", "", "", "the Slack incoming webhook URL to notify of deleted resources. Defaults to the $"+slackWebhookEnvVar+" environment variable")
+	cmd.Flags().BoolVarP(&o.SlackNotifyEmpty, "slack-notify-empty", "", false, "notifies Slack even if no resources were deleted")
+	cmd.Flags().StringVarP(&o.GitHubToken, "github-token", "", "", "the GitHub token used to comment on the pull request of each deleted resource identified by its owner, repo and pr labels. Defaults to the $"+githubTokenEnvVar+" environment variable")
+	cmd.Flags().StringVarP(&o.GitHubURL, "github-url", "", defaultGitHubURL, "the GitHub API URL used when commenting on pull requests")
+	cmd.Flags().BoolVarP(&o.Yes, "yes", "y", false, "deletes the resources without prompting for confirmation when running in a terminal")
+	cmd.Flags().BoolVarP(&o.FailFast, "fail-fast", "", false, "stops on the first failure to delete a resource rather than attempting to delete all the resources and then failing")
+	cmd.Flags().BoolVarP(&o.Quiet, "quiet", "q", false, "only logs the resources which are deleted and the summary rather than the resources which are kept")
+	cmd.Flags().BoolVarP(&o.ReportOnlyErrors, "report-only-errors", "", false, "only logs the resources which failed to be garbage collected and the summary. Unlike --quiet the deleted resources are not logged")
+	cmd.Flags().BoolVarP(&o.Verbose, "verbose", "v", false, "logs the creation time, age, cutoff and decision for every resource")
+	cmd.Flags().BoolVarP(&o.DryRun, "dry-run", "", false, "logs the resources which would be garbage collected without removing them")
+
+	cmd.AddCommand(cobras.SplitCommand(NewCmdDescribe()))
+	cmd.AddCommand(cobras.SplitCommand(NewCmdList()))
 	return cmd, o
 }
 
 // Run implements the command
 func (o *Options) Run() error {
+	if o.ValidateConfig {
+		return o.validateConfigFile()
+	}
+	if o.CheckPermissions {
+		return o.checkPermissions(o.GetContext())
+	}
+	if o.Watch {
+		return o.watch(o.GetContext())
+	}
+	_, err := o.RunWithResult(o.GetContext())
+	return err
+}
+
+// RunWithResult garbage collects the resources using the given context returning the result of the run
+// which is nil if the run failed before any resources were queried
+func (o *Options) RunWithResult(ctx context.Context) (*RunResult, error) {
+	o.Result = nil
+	err := o.run(ctx)
+	return o.Result, err
+}
+
+func (o *Options) run(ctx context.Context) error {
+	start := time.Now()
 	err := o.Validate()
 	if err != nil {
 		return errors.Wrapf(err, "failed to validate setup")
 	}
 
-	ctx := o.GetContext()
-	ns := o.Namespace
-	gvr := terraforms.TerraformResource
-	o.Client = dynkube.DynamicResource(o.DynamicClient, ns, gvr)
+	if o.Output != "" || o.Template != "" || o.ReportOnlyErrors {
+		// lets only log warnings so that the output can be parsed or only the errors are reported
+		level := log.GetLevel()
+		err = log.SetLevel("warn")
+		if err != nil {
+			return errors.Wrapf(err, "failed to set log level")
+		}
+		defer log.SetLevel(level) //nolint:errcheck
+	}
 
-	kind := strings.Title(strings.TrimSuffix(gvr.Resource, "s"))
+	if o.Verbose && o.Output == "" && o.Template == "" {
+		level := log.GetLevel()
+		err = log.SetLevel("debug")
+		if err != nil {
+			return errors.Wrapf(err, "failed to set log level")
+		}
+		defer log.SetLevel(level) //nolint:errcheck
+	}
 
-	// lets delete all the previous resources for this Pull Request and Context
-	list, err := o.Client.List(ctx, metav1.ListOptions{
-		LabelSelector: o.Selector,
-	})
-	if err != nil && apierrors.IsNotFound(err) {
-		return errors.Wrapf(err, "could not find resources for ")
+	err = o.startMetrics()
+	if err != nil {
+		return err
+	}
+
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
 	}
+	gvrs, err := o.GroupVersionResources()
+	if err != nil {
+		return err
+	}
+	dynamicClient := o.Metrics.instrument(o.DynamicClient)
+	defer func() {
+		o.gvr = schema.GroupVersionResource{}
+	}()
 
-	createdBefore := time.Now().Add(o.Duration * -1)
+	now := time.Now()
+	createdBefore := o.cutoff(now)
 	createdTime := &metav1.Time{
 		Time: createdBefore,
 	}
-	for _, r := range list.Items {
-		name := r.GetName()
+	o.Deleted = 0
+	o.Result = &RunResult{
+		Selector: o.Selector(),
+		Cutoff:   createdBefore,
+	}
+	defer o.completeResult(start)
+	namespaces, err := o.ListNamespaces(ctx, o.KubeClient)
+	if err != nil {
+		return err
+	}
+	if o.StateConfigMap != "" {
+		o.state, err = o.loadState(ctx)
+		if err != nil {
+			return err
+		}
+		if o.SinceLastRun {
+			o.keptSelector = o.sinceLastRunSelector()
+		}
+		defer func() {
+			o.state = nil
+			o.keptSelector = ""
+		}()
+	}
 
-		labels := r.GetLabels()
-		if labels != nil {
-			keep := labels["keep"]
-			if keep != "" {
-				log.Logger().Infof("not removing %s %s as it has a keep label", kind, info(name))
-				continue
+	var batches []*resourceBatch
+	total := 0
+	for _, gvr := range gvrs {
+		o.gvr = gvr
+		b := &resourceBatch{
+			gvr:  gvr,
+			kind: o.ResourceKindName(o.KubeClient, gvr),
+		}
+		matched := 0
+		b.resources, matched, err = o.collectResources(ctx, dynamicClient, namespaces, gvr, b.kind, now)
+		if err != nil {
+			return err
+		}
+		if matched == 0 && o.FailIfNone {
+			err = o.report(ctx, start)
+			if err != nil {
+				return err
 			}
+			return errors.Errorf("no %s resources matched the selector %s", b.kind, o.Selector())
 		}
+		batches = append(batches, b)
+		total += len(b.resources)
+	}
+	o.Result.Candidates = total
+	o.Metrics.setCandidates(total)
 
-		created := r.GetCreationTimestamp()
-		if !created.Before(createdTime) {
-			log.Logger().Infof("not removing %s %s as it was created at %s", kind, info(name), created.String())
+	if o.MaxDelete > 0 && total > o.MaxDelete {
+		if !o.DryRun {
+			return errors.Errorf("refusing to delete %d %s resources as it exceeds the --max-delete limit of %d", total, batchKinds(batches), o.MaxDelete)
+		}
+		log.Logger().Warnf("dry-run: %d %s resources exceeds the --max-delete limit of %d so nothing would be deleted", total, batchKinds(batches), o.MaxDelete)
+	}
+
+	if o.ShowTerraformPlan {
+		for _, b := range batches {
+			o.gvr = b.gvr
+			o.showTerraformPlans(ctx, b.kind, b.resources)
+		}
+	}
+
+	if o.Mark {
+		var markErrs []error
+		for _, b := range batches {
+			o.gvr = b.gvr
+			markErr := o.markResources(ctx, b.gvr, b.kind, b.resources, now)
+			if markErr != nil {
+				if o.FailFast {
+					return markErr
+				}
+				markErrs = append(markErrs, markErr)
+			}
+		}
+		err = o.report(ctx, start)
+		if err != nil {
+			return err
+		}
+		return utilerrors.NewAggregate(markErrs)
+	}
+
+	for _, b := range batches {
+		if len(b.resources) > 0 && !o.DryRun {
+			confirmed, err := o.confirmDelete(b.kind, b.resources)
+			if err != nil {
+				return errors.Wrapf(err, "failed to confirm deletion")
+			}
+			if !confirmed {
+				log.Logger().Infof("not deleting any %s resources", batchKinds(batches))
+				// lets still report the declined resources as kept
+				for _, declined := range batches {
+					for _, r := range declined.resources {
+						o.addResult(r, now, ActionKeptDeclined, nil)
+					}
+				}
+				return o.report(ctx, start)
+			}
+		}
+	}
+
+	completed := false
+	if o.CheckpointFile != "" {
+		o.checkpoint, err = openCheckpoint(o.CheckpointFile, o.Resume)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			o.checkpoint.close(completed)
+			o.checkpoint = nil
+		}()
+	}
+
+	var deleteErrs []error
+	for _, b := range batches {
+		o.gvr = b.gvr
+		deleted := o.Deleted
+		deleteErr := o.deleteResources(ctx, b.gvr, b.kind, b.resources, now)
+		if deleteErr != nil {
+			if o.FailFast {
+				return deleteErr
+			}
+			deleteErrs = append(deleteErrs, deleteErr)
+		}
+		if o.DryRun {
+			log.Logger().Infof("dry-run: would delete %d %s resources", o.Deleted-deleted, b.kind)
+		}
+	}
+	o.gvr = schema.GroupVersionResource{}
+	deleteErr := utilerrors.NewAggregate(deleteErrs)
+
+	for _, ns := range namespaces {
+		err = o.gcLeases(ctx, ns, createdTime)
+		if err != nil {
+			return errors.Wrapf(err, "failed to GC leases")
+		}
+
+		err = o.gcTerraformState(ctx, ns, createdTime)
+		if err != nil {
+			return errors.Wrapf(err, "failed to GC terraform state")
+		}
+
+		err = o.gcTerraformConfigMaps(ctx, ns, createdTime)
+		if err != nil {
+			return errors.Wrapf(err, "failed to GC terraform configs")
+		}
+
+		if o.GCOrphanJobs {
+			err = o.gcOrphanJobs(ctx, ns, createdTime)
+			if err != nil {
+				return errors.Wrapf(err, "failed to GC orphaned Terraform Jobs")
+			}
+		}
+	}
+
+	if o.DeleteEmptyNamespace {
+		err = o.deleteEmptyNamespaces(ctx, batches, namespaces, createdBefore)
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete empty namespaces")
+		}
+	}
+
+	if o.state != nil && !o.DryRun {
+		err = o.saveState(ctx, o.state, now)
+		if err != nil {
+			log.Logger().Warnf("failed to save the state of this run so the next run will evaluate all resources: %s", err.Error())
+		}
+	}
+
+	err = o.report(ctx, start)
+	if err != nil {
+		return err
+	}
+	completed = deleteErr == nil
+	return deleteErr
+}
+
+// parseReferenceResource parses the --reference-resource as group/version/resource where the group is empty for
+// core resources
+func parseReferenceResource(text string) (schema.GroupVersionResource, error) {
+	paths := strings.Split(text, "/")
+	if len(paths) != 3 || paths[1] == "" || paths[2] == "" {
+		return schema.GroupVersionResource{}, options.InvalidOptionf("reference-resource", text, "must be group/version/resource")
+	}
+	return schema.GroupVersionResource{Group: paths[0], Version: paths[1], Resource: paths[2]}, nil
+}
+
+// resourceBatch the resources of a kind to garbage collect
+type resourceBatch struct {
+	gvr       schema.GroupVersionResource
+	kind      string
+	resources []*unstructured.Unstructured
+}
+
+// batchKinds returns the kinds of the batches for logging
+func batchKinds(batches []*resourceBatch) string {
+	var kinds []string
+	for _, b := range batches {
+		kinds = append(kinds, b.kind)
+	}
+	return strings.Join(kinds, ", ")
+}
+
+// collectResources finds the resources of the current kind which should be garbage collected recording the result
+// of any which are kept. Each page of resources is evaluated as it is listed so that only the resources to delete
+// are held in memory. The number of resources which matched the selector regardless of their age is also returned
+func (o *Options) collectResources(ctx context.Context, dynamicClient dynamic.Interface, namespaces []string, gvr schema.GroupVersionResource, kind string, now time.Time) ([]*unstructured.Unstructured, int, error) {
+	o.Client = dynkube.DynamicResource(dynamicClient, o.listNamespace(), gvr)
+
+	var resources []*unstructured.Unstructured
+	matched := 0
+	collect := func(candidates []*Candidate) error {
+		matched += len(candidates)
+		if o.OnlyFailed {
+			var err error
+			candidates, err = o.failedCandidates(ctx, kind, candidates)
+			if err != nil {
+				return err
+			}
+		}
+		resources = append(resources, o.selectResources(ctx, gvr, kind, candidates, now)...)
+		return nil
+	}
+	var err error
+	if len(o.Names) > 0 {
+		var candidates []*Candidate
+		candidates, err = o.getNamedCandidates(ctx, dynamicClient, o.Names, gvr, kind, now)
+		if err == nil {
+			err = collect(candidates)
+		}
+	} else {
+		err = o.forEachCandidatePage(ctx, dynamicClient, namespaces, gvr, kind, now, collect)
+	}
+	if err != nil {
+		return nil, matched, err
+	}
+	if o.Sweep {
+		resources = o.sweepable(kind, resources, now, o.logKept())
+	}
+	o.sortResources(resources)
+	return resources, matched, nil
+}
+
+// logKept returns the function used to log why a resource is kept which only logs at debug level with --quiet
+func (o *Options) logKept() func(format string, args ...interface{}) {
+	if o.Quiet {
+		return log.Logger().Debugf
+	}
+	return log.Logger().Infof
+}
+
+// selectResources returns the resources of the candidates which should be deleted recording the result of any
+// which are kept
+func (o *Options) selectResources(ctx context.Context, gvr schema.GroupVersionResource, kind string, candidates []*Candidate, now time.Time) []*unstructured.Unstructured {
+	logKept := o.logKept()
+	var resources []*unstructured.Unstructured
+	for _, c := range candidates {
+		r := c.Resource
+		if isTerminating(r) && !o.includeTerminating() {
+			log.Logger().Debugf("skipping %s %s as it is already terminating", kind, info(r.GetName()))
+			o.addResult(r, now, ActionSkippedTerminating, nil)
+			continue
+		}
+		o.logDecision(kind, c, now)
+		if c.ShouldDelete && o.ForceRemoveFinalizers && o.isStuckTerminating(r, now) {
+			o.removeFinalizers(ctx, gvr, kind, r, now)
 			continue
 		}
+		if c.ShouldDelete {
+			resources = append(resources, r)
+			continue
+		}
+		switch c.Reason {
+		case ActionKeptLabel:
+			o.recordKept(r)
+			logKept("not removing %s %s (age %s) as it has a keep label or annotation", kind, info(r.GetName()), resourceAge(r, now))
+		case ActionKeptLast:
+			logKept("not removing %s %s (age %s) as it is one of the %d most recent resources with the same %s label", kind, info(r.GetName()), resourceAge(r, now), o.KeepLast, o.KeepLastLabel)
+		case ActionKeptRetention:
+			logKept("not removing %s %s (age %s) as it is retained by the retention policy %s", kind, info(r.GetName()), resourceAge(r, now), o.Retention)
+		case ActionKeptMinAge:
+			log.Logger().Infof("protecting %s %s from deletion as it was created %s ago which is within the --min-age %s", kind, info(r.GetName()), resourceAge(r, now), o.MinAge.String())
+		default:
+			logKept("not removing %s %s as it was only created %s ago", kind, info(r.GetName()), resourceAge(r, now))
+		}
+		o.addResult(r, now, c.Reason, nil)
+	}
+	return resources
+}
+
+// validateConfigFile loads the --config file to report any errors without garbage collecting
+func (o *Options) validateConfigFile() error {
+	if o.ConfigFile == "" {
+		return options.MissingOption("config")
+	}
+	_, err := LoadConfig(o.ConfigFile)
+	if err != nil {
+		return err
+	}
+	log.Logger().Infof("the config file %s is valid", info(o.ConfigFile))
+	return nil
+}
+
+// report notifies the notifiers, logs the summary and writes the result of the run
+func (o *Options) report(ctx context.Context, start time.Time) error {
+	o.completeResult(start)
+	o.notify(ctx)
+	o.logSummary()
+	err := o.writeResult()
+	if err != nil {
+		return err
+	}
+	err = o.writeMetricsFile()
+	if err != nil {
+		return err
+	}
+	return o.writeReportFile()
+}
+
+// confirmDelete prompts the user to confirm the deletion of the resources if running in a terminal
+func (o *Options) confirmDelete(kind string, resources []*unstructured.Unstructured) (bool, error) {
+	if o.Yes {
+		return true, nil
+	}
+	if o.Input == nil {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			// lets preserve the behaviour of non interactive runs such as CronJobs
+			return true, nil
+		}
+		o.Input = survey.NewInput()
+	}
+	for _, r := range resources {
+		log.Logger().Infof("about to delete %s %s in namespace %s", kind, info(r.GetName()), o.resourceNamespace(r))
+	}
+	message := fmt.Sprintf("Delete %d %s resources?", len(resources), kind)
+	return o.Input.Confirm(message, false, "the resources will be deleted if you confirm, use --yes to skip this prompt")
+}
+
+// delayBetweenDeletes waits for --delay-between-deletes after a resource is deleted to limit the load on the
+// cloud APIs used by terraform destroy. When deleting concurrently each worker waits after its own deletions
+func (o *Options) delayBetweenDeletes(ctx context.Context) {
+	if o.DelayBetweenDeletes <= 0 || o.DryRun {
+		return
+	}
+	timer := o.Clock.NewTimer(o.DelayBetweenDeletes)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C():
+	}
+}
+
+// deleteResources deletes the given resources using a pool of Concurrency workers. A failure does not stop the
+// other resources being deleted unless FailFast is enabled; any failures are returned as a single error
+func (o *Options) deleteResources(ctx context.Context, gvr schema.GroupVersionResource, kind string, resources []*unstructured.Unstructured, now time.Time) error {
+	var errs []error
+	if o.Concurrency <= 1 {
+		for i, r := range resources {
+			err := o.deleteCheckpointedResource(ctx, gvr, kind, r, now)
+			if err != nil {
+				if o.FailFast {
+					return err
+				}
+				log.Logger().Warnf("%s: %s", r.GetName(), err.Error())
+				errs = append(errs, err)
+				continue
+			}
+			if i < len(resources)-1 {
+				o.delayBetweenDeletes(ctx)
+			}
+		}
+		return utilerrors.NewAggregate(errs)
+	}
+
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	queue := make(chan *unstructured.Unstructured)
+	for i := 0; i < o.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range queue {
+				errLock.Lock()
+				stop := o.FailFast && len(errs) > 0
+				errLock.Unlock()
+				if stop {
+					continue
+				}
+				// lets prefix each line logged for the resource with its name as the workers logs are interleaved
+				resourceCtx := terraforms.WithLogPrefix(ctx, r.GetName())
+				err := o.deleteCheckpointedResource(resourceCtx, gvr, kind, r, now)
+				if err != nil {
+					terraforms.Logger(resourceCtx).Warnf("%s", err.Error())
+					errLock.Lock()
+					errs = append(errs, err)
+					errLock.Unlock()
+					continue
+				}
+				o.delayBetweenDeletes(ctx)
+			}
+		}()
+	}
+	for _, r := range resources {
+		queue <- r
+	}
+	close(queue)
+	wg.Wait()
+	return utilerrors.NewAggregate(errs)
+}
+
+func (o *Options) deleteResource(ctx context.Context, gvr schema.GroupVersionResource, kind string, r *unstructured.Unstructured, now time.Time) error {
+	name := r.GetName()
+	ns := o.resourceNamespace(r)
+
+	if o.SkipActive {
+		activeJobs, err := terraforms.CountActiveTerraformJobs(ctx, o.KubeClient, ns, name, o.jobOptions())
+		if err != nil {
+			o.addResult(r, now, ActionError, err)
+			return errors.Wrapf(err, "failed to find active Terraform Jobs for %s %s in namespace %s", kind, name, ns)
+		}
+		if activeJobs > 0 {
+			terraforms.Logger(ctx).Warnf("skipping %s %s in namespace %s as it has %d active Terraform Jobs", kind, info(name), ns, activeJobs)
+			o.addResult(r, now, ActionSkippedActiveJob, nil)
+			return nil
+		}
+	}
+
+	if o.ProtectIfReferenced {
+		references, err := o.References.ActiveReferences(ctx, ns, name)
+		if err != nil {
+			o.addResult(r, now, ActionError, err)
+			return errors.Wrapf(err, "failed to find the active references to %s %s in namespace %s", kind, name, ns)
+		}
+		if len(references) > 0 {
+			terraforms.Logger(ctx).Warnf("skipping %s %s in namespace %s as it is referenced by the active %s %s", kind, info(name), ns, o.ReferenceResource, strings.Join(references, ", "))
+			o.addResult(r, now, ActionSkippedReferenced, nil)
+			return nil
+		}
+	}
+
+	if o.DryRun {
+		terraforms.Logger(ctx).Infof("dry-run: would delete %s %s in namespace %s (age %s)", kind, info(name), ns, resourceAge(r, now))
+		err := o.deleteActiveTerraformJobs(ctx, ns, name)
+		if err != nil {
+			terraforms.Logger(ctx).Warnf("failed to find the active Terraform Jobs for %s %s in namespace %s: %s", kind, info(name), ns, err.Error())
+		}
+		if o.DeleteHelmRelease {
+			o.deleteHelmRelease(ctx, kind, ns, name)
+		}
+		if o.CascadeNamespaces {
+			err = o.deleteOwnedNamespaces(ctx, kind, ns, r)
+			if err != nil {
+				terraforms.Logger(ctx).Warnf("%s", err.Error())
+			}
+		}
+		err = o.runHook(ctx, "pre-delete-hook", o.PreDeleteHook, kind, ns, name)
+		if err != nil {
+			terraforms.Logger(ctx).Warnf("%s", err.Error())
+		}
+		err = o.runHook(ctx, "post-delete-hook", o.PostDeleteHook, kind, ns, name)
+		if err != nil {
+			terraforms.Logger(ctx).Warnf("%s", err.Error())
+		}
+		o.addResult(r, now, ActionWouldDelete, nil)
+		return nil
+	}
+
+	if o.WaitForJobs {
+		err := terraforms.WaitForActiveTerraformJobsWithOptions(ctx, o.KubeClient, ns, name, o.WaitForJobsTimeout, o.jobOptions())
+		if terraforms.IsWaitTimeout(err) {
+			terraforms.Logger(ctx).Warnf("not deleting %s %s in namespace %s as its Terraform Job is still active: %s", kind, info(name), ns, err.Error())
+			o.addResult(r, now, ActionSkippedActiveJob, nil)
+			return nil
+		}
+		if err != nil {
+			o.addResult(r, now, ActionError, err)
+			return errors.Wrapf(err, "failed to wait for active Terraform Jobs for %s %s in namespace %s", kind, name, ns)
+		}
+	}
+
+	if o.AnnotateBeforeDelete {
+		err := o.annotateBeforeDelete(ctx, gvr, r, now)
+		if err != nil {
+			o.addResult(r, now, ActionError, err)
+			return errors.Wrapf(err, "failed to annotate %s %s in namespace %s before deleting it", kind, name, ns)
+		}
+	}
+
+	err := o.runHook(ctx, "pre-delete-hook", o.PreDeleteHook, kind, ns, name)
+	if err != nil {
+		o.addResult(r, now, ActionError, err)
+		return errors.Wrapf(err, "not deleting %s %s in namespace %s", kind, name, ns)
+	}
 
-		err = o.deleteTerraform(ctx, kind, name)
+	err = o.deleteTerraform(ctx, gvr, kind, ns, r)
+	if err != nil {
+		o.addResult(r, now, ActionError, err)
+		return errors.Wrapf(err, "failed to delete %s %s in namespace %s", kind, name, ns)
+	}
+	o.addResult(r, now, ActionDeleted, nil)
+
+	terraforms.Logger(ctx).Infof("deleted %s %s in namespace %s (age %s)", kind, info(name), ns, resourceAge(r, now))
+	if o.VerifyDeleted {
+		o.verifyDeleted(ctx, gvr, kind, ns, name)
+	}
+	if o.DeleteHelmRelease {
+		o.deleteHelmRelease(ctx, kind, ns, name)
+	}
+	err = o.runHook(ctx, "post-delete-hook", o.PostDeleteHook, kind, ns, name)
+	if err != nil {
+		terraforms.Logger(ctx).Warnf("%s", err.Error())
+	}
+	if o.EmitEvents {
+		o.emitEvent(ctx, kind, r, now)
+	}
+	o.commentOnPullRequest(ctx, kind, ns, name, r.GetLabels())
+	return nil
+}
+
+// showTerraformPlans logs the cloud resources which would be destroyed for each resource. This is only advisory
+// so any failures are logged rather than failing the run
+func (o *Options) showTerraformPlans(ctx context.Context, kind string, resources []*unstructured.Unstructured) {
+	if o.Planner == nil {
+		o.Planner = &terraforms.StatePlanner{KubeClient: o.KubeClient}
+	}
+	for _, r := range resources {
+		name := r.GetName()
+		ns := o.resourceNamespace(r)
+		plan, err := o.Planner.PlanDestroy(ctx, ns, name)
 		if err != nil {
-			return errors.Wrapf(err, "failed to delete %s %s", kind, name)
+			log.Logger().Warnf("failed to find the cloud resources for %s %s in namespace %s: %s", kind, info(name), ns, err.Error())
+			continue
+		}
+		if plan == nil {
+			log.Logger().Infof("no Terraform state found for %s %s in namespace %s", kind, info(name), ns)
+			continue
 		}
+		log.Logger().Infof("%s %s in namespace %s would destroy %d cloud resources: %s", kind, info(name), ns, plan.Total(), plan.String())
+	}
+}
+
+// isTerminating returns true if the resource has a deletion timestamp so it is already being deleted
+func isTerminating(r *unstructured.Unstructured) bool {
+	deleted := r.GetDeletionTimestamp()
+	return deleted != nil && !deleted.IsZero()
+}
+
+// includeTerminating returns true if resources which are already terminating should be processed
+func (o *Options) includeTerminating() bool {
+	return o.IncludeTerminating || o.ForceRemoveFinalizers
+}
+
+// isStuckTerminating returns true if the resource has finalizers and has been terminating for longer than
+// the finalizer grace period
+func (o *Options) isStuckTerminating(r *unstructured.Unstructured, now time.Time) bool {
+	return isTerminating(r) && len(r.GetFinalizers()) > 0 && now.Sub(r.GetDeletionTimestamp().Time) > o.FinalizerGracePeriod
+}
 
-		log.Logger().Infof("deleted %s %s as it was created at: %s", kind, info(name), created.String())
+// removeFinalizers removes the finalizers from a resource which is stuck terminating
+func (o *Options) removeFinalizers(ctx context.Context, gvr schema.GroupVersionResource, kind string, r *unstructured.Unstructured, now time.Time) {
+	name := r.GetName()
+	ns := o.resourceNamespace(r)
+	deleted := r.GetDeletionTimestamp()
+	finalizers := strings.Join(r.GetFinalizers(), ", ")
+	if o.DryRun {
+		log.Logger().Warnf("dry-run: would FORCE REMOVE the finalizers %s from %s %s in namespace %s as it has been terminating since %s", finalizers, kind, info(name), ns, deleted.String())
+		o.addResult(r, now, ActionRemovedFinalizers, nil)
+		return
+	}
+	log.Logger().Warnf("FORCE REMOVING the finalizers %s from %s %s in namespace %s as it has been terminating since %s", finalizers, kind, info(name), ns, deleted.String())
+	client := dynkube.DynamicResource(o.DynamicClient, ns, gvr)
+	err := dynkube.RemoveFinalizers(ctx, client, name)
+	if err != nil {
+		log.Logger().Warnf("failed to remove the finalizers from %s %s in namespace %s: %s", kind, info(name), ns, err.Error())
+		o.addResult(r, now, ActionError, err)
+		return
 	}
+	o.addResult(r, now, ActionRemovedFinalizers, nil)
+}
 
-	err = o.gcLeases(ctx, createdTime)
+// verifyDeleted waits for the deleted resource to be removed logging whether it fully terminated
+func (o *Options) verifyDeleted(ctx context.Context, gvr schema.GroupVersionResource, kind, ns, name string) {
+	client := dynkube.DynamicResource(o.DynamicClient, ns, gvr)
+	err := dynkube.WaitForDeletion(ctx, client, name, o.VerifyDeletedTimeout)
+	if dynkube.IsWaitTimeout(err) {
+		terraforms.Logger(ctx).Warnf("%s %s in namespace %s is still terminating after %s", kind, info(name), ns, o.VerifyDeletedTimeout.String())
+		return
+	}
 	if err != nil {
-		return errors.Wrapf(err, "failed to GC leases")
+		terraforms.Logger(ctx).Warnf("failed to verify %s %s in namespace %s was removed: %s", kind, info(name), ns, err.Error())
+		return
+	}
+	terraforms.Logger(ctx).Infof("%s %s in namespace %s has fully terminated", kind, info(name), ns)
+}
+
+// logDecision logs at debug level why the resource is or is not being garbage collected when using --verbose
+func (o *Options) logDecision(kind string, c *Candidate, now time.Time) {
+	if !o.Verbose {
+		return
+	}
+	r := c.Resource
+	created := r.GetCreationTimestamp()
+	cutoff := terraforms.ResourceCutoff(r, o.cutoff(now), now)
+	decision := "delete"
+	if !c.ShouldDelete {
+		decision = "keep: " + c.Reason
 	}
+	log.Logger().Debugf("%s %s in namespace %s created: %s age: %s cutoff: %s decision: %s", kind, r.GetName(), o.resourceNamespace(r), created.Format(time.RFC3339), now.Sub(created.Time).Round(time.Second).String(), cutoff.Format(time.RFC3339), decision)
+}
 
-	err = o.gcTerraformState(ctx, createdTime)
+func (o *Options) deleteTerraform(ctx context.Context, gvr schema.GroupVersionResource, kind, ns string, r *unstructured.Unstructured) error {
+	name := r.GetName()
+	err := o.deleteActiveTerraformJobs(ctx, ns, name)
 	if err != nil {
-		return errors.Wrapf(err, "failed to GC terraform state")
+		return err
 	}
 
-	err = o.gcTerraformConfigMaps(ctx, createdTime)
+	terraforms.Logger(ctx).Infof("deleting %s %s in namespace %s", kind, info(name), ns)
+	err = o.retry(ctx, name, func() error {
+		return o.deleteTerraformResource(ctx, gvr, kind, ns, name)
+	})
 	if err != nil {
-		return errors.Wrapf(err, "failed to GC terraform configs")
+		return err
+	}
+
+	if o.CascadeOwned {
+		labelKey := o.OwnedLabel
+		if labelKey == "" {
+			labelKey = terraforms.LabelTerraform
+		}
+		for _, ownedNS := range o.ownedNamespaces(ctx, kind, ns, name, r.GetLabels()) {
+			err = o.retry(ctx, name, func() error {
+				return terraforms.DeleteOwnedResourcesWithLabel(ctx, o.KubeClient, ownedNS, labelKey, name)
+			})
+			if err != nil {
+				return errors.Wrapf(err, "failed to delete resources owned by %s %s in namespace %s", kind, name, ownedNS)
+			}
+		}
+	}
+	if o.CascadeNamespaces {
+		return o.deleteOwnedNamespaces(ctx, kind, ns, r)
 	}
 	return nil
 }
 
-func (o *Options) deleteTerraform(ctx context.Context, kind, name string) error {
-	ns := o.Namespace
-	err := terraforms.DeleteActiveTerraformJobs(ctx, o.KubeClient, ns, name)
+// ownedNamespaces returns the namespaces to delete the owned resources of a Terraform resource from which are its
+// own namespace and the workload namespace in its --namespace-from-label label if it has one
+func (o *Options) ownedNamespaces(ctx context.Context, kind, ns, name string, resourceLabels map[string]string) []string {
+	answer := []string{ns}
+	if o.NamespaceFromLabel == "" {
+		return answer
+	}
+	workloadNS := resourceLabels[o.NamespaceFromLabel]
+	if workloadNS == "" || workloadNS == ns {
+		return answer
+	}
+	if o.isExcludedNamespace(workloadNS) {
+		terraforms.Logger(ctx).Warnf("not deleting the resources owned by %s %s in namespace %s as it is excluded", kind, info(name), workloadNS)
+		return answer
+	}
+	return append(answer, workloadNS)
+}
+
+// failedCandidates returns the candidates which have at least one failed Terraform Job for --only-failed
+func (o *Options) failedCandidates(ctx context.Context, kind string, candidates []*Candidate) ([]*Candidate, error) {
+	var answer []*Candidate
+	for _, c := range candidates {
+		name := c.Resource.GetName()
+		ns := o.resourceNamespace(c.Resource)
+		jobList, err := terraforms.ListTerraformJobsWithOptions(ctx, o.KubeClient, ns, name, o.jobOptions())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list Terraform Jobs for %s %s in namespace %s", kind, name, ns)
+		}
+		failed := false
+		for i := range jobList {
+			if terraforms.JobStatus(&jobList[i]) == terraforms.JobStatusFailed {
+				failed = true
+				break
+			}
+		}
+		if !failed {
+			log.Logger().Debugf("excluding %s %s as it has no failed Terraform Jobs", kind, info(name))
+			continue
+		}
+		answer = append(answer, c)
+	}
+	return answer, nil
+}
+
+// jobOptions returns the options used to find and delete the Terraform Jobs of a resource
+func (o *Options) jobOptions() terraforms.JobOptions {
+	opts := o.FilterOptions.jobOptions()
+	opts.DryRun = o.DryRun
+	opts.GracePeriodSeconds = o.gracePeriod()
+	return opts
+}
+
+// gracePeriod returns the --grace-period-seconds or nil to use the API default
+func (o *Options) gracePeriod() *int64 {
+	if o.GracePeriodSeconds < 0 {
+		return nil
+	}
+	seconds := o.GracePeriodSeconds
+	return &seconds
+}
+
+// deleteActiveTerraformJobs deletes the active Terraform Jobs of the resource or logs them in dry run mode
+func (o *Options) deleteActiveTerraformJobs(ctx context.Context, ns, name string) error {
+	err := o.retry(ctx, name, func() error {
+		return terraforms.DeleteActiveTerraformJobsWithOptions(ctx, o.KubeClient, ns, name, o.jobOptions())
+	})
 	if err != nil {
 		return errors.Wrapf(err, "failed to delete active Terraform Jobs for namespace %s name %s", ns, name)
 	}
+	return nil
+}
 
-	log.Logger().Infof("deleting %s %s", kind, info(name))
-	c := &cmdrunner.Command{
-		Name: "kubectl",
-		Args: []string{"delete", kind, name},
+func (o *Options) deleteTerraformResource(ctx context.Context, gvr schema.GroupVersionResource, kind, ns, name string) error {
+	if o.limiter != nil {
+		err := o.limiter.Wait(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "failed to wait for the delete rate limit")
+		}
 	}
-	_, err = o.CommandRunner(c)
+	if o.UseKubectl {
+		c := &cmdrunner.Command{
+			Name: "kubectl",
+			Args: []string{"delete", kind, name, "-n", ns},
+		}
+		if o.GracePeriodSeconds >= 0 {
+			c.Args = append(c.Args, fmt.Sprintf("--grace-period=%d", o.GracePeriodSeconds))
+		}
+		_, err := o.CommandRunner(c)
+		if err != nil {
+			return errors.Wrapf(err, "failed to run %s", c.CLI())
+		}
+		return nil
+	}
+	policy := metav1.DeletionPropagation(o.PropagationPolicy)
+	if policy == "" {
+		policy = metav1.DeletePropagationBackground
+	}
+	client := dynkube.DynamicResource(o.Metrics.instrument(o.DynamicClient), ns, gvr)
+	err := dynkube.DeleteResource(ctx, client, name, metav1.DeleteOptions{
+		PropagationPolicy:  &policy,
+		GracePeriodSeconds: o.gracePeriod(),
+	})
 	if err != nil {
-		return errors.Wrapf(err, "failed to run %s", c.CLI())
+		return errors.Wrapf(err, "failed to delete %s %s in namespace %s", kind, name, ns)
 	}
 	return nil
 }
@@ -170,14 +1112,165 @@ func (o *Options) Validate() error {
 	if o.CommandRunner == nil {
 		o.CommandRunner = cmdrunner.QuietCommandRunner
 	}
-	var err error
-	o.KubeClient, o.Namespace, err = kube.LazyCreateKubeClientAndNamespace(o.KubeClient, o.Namespace)
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	if o.LogOut == nil {
+		o.LogOut = os.Stderr
+	}
+	if o.Clock == nil {
+		// lets default the clock before any delete workers use it
+		o.Clock = clock.RealClock{}
+	}
+	if o.SlackWebhook == "" {
+		o.SlackWebhook = os.Getenv(slackWebhookEnvVar)
+	}
+	if o.GitHubToken == "" {
+		o.GitHubToken = os.Getenv(githubTokenEnvVar)
+	}
+	if o.Commenter == nil && o.GitHubToken != "" {
+		o.Commenter = &GitHubCommenter{Token: o.GitHubToken, URL: o.GitHubURL}
+	}
+	err := o.FilterOptions.Validate()
 	if err != nil {
-		return errors.Wrapf(err, "failed to create kube client")
+		return err
+	}
+	if o.config != nil && o.config.Concurrency > 0 && !o.flagChanged("concurrency") {
+		o.Concurrency = o.config.Concurrency
 	}
-	o.DynamicClient, err = kube.LazyCreateDynamicClient(o.DynamicClient)
+	if o.Output != "" && stringhelpers.StringArrayIndex(outputFormats, o.Output) < 0 {
+		return options.InvalidOption("output", o.Output, outputFormats)
+	}
+	err = o.parseTemplate()
+	if err != nil {
+		return err
+	}
+	for flag, hook := range map[string]string{"pre-delete-hook": o.PreDeleteHook, "post-delete-hook": o.PostDeleteHook} {
+		_, err := parseHook(flag, hook)
+		if err != nil {
+			return options.InvalidOptionf(flag, hook, err.Error())
+		}
+	}
+	_, err = MetricLabelNames(o.LabelsAsMetrics)
 	if err != nil {
-		return errors.Wrapf(err, "failed to craete dynamic client")
+		return options.InvalidOptionf("labels-as-metrics", o.LabelsAsMetrics, err.Error())
+	}
+	if o.Sort != "" && stringhelpers.StringArrayIndex(sortOrders, o.Sort) < 0 {
+		return options.InvalidOption("sort", o.Sort, sortOrders)
+	}
+	if o.LogFormat != "" && stringhelpers.StringArrayIndex(logFormats, o.LogFormat) < 0 {
+		return options.InvalidOption("log-format", o.LogFormat, logFormats)
+	}
+	if o.QPS < 0 {
+		return options.InvalidOptionf("qps", o.QPS, "must not be negative")
+	}
+	o.limiter = nil
+	if o.QPS > 0 {
+		burst := o.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		o.limiter = rate.NewLimiter(rate.Limit(o.QPS), burst)
+	}
+	if o.ReportOnlyErrors && o.Verbose {
+		return options.InvalidOptionf("report-only-errors", o.ReportOnlyErrors, "cannot be used with --verbose")
+	}
+	if o.Quiet && o.Verbose {
+		return options.InvalidOptionf("verbose", o.Verbose, "cannot be used with --quiet")
+	}
+	if o.DeleteEmptyNamespace {
+		if o.EmptyNamespaceSelector == "" {
+			return options.MissingOption("empty-namespace-selector")
+		}
+		_, err = labels.Parse(o.EmptyNamespaceSelector)
+		if err != nil {
+			return options.InvalidOptionf("empty-namespace-selector", o.EmptyNamespaceSelector, err.Error())
+		}
+	}
+	if o.RecheckEmptyNamespaces && !o.DeleteEmptyNamespace {
+		return options.InvalidOptionf("recheck-empty-namespaces", o.RecheckEmptyNamespaces, "requires --delete-empty-namespace")
+	}
+	if o.Watch {
+		if o.Interval <= 0 {
+			return options.InvalidOptionf("interval", o.Interval, "the interval should be positive")
+		}
+		if len(o.Names) > 0 {
+			return options.InvalidOptionf("watch", o.Watch, "resource names cannot be specified when watching")
+		}
+	}
+	if o.NamespaceFromLabel != "" && !o.CascadeOwned {
+		return options.InvalidOptionf("namespace-from-label", o.NamespaceFromLabel, "requires --cascade-owned")
+	}
+	if o.Mark && o.Sweep {
+		return options.InvalidOptionf("mark", o.Mark, "cannot be used with --sweep")
+	}
+	if o.SkipActive && o.WaitForJobs {
+		return options.InvalidOptionf("skip-active", o.SkipActive, "cannot be used with --wait-for-jobs")
+	}
+	if len(o.Names) > 0 && o.multiNamespace() {
+		return options.InvalidOptionf("all-namespaces", o.AllNamespaces, "resource names cannot be specified when querying more than one namespace")
+	}
+	if o.Resume && o.CheckpointFile == "" {
+		return options.MissingOption("checkpoint-file")
+	}
+	if o.SinceLastRun && o.StateConfigMap == "" {
+		return options.MissingOption("state-configmap")
+	}
+	if o.SinceLastRun && (o.KeepLast > 0 || o.Retention != "") {
+		return options.InvalidOptionf("since-last-run", o.SinceLastRun, "cannot be used with --keep-last or --retention as the resources kept by a keep label are not listed")
+	}
+	if o.StateConfigMap != "" {
+		_, _, err = o.stateConfigMapRef()
+		if err != nil {
+			return err
+		}
+	}
+	if o.CheckpointFile != "" && o.DryRun {
+		return options.InvalidOptionf("checkpoint-file", o.CheckpointFile, "cannot be used with --dry-run")
+	}
+	if o.GracePeriodSeconds < -1 {
+		return options.InvalidOptionf("grace-period-seconds", o.GracePeriodSeconds, "should be -1 for the API default or a non negative number of seconds")
+	}
+	if o.PropagationPolicy != "" && stringhelpers.StringArrayIndex(propagationPolicies, o.PropagationPolicy) < 0 {
+		return options.InvalidOption("propagation-policy", o.PropagationPolicy, propagationPolicies)
+	}
+	o.KubeClient, o.DynamicClient, err = o.LazyCreateClients(o.KubeClient, o.DynamicClient)
+	if err != nil {
+		return err
+	}
+	if o.ProtectIfReferenced && o.References == nil {
+		gvr, err := parseReferenceResource(o.ReferenceResource)
+		if err != nil {
+			return err
+		}
+		o.References = &terraforms.LabelReferenceChecker{
+			DynamicClient: o.DynamicClient,
+			Resource:      gvr,
+			Label:         o.ReferenceLabel,
+		}
+	}
+	if !o.SkipCRDCheck {
+		return o.checkCRD()
+	}
+	return nil
+}
+
+// checkCRD verifies the CRD of each resource is installed so that we can fail with a friendly error. If discovery
+// fails for some other reason such as permissions we carry on and let the query fail if the CRD is missing
+func (o *Options) checkCRD() error {
+	gvrs, err := o.GroupVersionResources()
+	if err != nil {
+		return err
+	}
+	for _, gvr := range gvrs {
+		exists, err := dynkube.ResourceExists(o.KubeClient.Discovery(), gvr)
+		if err != nil {
+			log.Logger().Debugf("could not check the CRD for %s is installed: %s", gvr.String(), err.Error())
+			continue
+		}
+		if !exists {
+			return errors.Errorf("%s CRD not found for version %s; is the operator installed? use --skip-crd-check to skip this check", o.ResourceKindName(nil, gvr), gvr.GroupVersion().String())
+		}
 	}
 	return nil
 }
@@ -190,80 +1283,122 @@ func (o *Options) GetContext() context.Context {
 	return o.Ctx
 }
 
-func (o *Options) gcLeases(ctx context.Context, createdTime *metav1.Time) error {
-	leaseInterface := o.KubeClient.CoordinationV1().Leases(o.Namespace)
-	list, err := leaseInterface.List(ctx, metav1.ListOptions{
+func (o *Options) gcLeases(ctx context.Context, ns string, createdTime *metav1.Time) error {
+	list, err := o.KubeClient.CoordinationV1().Leases(ns).List(ctx, metav1.ListOptions{
 		LabelSelector: terraformStateSelector,
 	})
 	if apierrors.IsNotFound(err) {
 		err = nil
 	}
 	if err != nil {
-		return errors.Wrapf(err, "failed to list Leases in namespace %s with selector %s", o.Namespace, terraformStateSelector)
+		return errors.Wrapf(err, "failed to list Leases in namespace %s with selector %s", ns, terraformStateSelector)
 	}
 	if list == nil {
 		return nil
 	}
 
 	for _, r := range list.Items {
+		if o.isExcludedNamespace(r.Namespace) {
+			log.Logger().Debugf("not removing Lease %s as its namespace %s is excluded", r.Name, r.Namespace)
+			continue
+		}
 		created := r.GetCreationTimestamp()
 		if !created.Before(createdTime) {
 			log.Logger().Debugf("not removing Lease %s as it was created at %s", r.Name, created.String())
 			continue
 		}
-		err = leaseInterface.Delete(ctx, r.Name, metav1.DeleteOptions{})
+		if o.DryRun {
+			log.Logger().Infof("dry-run: would delete Lease %s", r.Name)
+			continue
+		}
+		err = o.KubeClient.CoordinationV1().Leases(r.Namespace).Delete(ctx, r.Name, metav1.DeleteOptions{})
 		if err != nil {
-			return errors.Wrapf(err, "failed to delete Lease %s in namespace %s", r.Name, o.Namespace)
+			return errors.Wrapf(err, "failed to delete Lease %s in namespace %s", r.Name, r.Namespace)
 		}
 		log.Logger().Infof("deleted Lease %s", r.Name)
 	}
 	return nil
 }
 
-func (o *Options) gcTerraformState(ctx context.Context, createdTime *metav1.Time) error {
-	secretInterface := o.KubeClient.CoreV1().Secrets(o.Namespace)
+func (o *Options) gcOrphanJobs(ctx context.Context, ns string, createdTime *metav1.Time) error {
+	jobList, err := terraforms.ListOrphanedTerraformJobs(ctx, o.KubeClient, o.DynamicClient, ns)
+	if err != nil {
+		return err
+	}
 
-	list, err := secretInterface.List(ctx, metav1.ListOptions{
+	policy := metav1.DeletePropagationBackground
+	for i := range jobList {
+		r := &jobList[i]
+		if o.isExcludedNamespace(r.Namespace) {
+			log.Logger().Debugf("not removing orphaned Job %s as its namespace %s is excluded", r.Name, r.Namespace)
+			continue
+		}
+		created := r.GetCreationTimestamp()
+		if !created.Before(createdTime) {
+			log.Logger().Debugf("not removing orphaned Job %s as it was created at %s", r.Name, created.String())
+			continue
+		}
+		if o.DryRun {
+			log.Logger().Infof("dry-run: would delete orphaned Job %s in namespace %s", info(r.Name), r.Namespace)
+			continue
+		}
+		err = o.KubeClient.BatchV1().Jobs(r.Namespace).Delete(ctx, r.Name, metav1.DeleteOptions{PropagationPolicy: &policy})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete Job %s in namespace %s", r.Name, r.Namespace)
+		}
+		log.Logger().Infof("deleted orphaned Job %s in namespace %s", info(r.Name), r.Namespace)
+	}
+	return nil
+}
+
+func (o *Options) gcTerraformState(ctx context.Context, ns string, createdTime *metav1.Time) error {
+	list, err := o.KubeClient.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{
 		LabelSelector: terraformStateSelector,
 	})
 	if apierrors.IsNotFound(err) {
 		err = nil
 	}
 	if err != nil {
-		return errors.Wrapf(err, "failed to list Secrets in namespace %s with selector %s", o.Namespace, terraformStateSelector)
+		return errors.Wrapf(err, "failed to list Secrets in namespace %s with selector %s", ns, terraformStateSelector)
 	}
 	if list == nil {
 		return nil
 	}
 
 	for _, r := range list.Items {
+		if o.isExcludedNamespace(r.Namespace) {
+			log.Logger().Debugf("not removing Secret %s as its namespace %s is excluded", r.Name, r.Namespace)
+			continue
+		}
 		created := r.GetCreationTimestamp()
 		if !created.Before(createdTime) {
 			log.Logger().Debugf("not removing Secret %s as it was created at %s", r.Name, created.String())
 			continue
 		}
-		err = secretInterface.Delete(ctx, r.Name, metav1.DeleteOptions{})
+		if o.DryRun {
+			log.Logger().Infof("dry-run: would delete Secret %s", r.Name)
+			continue
+		}
+		err = o.KubeClient.CoreV1().Secrets(r.Namespace).Delete(ctx, r.Name, metav1.DeleteOptions{})
 		if err != nil {
-			return errors.Wrapf(err, "failed to delete Secret %s in namespace %s", r.Name, o.Namespace)
+			return errors.Wrapf(err, "failed to delete Secret %s in namespace %s", r.Name, r.Namespace)
 		}
 		log.Logger().Infof("deleted Secret %s", r.Name)
 	}
 	return nil
 }
 
-func (o *Options) gcTerraformConfigMaps(ctx context.Context, createdTime *metav1.Time) error {
+func (o *Options) gcTerraformConfigMaps(ctx context.Context, ns string, createdTime *metav1.Time) error {
 	if o.TerraformConfigMapPrefix == "" {
 		o.TerraformConfigMapPrefix = defaultTerraformConfigMapPrefix
 	}
 
-	configMapInterface := o.KubeClient.CoreV1().ConfigMaps(o.Namespace)
-
-	list, err := configMapInterface.List(ctx, metav1.ListOptions{})
+	list, err := o.KubeClient.CoreV1().ConfigMaps(ns).List(ctx, metav1.ListOptions{})
 	if apierrors.IsNotFound(err) {
 		err = nil
 	}
 	if err != nil {
-		return errors.Wrapf(err, "failed to list ConfigMaps in namespace %s with selector %s", o.Namespace, terraformStateSelector)
+		return errors.Wrapf(err, "failed to list ConfigMaps in namespace %s with selector %s", ns, terraformStateSelector)
 	}
 	if list == nil {
 		return nil
@@ -273,14 +1408,22 @@ func (o *Options) gcTerraformConfigMaps(ctx context.Context, createdTime *metav1
 		if !strings.HasPrefix(r.Name, o.TerraformConfigMapPrefix) {
 			continue
 		}
+		if o.isExcludedNamespace(r.Namespace) {
+			log.Logger().Debugf("not removing ConfigMap %s as its namespace %s is excluded", r.Name, r.Namespace)
+			continue
+		}
 		created := r.GetCreationTimestamp()
 		if !created.Before(createdTime) {
 			log.Logger().Debugf("not removing ConfigMap %s as it was created at %s", r.Name, created.String())
 			continue
 		}
-		err = configMapInterface.Delete(ctx, r.Name, metav1.DeleteOptions{})
+		if o.DryRun {
+			log.Logger().Infof("dry-run: would delete ConfigMap %s", r.Name)
+			continue
+		}
+		err = o.KubeClient.CoreV1().ConfigMaps(r.Namespace).Delete(ctx, r.Name, metav1.DeleteOptions{})
 		if err != nil {
-			return errors.Wrapf(err, "failed to delete ConfigMap %s in namespace %s", r.Name, o.Namespace)
+			return errors.Wrapf(err, "failed to delete ConfigMap %s in namespace %s", r.Name, r.Namespace)
 		}
 		log.Logger().Infof("deleted ConfigMap %s", r.Name)
 	}
@@ -1,18 +1,47 @@
 package gc_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
 	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
 	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/fakerunner"
+	fakeinput "github.com/jenkins-x/jx-helpers/v3/pkg/input/fake"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 import (
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 var (
@@ -59,7 +88,7 @@ metadata:
 func TestGC(t *testing.T) {
 	ns := "jx"
 
-	useKubectl := true
+	useKubectl := false
 
 	scheme := runtime.NewScheme()
 
@@ -84,6 +113,7 @@ func TestGC(t *testing.T) {
 
 	_, o := gc.NewCmdGC()
 	o.Namespace = ns
+	o.UseKubectl = useKubectl
 	o.DynamicClient = fakeDynClient
 	o.CommandRunner = runner.Run
 	o.KubeClient = fake.NewSimpleClientset()
@@ -106,3 +136,4036 @@ func TestGC(t *testing.T) {
 		t.Logf("has remaining Terraform %s\n", list.Items[0].GetName())
 	}
 }
+
+func TestGCDryRun(t *testing.T) {
+	ns := "jx"
+
+	scheme := runtime.NewScheme()
+
+	now := time.Now()
+	recentTime := now.Add(-1 * time.Hour)
+	oldTime := now.Add(-5 * time.Hour)
+
+	fn := func(idx int, u *unstructured.Unstructured) {
+		t := oldTime
+		if idx > 1 {
+			t = recentTime
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: t,
+		})
+	}
+
+	runner := &fakerunner.FakeRunner{}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(scheme, dynObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.DryRun = true
+	o.DynamicClient = fakeDynClient
+	o.CommandRunner = runner.Run
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	require.Equal(t, 2, o.Deleted, "should have found resources to delete")
+	require.Empty(t, runner.OrderedCommands, "should not have run any commands in dry run mode")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	require.Len(t, list.Items, 3, "should not have removed any resources")
+}
+
+func TestGCAllNamespaces(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	namespaces := []string{"test-1", "test-2", "test-2"}
+
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetNamespace(namespaces[idx])
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	runner := &fakerunner.FakeRunner{}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(scheme, dynObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.AllNamespaces = true
+	o.UseKubectl = true
+	o.DynamicClient = fakeDynClient
+	o.CommandRunner = runner.Run
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	require.Equal(t, 3, o.Deleted, "should have deleted resources in all namespaces")
+	runner.ExpectResults(t,
+		fakerunner.FakeResult{CLI: "kubectl delete Terraform tf-myrepo-pr456-myctx-1 -n test-1"},
+		fakerunner.FakeResult{CLI: "kubectl delete Terraform tf-myrepo-pr456-myctx-2 -n test-2"},
+		fakerunner.FakeResult{CLI: "kubectl delete Terraform tf-myrepo-pr999-myctx-3 -n test-2"},
+	)
+}
+
+func TestGCDeleteUsesNamespace(t *testing.T) {
+	ns := "jx"
+
+	scheme := runtime.NewScheme()
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	var deleteArgs [][]string
+	runner := func(c *cmdrunner.Command) (string, error) {
+		deleteArgs = append(deleteArgs, c.Args)
+		return "", nil
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(scheme, dynObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.UseKubectl = true
+	o.DynamicClient = fakeDynClient
+	o.CommandRunner = runner
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	require.Len(t, deleteArgs, 3, "should have deleted all resources")
+	for _, args := range deleteArgs {
+		require.Equal(t, "delete", args[0], "command args %v", args)
+		require.Contains(t, strings.Join(args, " "), "-n "+ns, "command args %v", args)
+	}
+}
+
+func TestGCListError(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	dynObjects := tftests.ParseUnstructureds(t, nil, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(scheme, dynObjects...)
+	fakeDynClient.PrependReactor("list", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(terraforms.TerraformResource.GroupResource(), "", errors.New("rbac denied"))
+	})
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should have failed to list resources")
+	require.True(t, apierrors.IsForbidden(errors.Cause(err)), "should have returned the forbidden error but got %s", err.Error())
+}
+
+func TestGCTTLAnnotation(t *testing.T) {
+	ns := "jx"
+
+	scheme := runtime.NewScheme()
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	ttls := []string{"24h", "not-a-duration", ""}
+
+	fn := func(idx int, u *unstructured.Unstructured) {
+		if ttls[idx] != "" {
+			u.SetAnnotations(map[string]string{terraforms.AnnotationTTL: ttls[idx]})
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(scheme, dynObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	require.Len(t, list.Items, 1, "should have only kept the resource with a TTL annotation")
+	require.Equal(t, "tf-myrepo-pr456-myctx-1", list.Items[0].GetName(), "remaining resource")
+}
+
+func TestGCConcurrency(t *testing.T) {
+	ns := "jx"
+	count := 20
+
+	scheme := runtime.NewScheme()
+
+	var resources []string
+	for i := 0; i < count; i++ {
+		resources = append(resources, fmt.Sprintf(`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+  name: tf-myrepo-pr%d-myctx-1
+  namespace: jx
+`, i))
+	}
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, resources)
+	fakeDynClient := tftests.NewFakeDynClient(scheme, dynObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.Concurrency = 4
+	// lets not slow the test down with the default delete rate limit
+	o.QPS = 0
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+	require.Equal(t, count, o.Deleted, "deleted count")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	require.Empty(t, list.Items, "should have removed all the resources")
+}
+
+func TestGCConcurrentLogPrefix(t *testing.T) {
+	ns := "jx"
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	var names []string
+	var kubeObjects []runtime.Object
+	for _, r := range dynObjects {
+		name := r.(*unstructured.Unstructured).GetName()
+		names = append(names, name)
+		kubeObjects = append(kubeObjects, &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+			Status:     batchv1.JobStatus{Active: 1},
+		})
+	}
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.Concurrency = 2
+	o.QPS = 0
+	o.PostDeleteHook = "echo {{.Name}}"
+	o.Verbose = true
+	o.CommandRunner = func(c *cmdrunner.Command) (string, error) {
+		return "", nil
+	}
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset(kubeObjects...)
+
+	var err error
+	output := log.CaptureOutput(func() {
+		err = o.Run()
+	})
+	require.NoError(t, err, "failed to run gc command")
+	require.Equal(t, len(names), o.Deleted, "deleted count")
+
+	t.Logf("got output:\n%s\n", output)
+	for _, name := range names {
+		assert.Contains(t, output, name+": deleted terraform apply Job", "should prefix the Job cleanup")
+		assert.Contains(t, output, name+": running the post-delete-hook", "should prefix the hooks")
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.Contains(line, "deleted") && !strings.Contains(line, "deleting") && !strings.Contains(line, "hook") {
+			continue
+		}
+		for _, name := range names {
+			if strings.Contains(line, name) {
+				assert.Contains(t, line, name+": ", "each line logged by a worker should be prefixed with its resource")
+			}
+		}
+	}
+}
+
+// TestGCConcurrentResults deletes many resources concurrently with some failures checking the results, summary
+// and metrics include every worker. Run it via make test-race to check for data races
+func TestGCConcurrentResults(t *testing.T) {
+	ns := "jx"
+	count := 200
+
+	var resources []string
+	for i := 0; i < count; i++ {
+		resources = append(resources, fmt.Sprintf(`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+    pr: pr%d
+  name: tf-myrepo-pr%d-myctx-1
+  namespace: jx
+`, i%10, i))
+	}
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, resources)...)
+	fakeDynClient.PrependReactor("delete", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.DeleteAction).GetName()
+		if strings.HasSuffix(name, "0-myctx-1") {
+			return true, nil, errors.New("simulated failure")
+		}
+		return false, nil, nil
+	})
+
+	reg := prometheus.NewRegistry()
+	metrics, err := gc.NewMetricsWithLabels(reg, []string{"pr"})
+	require.NoError(t, err, "failed to create metrics")
+
+	out := &bytes.Buffer{}
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.Concurrency = 16
+	o.MaxDelete = 0
+	o.Retries = 0
+	o.QPS = 0
+	o.Output = gc.OutputJSONLines
+	o.Out = out
+	o.Metrics = metrics
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	result, err := o.RunWithResult(o.GetContext())
+	require.Error(t, err, "should report the failed deletions")
+	require.NotNil(t, result, "should have a result")
+
+	expectedErrors := count / 10
+	expectedDeleted := count - expectedErrors
+	assert.Equal(t, expectedDeleted, o.Deleted, "deleted count")
+	assert.Equal(t, expectedDeleted, result.Deleted, "result deleted count")
+	assert.Equal(t, expectedErrors, result.Errors, "result error count")
+	assert.Len(t, result.Resources, count, "resource results")
+	assert.Len(t, strings.Split(strings.TrimSpace(out.String()), "\n"), count, "streamed results")
+
+	families, err := reg.Gather()
+	require.NoError(t, err, "failed to gather metrics")
+	values := map[string]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			if m.GetCounter() != nil {
+				values[f.GetName()] += m.GetCounter().GetValue()
+			}
+		}
+	}
+	assert.Equal(t, float64(expectedDeleted), values["jxtest_gc_deleted_total"], "deleted metric")
+	assert.Equal(t, float64(expectedErrors), values["jxtest_gc_errors_total"], "errors metric")
+}
+
+func TestGCRetries(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	for _, retries := range []int{3, 1} {
+		attempts := 0
+		runner := &fakerunner.FakeRunner{
+			CommandRunner: func(c *cmdrunner.Command) (string, error) {
+				attempts++
+				if attempts <= 2 {
+					return "", errors.Errorf("failed attempt %d", attempts)
+				}
+				return "", nil
+			},
+		}
+
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:1])
+		fakeDynClient := tftests.NewFakeDynClient(scheme, dynObjects...)
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.UseKubectl = true
+		o.Retries = retries
+		o.RetryBackoff = time.Millisecond
+		o.DynamicClient = fakeDynClient
+		o.CommandRunner = runner.Run
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		if retries < 2 {
+			require.Error(t, err, "should have failed after %d retries", retries)
+			require.Equal(t, 0, o.Deleted, "deleted count for retries %d", retries)
+			continue
+		}
+		require.NoError(t, err, "failed to run gc command with retries %d", retries)
+		require.Equal(t, 1, o.Deleted, "deleted count for retries %d", retries)
+		require.Len(t, runner.OrderedCommands, 3, "command invocations for retries %d", retries)
+	}
+}
+
+func TestGCOutputJSON(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	now := time.Now()
+	recentTime := now.Add(-1 * time.Hour)
+	oldTime := now.Add(-5 * time.Hour)
+
+	fn := func(idx int, u *unstructured.Unstructured) {
+		t := oldTime
+		if idx == 1 {
+			u.SetLabels(map[string]string{"kind": "jx-test", "keep": "yes"})
+		}
+		if idx > 1 {
+			t = recentTime
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: t,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(scheme, dynObjects...)
+
+	out := &bytes.Buffer{}
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Output = "json"
+	o.Out = out
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	result := &gc.RunResult{}
+	err = json.Unmarshal(out.Bytes(), result)
+	require.NoError(t, err, "failed to parse output %s", out.String())
+
+	assert.Equal(t, "kind=jx-test", result.Selector, "result.Selector")
+	assert.False(t, result.Cutoff.IsZero(), "result.Cutoff should be set")
+
+	actions := map[string]string{}
+	for _, r := range result.Resources {
+		assert.Equal(t, "jx", r.Namespace, "namespace of %s", r.Name)
+		assert.NotEmpty(t, r.Age, "age of %s", r.Name)
+		actions[r.Name] = r.Action
+	}
+	assert.Equal(t, map[string]string{
+		"tf-myrepo-pr456-myctx-1": gc.ActionDeleted,
+		"tf-myrepo-pr456-myctx-2": gc.ActionKeptLabel,
+		"tf-myrepo-pr999-myctx-3": gc.ActionKeptTooYoung,
+	}, actions, "actions")
+}
+
+func TestGCMaxDelete(t *testing.T) {
+	ns := "jx"
+	count := 5
+
+	var resources []string
+	for i := 0; i < count; i++ {
+		resources = append(resources, fmt.Sprintf(`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+  name: tf-myrepo-pr%d-myctx-1
+  namespace: jx
+`, i))
+	}
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, resources)
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.MaxDelete = 2
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should have refused to delete more than the limit")
+	assert.Contains(t, err.Error(), "refusing to delete 5 Terraform resources", "error message")
+	assert.Equal(t, 0, o.Deleted, "deleted count")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	require.Len(t, list.Items, count, "should not have removed any resources")
+}
+
+func TestGCConfirmation(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	for _, answer := range []string{"no", "yes"} {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+		fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.Input = &fakeinput.FakeInput{
+			Values: map[string]string{
+				"Delete 3 Terraform resources?": answer,
+			},
+		}
+		o.ReportFile = filepath.Join(t.TempDir(), "report.json")
+		o.DynamicClient = fakeDynClient
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command")
+		assert.FileExists(t, o.ReportFile, "should write the report when %s", answer)
+
+		list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+		require.NoError(t, err, "failed to list resources")
+		if answer == "yes" {
+			assert.Equal(t, 3, o.Deleted, "deleted count when confirmed")
+			assert.Empty(t, list.Items, "should have removed the resources when confirmed")
+		} else {
+			assert.Equal(t, 0, o.Deleted, "deleted count when declined")
+			assert.Len(t, list.Items, 3, "should not have removed the resources when declined")
+			require.NotNil(t, o.Result, "should report the result when declined")
+			assert.Equal(t, 3, o.Result.Kept, "kept count when declined")
+			for _, rr := range o.Result.Resources {
+				assert.Equal(t, gc.ActionKeptDeclined, rr.Action, "action of %s when declined", rr.Name)
+			}
+		}
+	}
+}
+
+func TestGCSelectors(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		if idx == 0 {
+			labels := u.GetLabels()
+			labels["repo"] = "otherrepo"
+			u.SetLabels(labels)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Selectors = []string{"kind=jx-test", "repo=myrepo"}
+	o.ExcludeSelectors = []string{"pr=pr-999"}
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Equal(t, 1, o.Deleted, "deleted count")
+	assert.Equal(t, "kind=jx-test,repo=myrepo", o.Result.Selector, "result.Selector")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+
+	var names []string
+	for _, r := range list.Items {
+		names = append(names, r.GetName())
+	}
+	assert.ElementsMatch(t, []string{"tf-myrepo-pr456-myctx-1", "tf-myrepo-pr999-myctx-3"}, names, "remaining resources")
+}
+
+func TestGCNameRegexp(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	resources := append([]string{}, testResources...)
+	resources = append(resources, `apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+    repo: otherrepo
+  name: tf-otherrepo-pr456-myctx-4
+  namespace: jx
+`)
+	dynObjects := tftests.ParseUnstructureds(t, fn, resources)
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.NameRegexp = "^tf-myrepo-pr456-"
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Equal(t, 2, o.Deleted, "deleted count")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+
+	var names []string
+	for _, r := range list.Items {
+		names = append(names, r.GetName())
+	}
+	assert.ElementsMatch(t, []string{"tf-myrepo-pr999-myctx-3", "tf-otherrepo-pr456-myctx-4"}, names, "remaining resources")
+}
+
+func TestGCCascadeOwned(t *testing.T) {
+	ns := "jx"
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	for _, cascade := range []bool{false, true} {
+		kubeClient := fake.NewSimpleClientset(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "tf-myrepo-pr456-myctx-1-state",
+					Namespace: ns,
+					Labels:    map[string]string{terraforms.LabelTerraform: "tf-myrepo-pr456-myctx-1"},
+				},
+			},
+		)
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = ns
+		o.CascadeOwned = cascade
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, testResources)...)
+		o.KubeClient = kubeClient
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command")
+
+		secrets, err := kubeClient.CoreV1().Secrets(ns).List(o.GetContext(), metav1.ListOptions{})
+		require.NoError(t, err, "failed to list Secrets")
+		if cascade {
+			assert.Empty(t, secrets.Items, "should have removed the owned Secret")
+		} else {
+			assert.Len(t, secrets.Items, 1, "should not have removed the owned Secret")
+		}
+	}
+}
+
+func TestGCCascadeNamespaces(t *testing.T) {
+	ns := "jx"
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+		u.SetUID(types.UID(fmt.Sprintf("uid-%d", idx)))
+	}
+	ownedBy := func(name string, uid types.UID) *corev1.Namespace {
+		return &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "tf.isaaguilar.com/v1alpha1", Kind: "Terraform", Name: "owner", UID: uid},
+				},
+			},
+		}
+	}
+
+	for _, dryRun := range []bool{false, true} {
+		kubeClient := fake.NewSimpleClientset(
+			ownedBy("tf-myrepo-pr456-myctx-1-test", "uid-0"),
+			ownedBy("kube-system", "uid-0"),
+			ownedBy("someone-else", "another-uid"),
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unowned"}},
+		)
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = ns
+		o.DryRun = dryRun
+		o.CascadeNamespaces = true
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, testResources)...)
+		o.KubeClient = kubeClient
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command")
+
+		list, err := kubeClient.CoreV1().Namespaces().List(o.GetContext(), metav1.ListOptions{})
+		require.NoError(t, err, "failed to list namespaces")
+		var names []string
+		for i := range list.Items {
+			names = append(names, list.Items[i].Name)
+		}
+		if dryRun {
+			assert.Contains(t, names, "tf-myrepo-pr456-myctx-1-test", "should not have deleted the owned namespace in dry run")
+		} else {
+			assert.NotContains(t, names, "tf-myrepo-pr456-myctx-1-test", "should have deleted the owned namespace")
+		}
+		assert.Contains(t, names, "kube-system", "should not have deleted the protected namespace")
+		assert.Contains(t, names, "someone-else", "should not have deleted the namespace owned by another resource")
+		assert.Contains(t, names, "unowned", "should not have deleted the unowned namespace")
+	}
+}
+
+// deleteOptionsDynClient records the options passed when deleting resources as the fake dynamic client ignores them
+type deleteOptionsDynClient struct {
+	dynamic.Interface
+	deleteOptions []metav1.DeleteOptions
+}
+
+func (c *deleteOptionsDynClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &deleteOptionsResource{NamespaceableResourceInterface: c.Interface.Resource(gvr), client: c}
+}
+
+type deleteOptionsResource struct {
+	dynamic.NamespaceableResourceInterface
+	client *deleteOptionsDynClient
+}
+
+func (r *deleteOptionsResource) Namespace(ns string) dynamic.ResourceInterface {
+	return &deleteOptionsNamespacedResource{ResourceInterface: r.NamespaceableResourceInterface.Namespace(ns), client: r.client}
+}
+
+type deleteOptionsNamespacedResource struct {
+	dynamic.ResourceInterface
+	client *deleteOptionsDynClient
+}
+
+func (r *deleteOptionsNamespacedResource) Delete(ctx context.Context, name string, options metav1.DeleteOptions, subresources ...string) error {
+	r.client.deleteOptions = append(r.client.deleteOptions, options)
+	return r.ResourceInterface.Delete(ctx, name, options, subresources...)
+}
+
+func TestGCPropagationPolicy(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	testCases := []struct {
+		policy   string
+		expected metav1.DeletionPropagation
+	}{
+		{policy: "", expected: metav1.DeletePropagationBackground},
+		{policy: "Foreground", expected: metav1.DeletePropagationForeground},
+		{policy: "Orphan", expected: metav1.DeletePropagationOrphan},
+	}
+	for _, tc := range testCases {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+		dynClient := &deleteOptionsDynClient{Interface: tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)}
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		if tc.policy != "" {
+			o.PropagationPolicy = tc.policy
+		}
+		o.DynamicClient = dynClient
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command for policy %s", tc.policy)
+
+		require.Len(t, dynClient.deleteOptions, 3, "delete calls for policy %s", tc.policy)
+		for _, opts := range dynClient.deleteOptions {
+			require.NotNil(t, opts.PropagationPolicy, "PropagationPolicy for policy %s", tc.policy)
+			assert.Equal(t, tc.expected, *opts.PropagationPolicy, "PropagationPolicy for policy %s", tc.policy)
+		}
+	}
+}
+
+func TestGCInvalidPropagationPolicy(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.PropagationPolicy = "Sideways"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail with an invalid propagation policy")
+	assert.Contains(t, err.Error(), "propagation-policy", "error should mention the flag")
+}
+
+func TestGCWaitForJobs(t *testing.T) {
+	terraforms.JobPollInterval = time.Millisecond
+
+	ns := "jx"
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	// the first resource has a Job which never finishes, the second one which has completed
+	kubeClient := fake.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-1", Namespace: ns},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-2", Namespace: ns},
+			Status:     batchv1.JobStatus{Succeeded: 1, CompletionTime: &metav1.Time{Time: oldTime}},
+		},
+	)
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.WaitForJobs = true
+	o.WaitForJobsTimeout = 20 * time.Millisecond
+	o.Output = "json"
+	o.Out = &bytes.Buffer{}
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Equal(t, 2, o.Deleted, "deleted count")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	require.Len(t, list.Items, 1, "remaining resources")
+	assert.Equal(t, "tf-myrepo-pr456-myctx-1", list.Items[0].GetName(), "remaining resource")
+
+	job, err := kubeClient.BatchV1().Jobs(ns).Get(o.GetContext(), "tf-myrepo-pr456-myctx-1", metav1.GetOptions{})
+	require.NoError(t, err, "should not have deleted the active Job")
+	assert.Equal(t, int32(1), job.Status.Active, "active Job")
+
+	actions := map[string]string{}
+	for _, r := range o.Result.Resources {
+		actions[r.Name] = r.Action
+	}
+	assert.Equal(t, gc.ActionSkippedActiveJob, actions["tf-myrepo-pr456-myctx-1"], "action for resource with an active Job")
+}
+
+func TestGCWaitForJobsTimeout(t *testing.T) {
+	terraforms.JobPollInterval = time.Millisecond
+
+	ns := "jx"
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	kubeClient := fake.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-1", Namespace: ns},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+	)
+
+	// lets check --timeout ending the run is not reported as the Job still being active
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.WaitForJobs = true
+	o.WaitForJobsTimeout = time.Minute
+	o.Timeout = 50 * time.Millisecond
+	o.Output = "json"
+	o.Out = &bytes.Buffer{}
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, testResources[0:1])...)
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.Error(t, err, "should fail when the run times out")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "should be a deadline exceeded error but got %s", err.Error())
+	require.Len(t, o.Result.Resources, 1, "results")
+	assert.Equal(t, gc.ActionError, o.Result.Resources[0].Action, "action")
+}
+
+func TestGCKeepLabel(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		labels := u.GetLabels()
+		switch idx {
+		case 0:
+			labels["jx-test/keep"] = "true"
+		case 1:
+			labels["keep"] = "true"
+		}
+		u.SetLabels(labels)
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.KeepLabel = "jx-test/keep"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Equal(t, 2, o.Deleted, "deleted count")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	require.Len(t, list.Items, 1, "remaining resources")
+	assert.Equal(t, "tf-myrepo-pr456-myctx-1", list.Items[0].GetName(), "should have kept the resource with the custom keep label")
+}
+
+func TestGCLogFormatJSON(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-5 * time.Hour)
+		if idx > 1 {
+			created = now.Add(-1 * time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+
+	for _, format := range []string{gc.LogFormatText, gc.LogFormatJSON} {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+		logOut := &bytes.Buffer{}
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.LogFormat = format
+		o.LogOut = logOut
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command with log format %s", format)
+
+		if format == gc.LogFormatText {
+			assert.Empty(t, logOut.String(), "should not log JSON entries with the text format")
+			continue
+		}
+
+		actions := map[string]string{}
+		lines := strings.Split(strings.TrimSpace(logOut.String()), "\n")
+		require.Len(t, lines, 3, "JSON log lines")
+		for _, line := range lines {
+			entry := gc.ActionLogEntry{}
+			err = json.Unmarshal([]byte(line), &entry)
+			require.NoError(t, err, "failed to parse log line %s", line)
+			assert.Equal(t, "Terraform", entry.Kind, "kind for %s", line)
+			assert.Equal(t, "jx", entry.Namespace, "namespace for %s", line)
+			assert.NotEmpty(t, entry.Age, "age for %s", line)
+			actions[entry.Name] = entry.Action
+		}
+		assert.Equal(t, map[string]string{
+			"tf-myrepo-pr456-myctx-1": gc.ActionDeleted,
+			"tf-myrepo-pr456-myctx-2": gc.ActionDeleted,
+			"tf-myrepo-pr999-myctx-3": gc.ActionKeptTooYoung,
+		}, actions, "logged actions")
+	}
+}
+
+func TestGCFailFast(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	failName := "tf-myrepo-pr456-myctx-1"
+	for _, failFast := range []bool{false, true} {
+		runner := &fakerunner.FakeRunner{
+			CommandRunner: func(c *cmdrunner.Command) (string, error) {
+				if stringhelpers.StringArrayIndex(c.Args, failName) >= 0 {
+					return "", errors.Errorf("cannot delete %s", failName)
+				}
+				return "", nil
+			},
+		}
+
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.UseKubectl = true
+		o.Retries = 0
+		o.FailFast = failFast
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.CommandRunner = runner.Run
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.Error(t, err, "should have failed to delete %s with fail fast %v", failName, failFast)
+		assert.Contains(t, err.Error(), failName, "error should mention the failed resource")
+
+		if failFast {
+			assert.Equal(t, 0, o.Deleted, "should stop on the first failure")
+			assert.Len(t, runner.OrderedCommands, 1, "command invocations with fail fast")
+		} else {
+			assert.Equal(t, 2, o.Deleted, "should delete the other resources")
+			assert.Len(t, runner.OrderedCommands, 3, "command invocations without fail fast")
+		}
+	}
+}
+
+func TestGCCreatedWindow(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	created := []time.Time{
+		now.Add(-10 * time.Hour),
+		now.Add(-30 * time.Minute),
+		now.Add(-5 * time.Minute),
+	}
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created[idx],
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.CreatedAfter = now.Add(-1 * time.Hour).Format(time.RFC3339)
+	o.CreatedBefore = now.Add(-10 * time.Minute).Format(time.RFC3339)
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Equal(t, 1, o.Deleted, "deleted count")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+
+	var names []string
+	for _, r := range list.Items {
+		names = append(names, r.GetName())
+	}
+	assert.ElementsMatch(t, []string{"tf-myrepo-pr456-myctx-1", "tf-myrepo-pr999-myctx-3"}, names, "should only remove the resource created within the window even though it is younger than the duration")
+}
+
+func TestGCSummary(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-5 * time.Hour)
+		if idx > 1 {
+			created = now.Add(-1 * time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	var err error
+	output := log.CaptureOutput(func() {
+		err = o.Run()
+	})
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Contains(t, output, "gc completed in", "should log a summary")
+	assert.Contains(t, output, "deleted 2, kept 1, errors 0", "summary counts")
+	assert.True(t, o.Result.DurationSeconds > 0, "should record the duration")
+}
+
+func TestGCNamespaceSelector(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	namespaces := []string{"test-1", "prod", "test-2", "staging"}
+
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetNamespace(namespaces[idx])
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	resources := append([]string{}, testResources...)
+	resources = append(resources, `apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+  name: tf-otherrepo-pr456-myctx-4
+`)
+	dynObjects := tftests.ParseUnstructureds(t, fn, resources)
+
+	var kubeObjects []runtime.Object
+	for _, ns := range namespaces {
+		nsLabels := map[string]string{}
+		if strings.HasPrefix(ns, "test-") {
+			nsLabels["purpose"] = "test"
+		} else {
+			nsLabels["purpose"] = ns
+		}
+		kubeObjects = append(kubeObjects,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns, Labels: nsLabels}},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "tfstate-" + ns,
+					Namespace:         ns,
+					Labels:            map[string]string{"tfstate": "true"},
+					CreationTimestamp: metav1.Time{Time: oldTime},
+				},
+			},
+		)
+	}
+	kubeClient := fake.NewSimpleClientset(kubeObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.NamespaceSelector = "purpose=test"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Equal(t, 2, o.Deleted, "deleted count")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+
+	remaining := map[string]string{}
+	for _, r := range list.Items {
+		remaining[r.GetName()] = r.GetNamespace()
+	}
+	assert.Equal(t, map[string]string{
+		"tf-myrepo-pr456-myctx-2":    "prod",
+		"tf-otherrepo-pr456-myctx-4": "staging",
+	}, remaining, "remaining resources")
+
+	secrets, err := kubeClient.CoreV1().Secrets("").List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Secrets")
+	var secretNames []string
+	for _, s := range secrets.Items {
+		secretNames = append(secretNames, s.Name)
+	}
+	assert.ElementsMatch(t, []string{"tfstate-prod", "tfstate-staging"}, secretNames, "should only remove state in the selected namespaces")
+}
+
+func TestResolveNamespaces(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-2", Labels: map[string]string{"purpose": "test"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{"purpose": "prod"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-1", Labels: map[string]string{"purpose": "test"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+	)
+
+	namespaces, err := gc.ResolveNamespaces(context.Background(), kubeClient, "purpose=test")
+	require.NoError(t, err, "failed to resolve namespaces")
+	assert.Equal(t, []string{"test-1", "test-2"}, namespaces, "namespaces")
+}
+
+func TestGCCustomResource(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "infra.example.com", Version: "v1beta1", Resource: "testclusters"}
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetAPIVersion(gvr.GroupVersion().String())
+		u.SetKind("TestCluster")
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := dynfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		terraforms.TerraformResource: "TerraformList",
+		gvr:                          "TestClusterList",
+	}, dynObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Group = gvr.Group
+	o.Version = gvr.Version
+	o.Resources = []string{gvr.Resource}
+	o.Kind = "TestCluster"
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Equal(t, 3, o.Deleted, "deleted count")
+
+	list, err := fakeDynClient.Resource(gvr).Namespace("jx").List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list custom resources")
+	assert.Empty(t, list.Items, "should have removed the custom resources")
+
+	for _, r := range o.Result.Resources {
+		assert.Equal(t, gc.ActionDeleted, r.Action, "action for %s", r.Name)
+	}
+}
+
+func TestGCDiscoversKind(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "infra.example.com", Version: "v1beta1", Resource: "testclusters"}
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetAPIVersion(gvr.GroupVersion().String())
+		u.SetKind("TestCluster")
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:1])
+	fakeDynClient := dynfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gvr: "TestClusterList",
+	}, dynObjects...)
+
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.Fake.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: gvr.GroupVersion().String(),
+			APIResources: []metav1.APIResource{{Name: gvr.Resource, Kind: "TestCluster"}},
+		},
+	}
+
+	runner := &fakerunner.FakeRunner{}
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Group = gvr.Group
+	o.Version = gvr.Version
+	o.Resources = []string{gvr.Resource}
+	o.UseKubectl = true
+	o.DynamicClient = fakeDynClient
+	o.CommandRunner = runner.Run
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	runner.ExpectResults(t,
+		fakerunner.FakeResult{CLI: "kubectl delete TestCluster tf-myrepo-pr456-myctx-1 -n jx"},
+	)
+}
+
+func TestGCAllAges(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		if idx == 0 {
+			u.SetLabels(map[string]string{"kind": "jx-test", "keep": "yes"})
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	cmd, o := gc.NewCmdGC()
+	err := cmd.ParseFlags([]string{"--duration", "0", "--all-ages"})
+	require.NoError(t, err, "failed to parse flags")
+
+	o.Namespace = "jx"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Equal(t, 2, o.Deleted, "should delete freshly created resources with --all-ages")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	require.Len(t, list.Items, 1, "remaining resources")
+	assert.Equal(t, "tf-myrepo-pr456-myctx-1", list.Items[0].GetName(), "should still keep the resource with a keep label")
+}
+
+func TestGCKeepLast(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		// the second resource is the most recent
+		created := now.Add(-5 * time.Hour)
+		switch idx {
+		case 1:
+			created = now.Add(-3 * time.Hour)
+		case 2:
+			created = now.Add(-4 * time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	cmd, o := gc.NewCmdGC()
+	err := cmd.ParseFlags([]string{"--keep-last", "1"})
+	require.NoError(t, err, "failed to parse flags")
+
+	o.Namespace = "jx"
+	o.Output = "json"
+	o.Out = &bytes.Buffer{}
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Equal(t, 2, o.Deleted, "should delete all but the most recent resource in the context")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	require.Len(t, list.Items, 1, "remaining resources")
+	assert.Equal(t, "tf-myrepo-pr456-myctx-2", list.Items[0].GetName(), "should keep the most recent resource")
+
+	actions := map[string]string{}
+	for _, r := range o.Result.Resources {
+		actions[r.Name] = r.Action
+	}
+	assert.Equal(t, gc.ActionKeptLast, actions["tf-myrepo-pr456-myctx-2"], "action for the most recent resource")
+}
+
+func TestGCInvalidKeepLast(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.KeepLast = -1
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail with a negative --keep-last")
+}
+
+func TestGCExcludeNamespace(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	namespaces := []string{"jx", "test-1", "test-1"}
+
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetNamespace(namespaces[idx])
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "jx", Labels: map[string]string{"purpose": "test"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-1", Labels: map[string]string{"purpose": "test"}}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "tfstate-jx",
+				Namespace:         "jx",
+				Labels:            map[string]string{"tfstate": "true"},
+				CreationTimestamp: metav1.Time{Time: oldTime},
+			},
+		},
+	)
+
+	for _, useSelector := range []bool{false, true} {
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		if useSelector {
+			o.NamespaceSelector = "purpose=test"
+		} else {
+			o.AllNamespaces = true
+		}
+		o.ExcludeNamespaces = []string{"kube-system", "jx"}
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = kubeClient
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command with namespace selector %v", useSelector)
+
+		assert.Equal(t, 2, o.Deleted, "deleted count with namespace selector %v", useSelector)
+
+		list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+		require.NoError(t, err, "failed to list resources")
+		require.Len(t, list.Items, 1, "remaining resources with namespace selector %v", useSelector)
+		assert.Equal(t, "jx", list.Items[0].GetNamespace(), "should not delete resources in an excluded namespace with namespace selector %v", useSelector)
+
+		_, err = kubeClient.CoreV1().Secrets("jx").Get(o.GetContext(), "tfstate-jx", metav1.GetOptions{})
+		require.NoError(t, err, "should not remove state in an excluded namespace with namespace selector %v", useSelector)
+	}
+}
+
+func TestGCOrphanJobs(t *testing.T) {
+	ns := "jx"
+	oldTime := time.Now().Add(-5 * time.Hour)
+	recentTime := time.Now().Add(-1 * time.Hour)
+
+	orphanJob := func(name string, created time.Time) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         ns,
+				CreationTimestamp: metav1.Time{Time: created},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: "tf.isaaguilar.com/v1alpha1",
+						Kind:       "Terraform",
+						Name:       name,
+					},
+				},
+			},
+		}
+	}
+	kubeClient := fake.NewSimpleClientset(
+		orphanJob("tf-old-orphan", oldTime),
+		orphanJob("tf-recent-orphan", recentTime),
+	)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.GCOrphanJobs = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	jobList, err := kubeClient.BatchV1().Jobs(ns).List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Jobs")
+	require.Len(t, jobList.Items, 1, "remaining Jobs")
+	assert.Equal(t, "tf-recent-orphan", jobList.Items[0].Name, "should only remove orphaned Jobs older than the cutoff")
+}
+
+// blockingDynClient blocks when listing resources until the context is done to simulate a hung API server
+type blockingDynClient struct {
+	dynamic.Interface
+}
+
+func (c *blockingDynClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &blockingResource{NamespaceableResourceInterface: c.Interface.Resource(gvr)}
+}
+
+type blockingResource struct {
+	dynamic.NamespaceableResourceInterface
+}
+
+func (r *blockingResource) Namespace(ns string) dynamic.ResourceInterface {
+	return &blockingNamespacedResource{ResourceInterface: r.NamespaceableResourceInterface.Namespace(ns)}
+}
+
+type blockingNamespacedResource struct {
+	dynamic.ResourceInterface
+}
+
+func (r *blockingNamespacedResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestGCTimeout(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Timeout = 50 * time.Millisecond
+	o.DynamicClient = &blockingDynClient{Interface: tftests.NewFakeDynClient(runtime.NewScheme())}
+	o.KubeClient = fake.NewSimpleClientset()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- o.Run()
+	}()
+
+	select {
+	case err := <-done:
+		require.Error(t, err, "should have timed out")
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "should be a deadline exceeded error but got %s", err.Error())
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "gc did not respect the timeout")
+	}
+}
+
+func TestGCNames(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		// only the first resource is old enough to be garbage collected via the selector
+		created := now.Add(-1 * time.Hour)
+		if idx == 0 {
+			created = now.Add(-5 * time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+		if idx == 1 {
+			// the selector does not match this resource
+			u.SetLabels(map[string]string{"kind": "something-else"})
+		}
+	}
+
+	testCases := []struct {
+		names     []string
+		remaining []string
+	}{
+		{
+			names:     nil,
+			remaining: []string{"tf-myrepo-pr456-myctx-2", "tf-myrepo-pr999-myctx-3"},
+		},
+		{
+			names:     []string{"tf-myrepo-pr456-myctx-2", "tf-myrepo-pr999-myctx-3"},
+			remaining: []string{"tf-myrepo-pr456-myctx-1"},
+		},
+	}
+	for _, tc := range testCases {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.Names = tc.names
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command for names %v", tc.names)
+
+		list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+		require.NoError(t, err, "failed to list resources")
+		var remaining []string
+		for _, r := range list.Items {
+			remaining = append(remaining, r.GetName())
+		}
+		assert.ElementsMatch(t, tc.remaining, remaining, "remaining resources for names %v", tc.names)
+	}
+}
+
+func TestGCNameFlag(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-1 * time.Hour)
+		switch idx {
+		case 0:
+			// lets use the name of a subcommand which can only be specified via --name
+			u.SetName("list")
+		case 1:
+			created = now.Add(-10 * time.Minute)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	cmd, o := gc.NewCmdGC()
+	err := cmd.Flags().Parse([]string{"--name", "list", "--name", "tf-myrepo-pr456-myctx-2", "--min-age", "30m"})
+	require.NoError(t, err, "failed to parse flags")
+	assert.Equal(t, []string{"list", "tf-myrepo-pr456-myctx-2"}, o.Names, "names from --name")
+
+	o.Namespace = "jx"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	var remaining []string
+	for _, r := range list.Items {
+		remaining = append(remaining, r.GetName())
+	}
+	assert.ElementsMatch(t, []string{"tf-myrepo-pr456-myctx-2", "tf-myrepo-pr999-myctx-3"}, remaining, "should keep the named resource younger than --min-age")
+	require.NotNil(t, o.Result, "result")
+	for _, rr := range o.Result.Resources {
+		if rr.Name == "tf-myrepo-pr456-myctx-2" {
+			assert.Equal(t, gc.ActionKeptMinAge, rr.Action, "action of the named resource younger than --min-age")
+		}
+	}
+}
+
+func TestGCNamesMissing(t *testing.T) {
+	dynObjects := tftests.ParseUnstructureds(t, nil, testResources)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Names = []string{"tf-myrepo-pr456-myctx-1", "does-not-exist"}
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail when a named resource does not exist")
+	assert.Contains(t, err.Error(), "does-not-exist", "error should mention the missing resource")
+	assert.Equal(t, 0, o.Deleted, "should not delete anything if a named resource does not exist")
+}
+
+func TestGCQuiet(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-5 * time.Hour)
+		if idx > 1 {
+			created = now.Add(-1 * time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+
+	for _, quiet := range []bool{false, true} {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.Quiet = quiet
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = fake.NewSimpleClientset()
+
+		var err error
+		output := log.CaptureOutput(func() {
+			err = o.Run()
+		})
+		require.NoError(t, err, "failed to run gc command with quiet %v", quiet)
+
+		assert.Contains(t, output, "deleted Terraform tf-myrepo-pr456-myctx-1", "should log deletions with quiet %v", quiet)
+		assert.Contains(t, output, "deleted 2, kept 1, errors 0", "should log the summary with quiet %v", quiet)
+		if quiet {
+			assert.NotContains(t, output, "not removing", "should not log kept resources when quiet")
+		} else {
+			assert.Contains(t, output, "not removing Terraform tf-myrepo-pr999-myctx-3", "should log kept resources")
+		}
+	}
+}
+
+func TestGCVerbose(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-1 * time.Hour),
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[2:])
+
+	for _, verbose := range []bool{false, true} {
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.Verbose = verbose
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = fake.NewSimpleClientset()
+
+		var err error
+		output := log.CaptureOutput(func() {
+			err = o.Run()
+		})
+		require.NoError(t, err, "failed to run gc command with verbose %v", verbose)
+
+		if verbose {
+			assert.Contains(t, output, "Terraform tf-myrepo-pr999-myctx-3 in namespace jx created: ", "should log the creation time")
+			assert.Contains(t, output, "age: 1h0m", "should log the age")
+			assert.Contains(t, output, " cutoff: ", "should log the cutoff")
+			assert.Contains(t, output, "decision: keep: "+gc.ActionKeptTooYoung, "should log the decision")
+		} else {
+			assert.NotContains(t, output, "decision:", "should not log decisions by default")
+		}
+	}
+}
+
+func TestGCVerboseAndQuiet(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Quiet = true
+	o.Verbose = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should not allow --verbose with --quiet")
+}
+
+func TestGCRunWithResult(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-5 * time.Hour)
+		if idx > 1 {
+			created = now.Add(-1 * time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	result, err := o.RunWithResult(context.Background())
+	require.NoError(t, err, "failed to run gc")
+	require.NotNil(t, result, "should return a result")
+
+	assert.Equal(t, 2, result.Candidates, "result.Candidates")
+	assert.Equal(t, 2, result.Deleted, "result.Deleted")
+	assert.Equal(t, 1, result.Kept, "result.Kept")
+	assert.Equal(t, 0, result.Errors, "result.Errors")
+	assert.True(t, result.Duration > 0, "result.Duration should be set")
+	assert.Len(t, result.Resources, 3, "result.Resources")
+}
+
+func TestGCVerifyDeleted(t *testing.T) {
+	dynkube.DeletionPollInterval = 10 * time.Millisecond
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:1])
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	// lets simulate a finalizer by reporting the resource as present the first time it is queried after deletion
+	gets := 0
+	fakeDynClient.PrependReactor("get", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		gets++
+		if gets > 1 {
+			return false, nil, nil
+		}
+		u := dynObjects[0].(*unstructured.Unstructured).DeepCopy()
+		u.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+		return true, u, nil
+	})
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.VerifyDeleted = true
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	var err error
+	output := log.CaptureOutput(func() {
+		err = o.Run()
+	})
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Equal(t, 2, gets, "should have polled until the resource was not found")
+	assert.Contains(t, output, "tf-myrepo-pr456-myctx-1 in namespace jx has fully terminated", "should log that the resource terminated")
+}
+
+func TestGCForceRemoveFinalizers(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-5 * time.Hour),
+		})
+		u.SetFinalizers([]string{"finalizer.tf.isaaguilar.com"})
+		deleted := now.Add(-2 * time.Hour)
+		if idx == 1 {
+			// still within the grace period
+			deleted = now.Add(-10 * time.Minute)
+		}
+		u.SetDeletionTimestamp(&metav1.Time{Time: deleted})
+	}
+
+	for _, force := range []bool{false, true} {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:2])
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.ForceRemoveFinalizers = force
+		o.FinalizerGracePeriod = time.Hour
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = fake.NewSimpleClientset()
+
+		var err error
+		output := log.CaptureOutput(func() {
+			err = o.Run()
+		})
+		require.NoError(t, err, "failed to run gc command with force %v", force)
+
+		actions := map[string]string{}
+		for _, r := range o.Result.Resources {
+			actions[r.Name] = r.Action
+		}
+		if !force {
+			assert.Equal(t, gc.ActionSkippedTerminating, actions["tf-myrepo-pr456-myctx-1"], "action without --force-remove-finalizers")
+			assert.NotContains(t, output, "FORCE REMOVING", "should not remove finalizers by default")
+			continue
+		}
+		assert.Equal(t, gc.ActionRemovedFinalizers, actions["tf-myrepo-pr456-myctx-1"], "action for the stuck resource")
+		assert.Equal(t, gc.ActionDeleted, actions["tf-myrepo-pr456-myctx-2"], "action for the resource within the grace period")
+		assert.Contains(t, output, "FORCE REMOVING the finalizers finalizer.tf.isaaguilar.com from Terraform tf-myrepo-pr456-myctx-1", "should log loudly")
+
+		u, err := o.Client.Get(o.GetContext(), "tf-myrepo-pr456-myctx-1", metav1.GetOptions{})
+		require.NoError(t, err, "failed to get the stuck resource")
+		assert.Empty(t, u.GetFinalizers(), "finalizers of the stuck resource")
+	}
+}
+
+func TestGCQPS(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:2])
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	var deleteTimes []time.Time
+	fakeDynClient.PrependReactor("delete", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deleteTimes = append(deleteTimes, time.Now())
+		return false, nil, nil
+	})
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.QPS = 1
+	o.Burst = 1
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	require.Len(t, deleteTimes, 2, "delete calls")
+	gap := deleteTimes[1].Sub(deleteTimes[0])
+	assert.True(t, gap >= 900*time.Millisecond, "deletes should be spaced apart by the rate limit but were %s apart", gap.String())
+}
+
+func TestGCInvalidQPS(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.QPS = -1
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail with a negative --qps")
+}
+
+func TestGCFieldSelector(t *testing.T) {
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme())
+
+	var fieldSelectors []string
+	fakeDynClient.PrependReactor("list", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		fieldSelectors = append(fieldSelectors, action.(k8stesting.ListAction).GetListRestrictions().Fields.String())
+		return false, nil, nil
+	})
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.FieldSelector = "metadata.name=tf-myrepo-pr456-myctx-1"
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	require.Len(t, fieldSelectors, 1, "list calls")
+	assert.Equal(t, "metadata.name=tf-myrepo-pr456-myctx-1", fieldSelectors[0], "field selector")
+}
+
+func TestGCInvalidFieldSelector(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.FieldSelector = "metadata.name"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail with an invalid field selector")
+}
+
+type fakePlanner struct {
+	plans map[string]*terraforms.DestroyPlan
+}
+
+func (p *fakePlanner) PlanDestroy(ctx context.Context, ns, name string) (*terraforms.DestroyPlan, error) {
+	plan, ok := p.plans[name]
+	if !ok {
+		return nil, errors.Errorf("simulated plan failure for %s", name)
+	}
+	return plan, nil
+}
+
+func TestGCShowTerraformPlan(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	for _, show := range []bool{false, true} {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.ShowTerraformPlan = show
+		o.Planner = &fakePlanner{
+			plans: map[string]*terraforms.DestroyPlan{
+				"tf-myrepo-pr456-myctx-1": {Resources: map[string]int{"google_container_cluster": 1}},
+				"tf-myrepo-pr456-myctx-2": nil,
+			},
+		}
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = fake.NewSimpleClientset()
+
+		var err error
+		output := log.CaptureOutput(func() {
+			err = o.Run()
+		})
+		require.NoError(t, err, "failed to run gc command with show plan %v", show)
+		assert.Equal(t, 3, o.Deleted, "should still delete all the resources with show plan %v", show)
+
+		if !show {
+			assert.NotContains(t, output, "would destroy", "should not show plans by default")
+			continue
+		}
+		assert.Contains(t, output, "tf-myrepo-pr456-myctx-1 in namespace jx would destroy 1 cloud resources: google_container_cluster: 1", "plan summary")
+		assert.Contains(t, output, "no Terraform state found for Terraform tf-myrepo-pr456-myctx-2", "missing plan")
+		assert.Contains(t, output, "simulated plan failure for tf-myrepo-pr999-myctx-3", "plan failure")
+	}
+}
+
+func TestGCRequireLabel(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		if idx == 0 {
+			labels := u.GetLabels()
+			labels["app.kubernetes.io/managed-by"] = "jx-test"
+			u.SetLabels(labels)
+		}
+		if idx == 1 {
+			labels := u.GetLabels()
+			labels["app.kubernetes.io/managed-by"] = "another-tool"
+			u.SetLabels(labels)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	cmd, o := gc.NewCmdGC()
+	err := cmd.ParseFlags([]string{"--require-label", "app.kubernetes.io/managed-by=jx-test"})
+	require.NoError(t, err, "failed to parse flags")
+
+	o.Namespace = "jx"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run gc command")
+	assert.Equal(t, 1, o.Deleted, "should only delete the resource with the required label")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	var remaining []string
+	for _, r := range list.Items {
+		remaining = append(remaining, r.GetName())
+	}
+	assert.ElementsMatch(t, []string{"tf-myrepo-pr456-myctx-2", "tf-myrepo-pr999-myctx-3"}, remaining, "should skip resources matching the selector without the required label")
+}
+
+func TestGCEmitEvents(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	for _, emit := range []bool{false, true} {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:1])
+		kubeClient := fake.NewSimpleClientset()
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.EmitEvents = emit
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = kubeClient
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command with emit events %v", emit)
+
+		events, err := kubeClient.CoreV1().Events("jx").List(o.GetContext(), metav1.ListOptions{})
+		require.NoError(t, err, "failed to list Events")
+		if !emit {
+			assert.Empty(t, events.Items, "should not emit Events by default")
+			continue
+		}
+		require.Len(t, events.Items, 1, "Events")
+		e := events.Items[0]
+		assert.Equal(t, gc.EventReasonGarbageCollected, e.Reason, "event reason")
+		assert.Equal(t, "Terraform", e.InvolvedObject.Kind, "involved object kind")
+		assert.Equal(t, "tf-myrepo-pr456-myctx-1", e.InvolvedObject.Name, "involved object name")
+		assert.Contains(t, e.Message, "deleted by jx-test gc as it was 5h0m", "event message")
+	}
+}
+
+func TestGCSkipActive(t *testing.T) {
+	ns := "jx"
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	// the first resource has an active Job, the second one a Job which has completed
+	kubeClient := fake.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-1", Namespace: ns},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-2", Namespace: ns},
+			Status:     batchv1.JobStatus{Succeeded: 1, CompletionTime: &metav1.Time{Time: oldTime}},
+		},
+	)
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:2])
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.SkipActive = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = kubeClient
+
+	result, err := o.RunWithResult(o.GetContext())
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Equal(t, 1, o.Deleted, "deleted count")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	require.Len(t, list.Items, 1, "remaining resources")
+	assert.Equal(t, "tf-myrepo-pr456-myctx-1", list.Items[0].GetName(), "remaining resource")
+
+	job, err := kubeClient.BatchV1().Jobs(ns).Get(o.GetContext(), "tf-myrepo-pr456-myctx-1", metav1.GetOptions{})
+	require.NoError(t, err, "should not have deleted the active Job")
+	assert.Equal(t, int32(1), job.Status.Active, "active Job")
+
+	actions := map[string]string{}
+	for _, r := range result.Resources {
+		actions[r.Name] = r.Action
+	}
+	assert.Equal(t, gc.ActionSkippedActiveJob, actions["tf-myrepo-pr456-myctx-1"], "action for resource with an active Job")
+	assert.Equal(t, gc.ActionDeleted, actions["tf-myrepo-pr456-myctx-2"], "action for resource with a completed Job")
+}
+
+func TestGCSkipActiveAndWaitForJobs(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.SkipActive = true
+	o.WaitForJobs = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail when using --skip-active with --wait-for-jobs")
+	assert.Contains(t, err.Error(), "wait-for-jobs", "error message")
+}
+
+func TestGCMark(t *testing.T) {
+	now := time.Now()
+	ages := []time.Duration{5 * time.Hour, 5 * time.Hour, time.Hour}
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-ages[idx]),
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Mark = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	result, err := o.RunWithResult(o.GetContext())
+	require.NoError(t, err, "failed to run gc command")
+	assert.Equal(t, 0, o.Deleted, "deleted count")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	require.Len(t, list.Items, 3, "should not have deleted any resources")
+
+	marked := map[string]string{}
+	for _, r := range list.Items {
+		marked[r.GetName()] = r.GetLabels()[terraforms.LabelMarkedForGC]
+	}
+	assert.NotEmpty(t, marked["tf-myrepo-pr456-myctx-1"], "should have marked an old resource")
+	assert.NotEmpty(t, marked["tf-myrepo-pr456-myctx-2"], "should have marked an old resource")
+	assert.Empty(t, marked["tf-myrepo-pr999-myctx-3"], "should not have marked a recent resource")
+
+	actions := map[string]string{}
+	for _, r := range result.Resources {
+		actions[r.Name] = r.Action
+	}
+	assert.Equal(t, gc.ActionMarked, actions["tf-myrepo-pr456-myctx-1"], "action")
+	assert.Equal(t, gc.ActionKeptTooYoung, actions["tf-myrepo-pr999-myctx-3"], "action")
+}
+
+func TestGCSweep(t *testing.T) {
+	now := time.Now()
+	oldTime := now.Add(-72 * time.Hour)
+	markedAgo := []time.Duration{48 * time.Hour, time.Hour, 0}
+	fn := func(idx int, u *unstructured.Unstructured) {
+		if markedAgo[idx] > 0 {
+			labels := u.GetLabels()
+			labels[terraforms.LabelMarkedForGC] = strconv.FormatInt(now.Add(-markedAgo[idx]).Unix(), 10)
+			u.SetLabels(labels)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Sweep = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	result, err := o.RunWithResult(o.GetContext())
+	require.NoError(t, err, "failed to run gc command")
+	assert.Equal(t, 1, o.Deleted, "deleted count")
+	assert.Equal(t, 2, result.Kept, "kept count")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	var names []string
+	for _, r := range list.Items {
+		names = append(names, r.GetName())
+	}
+	assert.ElementsMatch(t, []string{"tf-myrepo-pr456-myctx-2", "tf-myrepo-pr999-myctx-3"}, names, "remaining resources")
+
+	actions := map[string]string{}
+	for _, r := range result.Resources {
+		actions[r.Name] = r.Action
+	}
+	assert.Equal(t, gc.ActionDeleted, actions["tf-myrepo-pr456-myctx-1"], "action for a resource marked long ago")
+	assert.Equal(t, gc.ActionKeptNotMarked, actions["tf-myrepo-pr456-myctx-2"], "action for a recently marked resource")
+	assert.Equal(t, gc.ActionKeptNotMarked, actions["tf-myrepo-pr999-myctx-3"], "action for an unmarked resource")
+}
+
+func TestGCMarkAndSweep(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Mark = true
+	o.Sweep = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail when using --mark with --sweep")
+	assert.Contains(t, err.Error(), "sweep", "error message")
+}
+
+func TestGCOutputJSONLines(t *testing.T) {
+	now := time.Now()
+	recentTime := now.Add(-1 * time.Hour)
+	oldTime := now.Add(-5 * time.Hour)
+
+	fn := func(idx int, u *unstructured.Unstructured) {
+		t := oldTime
+		if idx == 1 {
+			u.SetLabels(map[string]string{"kind": "jx-test", "keep": "yes"})
+		}
+		if idx > 1 {
+			t = recentTime
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: t,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	out := &bytes.Buffer{}
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Output = gc.OutputJSONLines
+	o.Out = out
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3, "lines of output %s", out.String())
+
+	actions := map[string]string{}
+	for _, line := range lines {
+		r := &gc.ResourceResult{}
+		err = json.Unmarshal([]byte(line), r)
+		require.NoError(t, err, "failed to parse line %s", line)
+		assert.Equal(t, "jx", r.Namespace, "namespace of %s", r.Name)
+		assert.NotEmpty(t, r.Age, "age of %s", r.Name)
+		actions[r.Name] = r.Action
+	}
+	assert.Equal(t, map[string]string{
+		"tf-myrepo-pr456-myctx-1": gc.ActionDeleted,
+		"tf-myrepo-pr456-myctx-2": gc.ActionKeptLabel,
+		"tf-myrepo-pr999-myctx-3": gc.ActionKeptTooYoung,
+	}, actions, "actions")
+}
+
+func TestGCDryRunActiveJobs(t *testing.T) {
+	ns := "jx"
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	kubeClient := fake.NewSimpleClientset(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-1", Namespace: ns},
+		Status:     batchv1.JobStatus{Active: 1},
+	})
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:1])
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.DryRun = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = kubeClient
+
+	var err error
+	output := log.CaptureOutput(func() {
+		err = o.Run()
+	})
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Contains(t, output, "dry-run: would delete terraform apply Job tf-myrepo-pr456-myctx-1", "should log the active Job")
+
+	_, err = kubeClient.BatchV1().Jobs(ns).Get(o.GetContext(), "tf-myrepo-pr456-myctx-1", metav1.GetOptions{})
+	require.NoError(t, err, "should not have deleted the active Job")
+}
+
+func TestGCMissingCRD(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	for _, skip := range []bool{false, true} {
+		// lets register the group version without the terraforms resource
+		kubeClient := fake.NewSimpleClientset()
+		kubeClient.Fake.Resources = []*metav1.APIResourceList{
+			{
+				GroupVersion: terraforms.TerraformResource.GroupVersion().String(),
+				APIResources: []metav1.APIResource{{Name: "modules", Kind: "Module"}},
+			},
+		}
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.SkipCRDCheck = skip
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = kubeClient
+
+		err := o.Run()
+		if skip {
+			require.NoError(t, err, "should not check the CRD with --skip-crd-check")
+			assert.Equal(t, 3, o.Deleted, "deleted count")
+			continue
+		}
+		require.Error(t, err, "should fail when the CRD is missing")
+		assert.Contains(t, err.Error(), "Terraform CRD not found", "error message")
+		assert.Equal(t, 0, o.Deleted, "deleted count")
+	}
+}
+
+func TestGCProtectAnnotation(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		switch idx {
+		case 0:
+			u.SetAnnotations(map[string]string{terraforms.AnnotationKeep: "true"})
+		case 1:
+			labels := u.GetLabels()
+			labels["keep"] = "yes"
+			u.SetLabels(labels)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	result, err := o.RunWithResult(o.GetContext())
+	require.NoError(t, err, "failed to run gc command")
+	assert.Equal(t, 1, o.Deleted, "deleted count")
+
+	actions := map[string]string{}
+	for _, r := range result.Resources {
+		actions[r.Name] = r.Action
+	}
+	assert.Equal(t, map[string]string{
+		"tf-myrepo-pr456-myctx-1": gc.ActionKeptLabel,
+		"tf-myrepo-pr456-myctx-2": gc.ActionKeptLabel,
+		"tf-myrepo-pr999-myctx-3": gc.ActionDeleted,
+	}, actions, "actions")
+}
+
+func TestGCNewOptions(t *testing.T) {
+	// lets make sure we don't need a kube config when the clients are injected
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "does-not-exist"))
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	for _, allNamespaces := range []bool{false, true} {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+		o := gc.NewOptions(fake.NewSimpleClientset(), tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...))
+		if allNamespaces {
+			o.AllNamespaces = true
+		} else {
+			o.Namespace = "jx"
+		}
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc with all namespaces %v", allNamespaces)
+		assert.Equal(t, 3, o.Deleted, "deleted count with all namespaces %v", allNamespaces)
+		assert.Equal(t, 2*time.Hour, o.Duration, "should default the duration")
+	}
+}
+
+func TestGCReportFile(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"report.json", "report.yaml"} {
+		path := filepath.Join(dir, "nested", "dir", name)
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.ReportFile = path
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command for %s", name)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err, "failed to read report file %s", path)
+
+		// YAML is a superset of JSON so lets parse both via YAML
+		result := &gc.RunResult{}
+		err = yaml.Unmarshal(data, result)
+		require.NoError(t, err, "failed to parse report file %s", path)
+		if name == "report.json" {
+			assert.True(t, json.Valid(data), "report file %s should be JSON", path)
+		}
+		assert.Equal(t, "kind=jx-test", result.Selector, "selector in %s", name)
+		assert.Equal(t, 3, result.Deleted, "deleted count in %s", name)
+		assert.Len(t, result.Resources, 3, "resources in %s", name)
+	}
+}
+
+func TestGCReportFileFailure(t *testing.T) {
+	// lets use a file as the parent directory so that the report cannot be written
+	parent := filepath.Join(t.TempDir(), "file")
+	err := os.WriteFile(parent, []byte("not a dir"), 0600)
+	require.NoError(t, err, "failed to write file %s", parent)
+
+	for _, strict := range []bool{false, true} {
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.ReportFile = filepath.Join(parent, "report.json")
+		o.Strict = strict
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err = o.Run()
+		if strict {
+			require.Error(t, err, "should fail to write the report with --strict")
+			assert.Contains(t, err.Error(), "report", "error message")
+		} else {
+			require.NoError(t, err, "should only warn if the report cannot be written")
+		}
+	}
+}
+
+func TestGCDeleteEmptyNamespace(t *testing.T) {
+	now := time.Now()
+	oldTime := now.Add(-5 * time.Hour)
+	recentTime := now.Add(-time.Hour)
+
+	// pr-1 becomes empty, pr-2 still has a recent resource and pr-3 is not labelled to be deleted when empty
+	namespaces := []string{"pr-1", "pr-1", "pr-2", "pr-2", "pr-3"}
+	created := []time.Time{oldTime, oldTime, oldTime, recentTime, oldTime}
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetNamespace(namespaces[idx])
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created[idx],
+		})
+	}
+
+	resources := append([]string{}, testResources...)
+	resources = append(resources, `apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+  name: tf-otherrepo-pr456-myctx-4
+`, `apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+  name: tf-otherrepo-pr456-myctx-5
+`)
+
+	for _, dryRun := range []bool{false, true} {
+		dynObjects := tftests.ParseUnstructureds(t, fn, resources)
+		kubeClient := fake.NewSimpleClientset(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "pr-1", Labels: map[string]string{"jx-test/delete-when-empty": "true"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "pr-2", Labels: map[string]string{"jx-test/delete-when-empty": "true"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "pr-3"}},
+		)
+
+		_, o := gc.NewCmdGC()
+		o.AllNamespaces = true
+		o.DryRun = dryRun
+		o.DeleteEmptyNamespace = true
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = kubeClient
+
+		var err error
+		output := log.CaptureOutput(func() {
+			err = o.Run()
+		})
+		require.NoError(t, err, "failed to run gc command with dry run %v", dryRun)
+		assert.Equal(t, 4, o.Deleted, "deleted count with dry run %v", dryRun)
+
+		nsList, err := kubeClient.CoreV1().Namespaces().List(o.GetContext(), metav1.ListOptions{})
+		require.NoError(t, err, "failed to list namespaces")
+		var remaining []string
+		for _, ns := range nsList.Items {
+			remaining = append(remaining, ns.Name)
+		}
+		if dryRun {
+			assert.ElementsMatch(t, []string{"pr-1", "pr-2", "pr-3"}, remaining, "should not delete namespaces in dry run mode")
+			assert.Contains(t, output, "dry-run: would delete namespace pr-1", "should log the namespace which would be deleted")
+			assert.NotContains(t, output, "would delete namespace pr-2", "should not delete a namespace which is not empty")
+			continue
+		}
+		assert.ElementsMatch(t, []string{"pr-2", "pr-3"}, remaining, "remaining namespaces")
+	}
+}
+
+func TestGCDeleteEmptyNamespaceTerminating(t *testing.T) {
+	now := time.Now()
+	oldTime := now.Add(-5 * time.Hour)
+	gvr := terraforms.TerraformResource
+
+	for _, alreadyTerminating := range []bool{false, true} {
+		fn := func(idx int, u *unstructured.Unstructured) {
+			u.SetNamespace("pr-1")
+			u.SetCreationTimestamp(metav1.Time{
+				Time: oldTime,
+			})
+			u.SetFinalizers([]string{"finalizer.tf.isaaguilar.com"})
+			if alreadyTerminating {
+				u.SetDeletionTimestamp(&metav1.Time{Time: now.Add(-time.Minute)})
+			}
+		}
+		fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, testResources[:1])...)
+
+		// lets simulate the operator finalizer blocking the deletion
+		fakeDynClient.PrependReactor("delete", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			deleteAction := action.(k8stesting.DeleteAction)
+			obj, err := fakeDynClient.Tracker().Get(gvr, deleteAction.GetNamespace(), deleteAction.GetName())
+			if err != nil {
+				return true, nil, err
+			}
+			u := obj.(*unstructured.Unstructured)
+			u.SetDeletionTimestamp(&metav1.Time{Time: now})
+			return true, nil, fakeDynClient.Tracker().Update(gvr, u, deleteAction.GetNamespace())
+		})
+
+		labels := map[string]string{"jx-test/delete-when-empty": "true"}
+		kubeClient := fake.NewSimpleClientset(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "pr-1", Labels: labels, CreationTimestamp: metav1.Time{Time: oldTime}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "pr-new", Labels: labels, CreationTimestamp: metav1.Time{Time: now}}},
+		)
+
+		remainingNamespaces := func() []string {
+			nsList, err := kubeClient.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+			require.NoError(t, err, "failed to list namespaces")
+			var answer []string
+			for _, ns := range nsList.Items {
+				answer = append(answer, ns.Name)
+			}
+			return answer
+		}
+		runGC := func(recheck bool) {
+			_, o := gc.NewCmdGC()
+			o.AllNamespaces = true
+			o.DeleteEmptyNamespace = true
+			o.RecheckEmptyNamespaces = recheck
+			o.VerifyDeleted = false
+			o.DynamicClient = fakeDynClient
+			o.KubeClient = kubeClient
+
+			err := o.Run()
+			require.NoError(t, err, "failed to run gc command when already terminating %v recheck %v", alreadyTerminating, recheck)
+		}
+
+		runGC(false)
+		assert.ElementsMatch(t, []string{"pr-1", "pr-new"}, remainingNamespaces(), "should not delete the namespace while its resource is terminating when already terminating %v", alreadyTerminating)
+
+		// lets simulate the operator removing the resource once it has been destroyed
+		err := fakeDynClient.Tracker().Delete(gvr, "pr-1", "tf-myrepo-pr456-myctx-1")
+		require.NoError(t, err, "failed to remove the terminating resource")
+
+		runGC(false)
+		assert.ElementsMatch(t, []string{"pr-1", "pr-new"}, remainingNamespaces(), "should only delete the namespaces emptied by this run without --recheck-empty-namespaces when already terminating %v", alreadyTerminating)
+
+		runGC(true)
+		assert.Equal(t, []string{"pr-new"}, remainingNamespaces(), "should delete the older empty namespace but not the new one with --recheck-empty-namespaces when already terminating %v", alreadyTerminating)
+	}
+}
+
+func TestGCKubeConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(path, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: cluster-a
+  cluster:
+    server: https://cluster-a.example.com
+- name: cluster-b
+  cluster:
+    server: https://cluster-b.example.com
+contexts:
+- name: context-a
+  context:
+    cluster: cluster-a
+    namespace: ns-a
+- name: context-b
+  context:
+    cluster: cluster-b
+    namespace: ns-b
+current-context: context-a
+`), 0600)
+	require.NoError(t, err, "failed to write kube config %s", path)
+
+	// lets make sure the ambient kube config is not used
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "does-not-exist"))
+
+	for _, kubeContext := range []string{"", "context-b"} {
+		_, o := gc.NewCmdGC()
+		o.KubeConfig = path
+		o.KubeContext = kubeContext
+		o.SkipCRDCheck = true
+
+		err = o.Validate()
+		require.NoError(t, err, "failed to validate with context %q", kubeContext)
+		assert.NotNil(t, o.KubeClient, "kube client with context %q", kubeContext)
+		assert.NotNil(t, o.DynamicClient, "dynamic client with context %q", kubeContext)
+
+		expected := "ns-a"
+		if kubeContext != "" {
+			expected = "ns-b"
+		}
+		assert.Equal(t, expected, o.Namespace, "namespace with context %q", kubeContext)
+	}
+
+	_, o := gc.NewCmdGC()
+	o.KubeConfig = path
+	o.KubeContext = "does-not-exist"
+	o.SkipCRDCheck = true
+	err = o.Validate()
+	require.Error(t, err, "should fail with a missing context")
+}
+
+func TestGCJobLabel(t *testing.T) {
+	ns := "jx"
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	kubeClient := fake.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "apply-abcde", Namespace: ns, Labels: map[string]string{"example.com/terraform": "tf-myrepo-pr456-myctx-1"}},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "apply-fghij", Namespace: ns, Labels: map[string]string{"example.com/terraform": "another"}},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+	)
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:1])
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.JobLabel = "example.com/terraform"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+	assert.Equal(t, 1, o.Deleted, "deleted count")
+
+	jobs, err := kubeClient.BatchV1().Jobs(ns).List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Jobs")
+	require.Len(t, jobs.Items, 1, "remaining Jobs")
+	assert.Equal(t, "apply-fghij", jobs.Items[0].Name, "remaining Job")
+}
+
+func TestGCAnnotateBeforeDelete(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:2])
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	// lets fail the deletion of one resource to check the audit annotations survive
+	var calls []string
+	fakeDynClient.PrependReactor("*", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		verb := action.GetVerb()
+		if verb != "patch" && verb != "delete" {
+			return false, nil, nil
+		}
+		name := action.(interface{ GetName() string }).GetName()
+		calls = append(calls, verb+" "+name)
+		if verb == "delete" && name == "tf-myrepo-pr456-myctx-2" {
+			return true, nil, errors.Errorf("simulated failure")
+		}
+		return false, nil, nil
+	})
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Retries = 0
+	o.AnnotateBeforeDelete = true
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail as a deletion failed")
+	assert.Equal(t, 1, o.Deleted, "deleted count")
+
+	assert.Equal(t, []string{
+		"patch tf-myrepo-pr456-myctx-1",
+		"delete tf-myrepo-pr456-myctx-1",
+		"patch tf-myrepo-pr456-myctx-2",
+		"delete tf-myrepo-pr456-myctx-2",
+	}, calls, "the annotations should be patched before each delete")
+
+	client := dynkube.DynamicResource(fakeDynClient, "jx", terraforms.TerraformResource)
+	u, err := client.Get(o.GetContext(), "tf-myrepo-pr456-myctx-2", metav1.GetOptions{})
+	require.NoError(t, err, "failed to get the resource which failed to delete")
+	annotations := u.GetAnnotations()
+	assert.True(t, strings.HasPrefix(annotations[terraforms.AnnotationGCReason], "age 5h0m"), "reason annotation %s", annotations[terraforms.AnnotationGCReason])
+	gcTime, err := time.Parse(time.RFC3339, annotations[terraforms.AnnotationGCTime])
+	require.NoError(t, err, "failed to parse the time annotation")
+	assert.WithinDuration(t, time.Now(), gcTime, time.Minute, "time annotation")
+}
+
+func TestGCNoCandidates(t *testing.T) {
+	for _, failIfNone := range []bool{false, true} {
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.FailIfNone = failIfNone
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+		o.KubeClient = fake.NewSimpleClientset()
+
+		result, err := o.RunWithResult(context.Background())
+		if failIfNone {
+			require.Error(t, err, "should fail with --fail-if-none when no resources match")
+			assert.Contains(t, err.Error(), "no Terraform resources matched the selector", "error message")
+		} else {
+			require.NoError(t, err, "should succeed when there is nothing to delete")
+		}
+		require.NotNil(t, result, "result for failIfNone %v", failIfNone)
+		assert.Equal(t, 0, result.Candidates, "candidates for failIfNone %v", failIfNone)
+		assert.Equal(t, 0, o.Deleted, "deleted count for failIfNone %v", failIfNone)
+	}
+}
+
+func TestGCFailIfNoneWithKeptResources(t *testing.T) {
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: time.Now(),
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.FailIfNone = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "should not fail when resources matched but were too young to delete")
+	assert.Equal(t, 0, o.Deleted, "deleted count")
+}
+
+func TestGCWatch(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:2])
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	client := dynkube.DynamicResource(fakeDynClient, "jx", terraforms.TerraformResource)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, o := gc.NewCmdGC()
+	o.Ctx = ctx
+	o.Namespace = "jx"
+	o.Watch = true
+	o.Interval = 10 * time.Millisecond
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- o.Run()
+	}()
+
+	remaining := func() int {
+		list, err := client.List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err, "failed to list resources")
+		return len(list.Items)
+	}
+	require.Eventually(t, func() bool { return remaining() == 0 }, 5*time.Second, 10*time.Millisecond, "should delete the existing resources on the first tick")
+
+	// lets check resources created after the watch starts are deleted on a later tick
+	created := tftests.ParseUnstructureds(t, fn, testResources[2:3])[0].(*unstructured.Unstructured)
+	_, err := client.Create(context.Background(), created, metav1.CreateOptions{})
+	require.NoError(t, err, "failed to create resource")
+	require.Eventually(t, func() bool { return remaining() == 0 }, 5*time.Second, 10*time.Millisecond, "should delete the new resource on a later tick")
+
+	cancel()
+	select {
+	case err = <-done:
+		require.NoError(t, err, "watch should stop cleanly when the context is cancelled")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "watch did not stop after the context was cancelled")
+	}
+}
+
+func TestGCWatchInvalidInterval(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Watch = true
+	o.Interval = 0
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail with a zero --interval")
+}
+
+func TestGCNamespaceFromLabel(t *testing.T) {
+	ns := "jx"
+	workloadNS := "workload-456"
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+		if idx == 0 {
+			l := u.GetLabels()
+			l["jx-test/workload-namespace"] = workloadNS
+			u.SetLabels(l)
+		}
+	}
+	owned := func(name, secretNS string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name + "-state",
+				Namespace: secretNS,
+				Labels:    map[string]string{terraforms.LabelTerraform: name},
+			},
+		}
+	}
+	kubeClient := fake.NewSimpleClientset(
+		owned("tf-myrepo-pr456-myctx-1", ns),
+		owned("tf-myrepo-pr456-myctx-1", workloadNS),
+		// the second resource has no namespace label so its Secret in the workload namespace is not removed
+		owned("tf-myrepo-pr456-myctx-2", workloadNS),
+	)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.CascadeOwned = true
+	o.NamespaceFromLabel = "jx-test/workload-namespace"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, testResources[0:2])...)
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+	assert.Equal(t, 2, o.Deleted, "deleted count")
+
+	secrets, err := kubeClient.CoreV1().Secrets(ns).List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Secrets in namespace %s", ns)
+	assert.Empty(t, secrets.Items, "should have removed the owned Secret in namespace %s", ns)
+
+	secrets, err = kubeClient.CoreV1().Secrets(workloadNS).List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Secrets in namespace %s", workloadNS)
+	require.Len(t, secrets.Items, 1, "remaining Secrets in namespace %s", workloadNS)
+	assert.Equal(t, "tf-myrepo-pr456-myctx-2-state", secrets.Items[0].Name, "remaining Secret in namespace %s", workloadNS)
+}
+
+func TestGCNamespaceFromLabelRequiresCascadeOwned(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.NamespaceFromLabel = "jx-test/workload-namespace"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail without --cascade-owned")
+}
+
+func TestGCMinAge(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-10 * time.Minute)
+		if idx == 2 {
+			created = now.Add(-time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+
+	testCases := []struct {
+		name string
+		fn   func(o *gc.Options)
+	}{
+		{name: "all ages", fn: func(o *gc.Options) { o.AllAges = true }},
+		{name: "named", fn: func(o *gc.Options) { o.Names = []string{"tf-myrepo-pr456-myctx-1", "tf-myrepo-pr999-myctx-3"} }},
+	}
+	for _, tc := range testCases {
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.MinAge = 30 * time.Minute
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, testResources)...)
+		o.KubeClient = fake.NewSimpleClientset()
+		tc.fn(o)
+
+		result, err := o.RunWithResult(context.Background())
+		require.NoError(t, err, "failed to run gc command for %s", tc.name)
+		assert.Equal(t, 1, o.Deleted, "deleted count for %s", tc.name)
+
+		actions := map[string]string{}
+		for _, r := range result.Resources {
+			actions[r.Name] = r.Action
+		}
+		assert.Equal(t, gc.ActionKeptMinAge, actions["tf-myrepo-pr456-myctx-1"], "action for the 10m old resource for %s", tc.name)
+		assert.Equal(t, gc.ActionDeleted, actions["tf-myrepo-pr999-myctx-3"], "action for the 1h old resource for %s", tc.name)
+	}
+}
+
+func TestGCOnlyFailed(t *testing.T) {
+	ns := "jx"
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-5 * time.Hour)
+		if idx == 2 {
+			created = now.Add(-time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+	condition := func(conditionType batchv1.JobConditionType) batchv1.JobStatus {
+		return batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue}},
+		}
+	}
+	kubeClient := fake.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-1", Namespace: ns},
+			Status:     condition(batchv1.JobFailed),
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-2", Namespace: ns},
+			Status:     condition(batchv1.JobComplete),
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr999-myctx-3", Namespace: ns},
+			Status:     condition(batchv1.JobFailed),
+		},
+	)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.OnlyFailed = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, testResources)...)
+	o.KubeClient = kubeClient
+
+	result, err := o.RunWithResult(context.Background())
+	require.NoError(t, err, "failed to run gc command")
+	assert.Equal(t, 1, o.Deleted, "deleted count")
+
+	actions := map[string]string{}
+	for _, r := range result.Resources {
+		actions[r.Name] = r.Action
+	}
+	assert.Equal(t, map[string]string{
+		"tf-myrepo-pr456-myctx-1": gc.ActionDeleted,
+		"tf-myrepo-pr999-myctx-3": gc.ActionKeptTooYoung,
+	}, actions, "the resource whose Job succeeded should not be a candidate")
+
+	list, err := o.Client.List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	var names []string
+	for _, r := range list.Items {
+		names = append(names, r.GetName())
+	}
+	assert.ElementsMatch(t, []string{"tf-myrepo-pr456-myctx-2", "tf-myrepo-pr999-myctx-3"}, names, "remaining resources")
+}
+
+func TestGCGracePeriodSeconds(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	for _, gracePeriod := range []int64{-1, 0, 30} {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+		dynClient := &deleteOptionsDynClient{Interface: tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)}
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.GracePeriodSeconds = gracePeriod
+		o.DynamicClient = dynClient
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command for grace period %d", gracePeriod)
+
+		require.Len(t, dynClient.deleteOptions, 3, "delete calls for grace period %d", gracePeriod)
+		for _, opts := range dynClient.deleteOptions {
+			if gracePeriod < 0 {
+				assert.Nil(t, opts.GracePeriodSeconds, "GracePeriodSeconds should use the API default")
+				continue
+			}
+			require.NotNil(t, opts.GracePeriodSeconds, "GracePeriodSeconds for grace period %d", gracePeriod)
+			assert.Equal(t, gracePeriod, *opts.GracePeriodSeconds, "GracePeriodSeconds")
+		}
+	}
+}
+
+func TestGCInvalidGracePeriodSeconds(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.GracePeriodSeconds = -2
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail with a negative --grace-period-seconds other than -1")
+}
+
+func TestGCGracePeriodSecondsKubectl(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	runner := &fakerunner.FakeRunner{}
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.UseKubectl = true
+	o.GracePeriodSeconds = 10
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, testResources[0:1])...)
+	o.CommandRunner = runner.Run
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	runner.ExpectResults(t,
+		fakerunner.FakeResult{CLI: "kubectl delete Terraform tf-myrepo-pr456-myctx-1 -n jx --grace-period=10"},
+	)
+}
+
+func TestGCCheckpointResume(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	client := dynkube.DynamicResource(fakeDynClient, "jx", terraforms.TerraformResource)
+	path := filepath.Join(t.TempDir(), "gc", "checkpoint.txt")
+
+	// lets simulate a run which is interrupted after the first resource is processed. The first resource is not
+	// actually removed so we can check it is skipped when resuming
+	var deleted []string
+	fakeDynClient.PrependReactor("delete", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.DeleteAction).GetName()
+		deleted = append(deleted, name)
+		if name == "tf-myrepo-pr456-myctx-1" {
+			return true, nil, nil
+		}
+		return true, nil, errors.Errorf("simulated interruption")
+	})
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Retries = 0
+	o.FailFast = true
+	o.CheckpointFile = path
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "the interrupted run should fail")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "the checkpoint file should remain after an interrupted run")
+	assert.Equal(t, "jx/tf-myrepo-pr456-myctx-1\n", string(data), "checkpoint file")
+
+	fakeDynClient.ReactionChain = fakeDynClient.ReactionChain[1:]
+	deleted = nil
+	fakeDynClient.PrependReactor("delete", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deleted = append(deleted, action.(k8stesting.DeleteAction).GetName())
+		return false, nil, nil
+	})
+
+	_, o = gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.CheckpointFile = path
+	o.Resume = true
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err = o.Run()
+	require.NoError(t, err, "failed to resume gc command")
+	assert.Equal(t, []string{"tf-myrepo-pr456-myctx-2", "tf-myrepo-pr999-myctx-3"}, deleted, "should only delete the resources not processed before")
+	assert.NoFileExists(t, path, "the checkpoint file should be removed once the run completes")
+
+	_, err = client.Get(o.GetContext(), "tf-myrepo-pr456-myctx-1", metav1.GetOptions{})
+	assert.NoError(t, err, "the resource processed before should have been skipped")
+}
+
+func TestGCResumeRequiresCheckpointFile(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Resume = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail with --resume but no --checkpoint-file")
+}
+
+func TestGCIncludeTerminating(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-5 * time.Hour),
+		})
+		if idx == 0 {
+			u.SetFinalizers([]string{"finalizer.tf.isaaguilar.com"})
+			u.SetDeletionTimestamp(&metav1.Time{Time: now.Add(-10 * time.Minute)})
+		}
+	}
+
+	for _, include := range []bool{false, true} {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:2])
+		fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+		var deleted []string
+		fakeDynClient.PrependReactor("delete", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			deleted = append(deleted, action.(k8stesting.DeleteAction).GetName())
+			return false, nil, nil
+		})
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.IncludeTerminating = include
+		o.DynamicClient = fakeDynClient
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command with --include-terminating %v", include)
+
+		actions := map[string]string{}
+		for _, r := range o.Result.Resources {
+			actions[r.Name] = r.Action
+		}
+		assert.Equal(t, gc.ActionDeleted, actions["tf-myrepo-pr456-myctx-2"], "action for the resource which is not terminating with --include-terminating %v", include)
+		if !include {
+			assert.Equal(t, gc.ActionSkippedTerminating, actions["tf-myrepo-pr456-myctx-1"], "action for the terminating resource by default")
+			assert.Equal(t, []string{"tf-myrepo-pr456-myctx-2"}, deleted, "should not delete the terminating resource again")
+			assert.Equal(t, 1, o.Result.Kept, "kept count")
+			continue
+		}
+		assert.Equal(t, gc.ActionDeleted, actions["tf-myrepo-pr456-myctx-1"], "action for the terminating resource with --include-terminating")
+		assert.Equal(t, []string{"tf-myrepo-pr456-myctx-1", "tf-myrepo-pr456-myctx-2"}, deleted, "deleted resources with --include-terminating")
+	}
+}
+
+func TestGCMultipleResources(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "tf.isaaguilar.com", Version: "v1alpha1", Resource: "tfworkspaces"}
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	workspaceFn := func(idx int, u *unstructured.Unstructured) {
+		fn(idx, u)
+		u.SetKind("TFWorkspace")
+		if idx == 1 {
+			labels := u.GetLabels()
+			labels["keep"] = "true"
+			u.SetLabels(labels)
+		}
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:2])
+	dynObjects = append(dynObjects, tftests.ParseUnstructureds(t, workspaceFn, testResources)...)
+	fakeDynClient := dynfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		terraforms.TerraformResource: "TerraformList",
+		gvr:                          "TFWorkspaceList",
+	}, dynObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Resources = []string{terraforms.TerraformResource.Resource, gvr.Group + "/" + gvr.Version + "/" + gvr.Resource}
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	assert.Equal(t, 4, o.Deleted, "deleted count")
+	assert.Equal(t, []gc.KindResult{
+		{Kind: "TFWorkspace", Deleted: 2, Kept: 1},
+		{Kind: "Terraform", Deleted: 2},
+	}, o.Result.Kinds, "results grouped by kind")
+
+	var kinds []string
+	for _, r := range o.Result.Resources {
+		kinds = append(kinds, r.Kind)
+	}
+	assert.Equal(t, []string{"TFWorkspace", "TFWorkspace", "TFWorkspace", "Terraform", "Terraform"}, kinds, "resources should be grouped by kind")
+
+	list, err := fakeDynClient.Resource(terraforms.TerraformResource).Namespace("jx").List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Terraform resources")
+	assert.Empty(t, list.Items, "should have removed the Terraform resources")
+
+	list, err = fakeDynClient.Resource(gvr).Namespace("jx").List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list TFWorkspace resources")
+	require.Len(t, list.Items, 1, "should have kept one TFWorkspace resource")
+	assert.Equal(t, "tf-myrepo-pr456-myctx-2", list.Items[0].GetName(), "kept TFWorkspace resource")
+}
+
+func TestGCMultipleResourcesDeleteHelpers(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "tf.isaaguilar.com", Version: "v1alpha1", Resource: "tfworkspaces"}
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	workspaceFn := func(idx int, u *unstructured.Unstructured) {
+		fn(idx, u)
+		u.SetKind("TFWorkspace")
+	}
+
+	// the last resource only exists as a TFWorkspace
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:2])
+	dynObjects = append(dynObjects, tftests.ParseUnstructureds(t, workspaceFn, testResources)...)
+	fakeDynClient := dynfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		terraforms.TerraformResource: "TerraformList",
+		gvr:                          "TFWorkspaceList",
+	}, dynObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Resources = []string{terraforms.TerraformResource.Resource, gvr.Group + "/" + gvr.Version + "/" + gvr.Resource}
+	o.AnnotateBeforeDelete = true
+	o.VerifyDeleted = true
+	o.VerifyDeletedTimeout = time.Second
+	o.Concurrency = 2
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+	assert.Equal(t, 5, o.Deleted, "deleted count")
+
+	// lets check the resource which only exists as a TFWorkspace is always annotated, deleted and verified as one
+	verbs := map[string]bool{}
+	for _, action := range fakeDynClient.Actions() {
+		named, ok := action.(interface{ GetName() string })
+		if !ok || named.GetName() != "tf-myrepo-pr999-myctx-3" {
+			continue
+		}
+		assert.Equal(t, gvr, action.GetResource(), "resource of the %s action", action.GetVerb())
+		verbs[action.GetVerb()] = true
+	}
+	assert.Equal(t, map[string]bool{"get": true, "patch": true, "delete": true}, verbs, "actions on the TFWorkspace")
+
+	list, err := fakeDynClient.Resource(gvr).Namespace("jx").List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list TFWorkspace resources")
+	assert.Empty(t, list.Items, "should have removed the TFWorkspace resources")
+}
+
+func TestGCMultipleResourcesMissingCRD(t *testing.T) {
+	// lets register the first resource but not the second
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.Fake.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: terraforms.TerraformResource.GroupVersion().String(),
+			APIResources: []metav1.APIResource{{Name: "modules", Kind: "Module"}},
+		},
+	}
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Resources = []string{"modules", terraforms.TerraformResource.Resource}
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.Error(t, err, "should fail when the CRD of the second resource is missing")
+	assert.Contains(t, err.Error(), "Terraform CRD not found", "error message")
+}
+
+func TestGCInvalidResource(t *testing.T) {
+	for _, resources := range [][]string{
+		{"tf.isaaguilar.com/terraforms"},
+		{"terraforms", "tf.isaaguilar.com/v1alpha1/terraforms"},
+		{"tf.isaaguilar.com//terraforms"},
+	} {
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.Resources = resources
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.Error(t, err, "should fail for --resource %v", resources)
+	}
+}
+
+func TestGCSort(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		// lets make the last resource the oldest and the first the newest
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(time.Duration(-5-idx) * time.Hour),
+		})
+	}
+
+	testCases := map[string][]string{
+		gc.SortOldestFirst: {"tf-myrepo-pr999-myctx-3", "tf-myrepo-pr456-myctx-2", "tf-myrepo-pr456-myctx-1"},
+		gc.SortNewestFirst: {"tf-myrepo-pr456-myctx-1", "tf-myrepo-pr456-myctx-2", "tf-myrepo-pr999-myctx-3"},
+		gc.SortName:        {"tf-myrepo-pr456-myctx-1", "tf-myrepo-pr456-myctx-2", "tf-myrepo-pr999-myctx-3"},
+	}
+	for sortOrder, expected := range testCases {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+		fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+		var deleted []string
+		fakeDynClient.PrependReactor("delete", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			deleted = append(deleted, action.(k8stesting.DeleteAction).GetName())
+			return false, nil, nil
+		})
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.Sort = sortOrder
+		o.DynamicClient = fakeDynClient
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command with --sort %s", sortOrder)
+		assert.Equal(t, expected, deleted, "order of deletions with --sort %s", sortOrder)
+	}
+}
+
+func TestGCInvalidSort(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Sort = "biggest-first"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail with an invalid --sort")
+	assert.Contains(t, err.Error(), "biggest-first", "error message")
+}
+
+func TestGCOutputName(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-5 * time.Hour)
+		if idx == 1 {
+			created = now.Add(-1 * time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+
+	for _, allNamespaces := range []bool{false, true} {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+		out := &bytes.Buffer{}
+		_, o := gc.NewCmdGC()
+		if allNamespaces {
+			o.AllNamespaces = true
+		} else {
+			o.Namespace = "jx"
+		}
+		o.Output = gc.OutputName
+		o.Out = out
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command with all namespaces %v", allNamespaces)
+
+		expected := "tf-myrepo-pr456-myctx-1\ntf-myrepo-pr999-myctx-3\n"
+		if allNamespaces {
+			expected = "jx/tf-myrepo-pr456-myctx-1\njx/tf-myrepo-pr999-myctx-3\n"
+		}
+		assert.Equal(t, expected, out.String(), "should only output the names of the deleted resources with all namespaces %v", allNamespaces)
+	}
+}
+
+func TestGCTemplate(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-5 * time.Hour)
+		if idx == 1 {
+			created = now.Add(-1 * time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+
+	testCases := []struct {
+		template string
+		expected string
+	}{
+		{
+			template: `{{range .DeletedResources}}{{.Namespace}}/{{.Name}}{{"\n"}}{{end}}`,
+			expected: "jx/tf-myrepo-pr456-myctx-1\njx/tf-myrepo-pr999-myctx-3\n",
+		},
+		{
+			template: `deleted {{.Deleted}} kept {{.Kept}}{{range .KeptResources}} {{.Name}}={{.Action}}{{end}} errors {{.Errors}} {{len .ErrorResources}}`,
+			expected: "deleted 2 kept 1 tf-myrepo-pr456-myctx-2=kept-too-young errors 0 0",
+		},
+	}
+
+	for _, tc := range testCases {
+		out := &bytes.Buffer{}
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.Template = tc.template
+		o.Out = out
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, testResources)...)
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command with template %s", tc.template)
+		assert.Equal(t, tc.expected, out.String(), "output of template %s", tc.template)
+	}
+}
+
+func TestGCInvalidTemplate(t *testing.T) {
+	testCases := []struct {
+		template string
+		output   string
+	}{
+		{template: "{{range .DeletedResources}}"},
+		{template: "{{.Name"},
+		{template: "{{.Selector}}", output: gc.OutputJSON},
+	}
+
+	for _, tc := range testCases {
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.Template = tc.template
+		o.Output = tc.output
+		// lets check the template is validated before garbage collecting
+		o.DynamicClient = &panicDynClient{}
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.Error(t, err, "should fail for template %s with output %q", tc.template, tc.output)
+		assert.Contains(t, err.Error(), "template", "error for template %s", tc.template)
+	}
+}
+
+func TestGCDeleteHelmRelease(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-5 * time.Hour)
+		if idx == 2 {
+			created = now.Add(-1 * time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			if c.Args[1] == "tf-myrepo-pr456-myctx-2" {
+				return "", errors.Errorf("Error: uninstall: Release not loaded: tf-myrepo-pr456-myctx-2: release: not found")
+			}
+			return "", nil
+		},
+	}
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.DeleteHelmRelease = true
+	o.CommandRunner = runner.Run
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "a missing helm release should not fail the run")
+
+	assert.Equal(t, 2, o.Deleted, "deleted count")
+	runner.ExpectResults(t,
+		fakerunner.FakeResult{CLI: "helm uninstall tf-myrepo-pr456-myctx-1 --namespace jx"},
+		fakerunner.FakeResult{CLI: "helm uninstall tf-myrepo-pr456-myctx-2 --namespace jx"},
+	)
+}
+
+func TestGCDeleteHooks(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-5 * time.Hour),
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			command := c.Args[1]
+			if command == "dnsctl deregister tf-myrepo-pr456-myctx-2.jx" {
+				return "", errors.Errorf("failed to deregister")
+			}
+			if command == "notify tf-myrepo-pr999-myctx-3" {
+				return "", errors.Errorf("failed to notify")
+			}
+			return "", nil
+		},
+	}
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Retries = 0
+	o.PreDeleteHook = "dnsctl deregister {{.Name}}.{{.Namespace}}"
+	o.PostDeleteHook = "notify {{.Name}}"
+	o.CommandRunner = runner.Run
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "a failing pre-delete hook should fail the run")
+
+	runner.ExpectResults(t,
+		fakerunner.FakeResult{CLI: "sh -c dnsctl deregister tf-myrepo-pr456-myctx-1.jx"},
+		fakerunner.FakeResult{CLI: "sh -c notify tf-myrepo-pr456-myctx-1"},
+		fakerunner.FakeResult{CLI: "sh -c dnsctl deregister tf-myrepo-pr456-myctx-2.jx"},
+		fakerunner.FakeResult{CLI: "sh -c dnsctl deregister tf-myrepo-pr999-myctx-3.jx"},
+		fakerunner.FakeResult{CLI: "sh -c notify tf-myrepo-pr999-myctx-3"},
+	)
+
+	actions := map[string]string{}
+	for _, r := range o.Result.Resources {
+		actions[r.Name] = r.Action
+	}
+	assert.Equal(t, map[string]string{
+		"tf-myrepo-pr456-myctx-1": gc.ActionDeleted,
+		"tf-myrepo-pr456-myctx-2": gc.ActionError,
+		"tf-myrepo-pr999-myctx-3": gc.ActionDeleted,
+	}, actions, "a failing post-delete hook should not stop the resource being deleted")
+
+	client := dynkube.DynamicResource(fakeDynClient, "jx", terraforms.TerraformResource)
+	_, err = client.Get(o.GetContext(), "tf-myrepo-pr456-myctx-2", metav1.GetOptions{})
+	assert.NoError(t, err, "the resource with a failing pre-delete hook should not be deleted")
+}
+
+func TestGCInvalidDeleteHook(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.PreDeleteHook = "dnsctl deregister {{.Name"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail with an invalid hook template")
+	assert.Contains(t, err.Error(), "pre-delete-hook", "error message")
+}
+
+func TestGCDelayBetweenDeletes(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-5 * time.Hour),
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	var lock sync.Mutex
+	var deleted []string
+	fakeDynClient.PrependReactor("delete", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		lock.Lock()
+		deleted = append(deleted, action.(k8stesting.DeleteAction).GetName())
+		lock.Unlock()
+		return false, nil, nil
+	})
+	deletedCount := func() int {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(deleted)
+	}
+
+	fakeClock := testingclock.NewFakeClock(now)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.DelayBetweenDeletes = time.Minute
+	o.Clock = fakeClock
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	done := make(chan error)
+	go func() {
+		done <- o.Run()
+	}()
+
+	for i := 1; i < 3; i++ {
+		require.Eventually(t, fakeClock.HasWaiters, 10*time.Second, time.Millisecond, "should wait after delete %d", i)
+		assert.Equal(t, i, deletedCount(), "should not delete the next resource until the delay has passed")
+
+		fakeClock.Step(30 * time.Second)
+		assert.True(t, fakeClock.HasWaiters(), "should still be waiting after half of the delay")
+		assert.Equal(t, i, deletedCount(), "should not delete the next resource after half of the delay")
+		fakeClock.Step(30 * time.Second)
+	}
+
+	select {
+	case err := <-done:
+		require.NoError(t, err, "failed to run gc command")
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "gc did not complete")
+	}
+	assert.Equal(t, 3, deletedCount(), "deleted count")
+	assert.False(t, fakeClock.HasWaiters(), "should not wait after the last delete")
+}
+
+// TestGCConcurrentDelayBetweenDeletes deletes concurrently with a delay using the default clock. Run it via
+// make test-race to check the workers do not race on the options
+func TestGCConcurrentDelayBetweenDeletes(t *testing.T) {
+	count := 8
+	var resources []string
+	for i := 0; i < count; i++ {
+		resources = append(resources, fmt.Sprintf(`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+  name: tf-myrepo-pr%d-myctx-1
+  namespace: jx
+`, i))
+	}
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, resources)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Concurrency = 4
+	o.DelayBetweenDeletes = time.Millisecond
+	o.QPS = 0
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+	assert.Equal(t, count, o.Deleted, "deleted count")
+	assert.NotNil(t, o.Clock, "should default the clock before deleting")
+}
+
+func TestGCReportOnlyErrors(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-5 * time.Hour)
+		if idx == 2 {
+			created = now.Add(-1 * time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			if stringhelpers.StringArrayIndex(c.Args, "tf-myrepo-pr456-myctx-2") >= 0 {
+				return "", errors.Errorf("simulated failure")
+			}
+			return "", nil
+		},
+	}
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.ReportOnlyErrors = true
+	o.UseKubectl = true
+	o.Retries = 0
+	o.CommandRunner = runner.Run
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	var err error
+	output := log.CaptureOutput(func() {
+		err = o.Run()
+	})
+	require.Error(t, err, "should fail as a resource could not be deleted")
+
+	assert.Contains(t, output, "simulated failure", "should log the error")
+	assert.Contains(t, output, "deleted 1, kept 1, errors 1", "should log the summary")
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if strings.Contains(line, "gc completed") {
+			continue
+		}
+		assert.Contains(t, line, "tf-myrepo-pr456-myctx-2", "should only log the failed resource")
+	}
+	assert.NotContains(t, output, "tf-myrepo-pr456-myctx-1", "should not log the deleted resource")
+	assert.NotContains(t, output, "tf-myrepo-pr999-myctx-3", "should not log the kept resource")
+}
+
+func TestGCNamespaceConfigMap(t *testing.T) {
+	namespaces := []string{"test-1", "test-2", "test-3"}
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetNamespace(namespaces[idx])
+		u.SetCreationTimestamp(metav1.Time{
+			Time: time.Now().Add(-5 * time.Hour),
+		})
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gc-namespaces",
+			Namespace: "jx",
+		},
+		Data: map[string]string{
+			"namespaces": "test-1\n test-3 , unknown\n",
+		},
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	kubeClient := fake.NewSimpleClientset(cm)
+
+	_, o := gc.NewCmdGC()
+	o.AllNamespaces = true
+	o.NamespaceConfigMap = "gc-namespaces/namespaces"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = kubeClient
+
+	result, err := o.RunWithResult(context.Background())
+	require.NoError(t, err, "failed to run gc command")
+
+	var deleted []string
+	for _, r := range result.Resources {
+		deleted = append(deleted, r.Namespace+"/"+r.Name)
+	}
+	assert.Equal(t, []string{"test-1/tf-myrepo-pr456-myctx-1", "test-3/tf-myrepo-pr999-myctx-3"}, deleted, "should only garbage collect the namespaces in the ConfigMap")
+
+	// lets check the ConfigMap is read again on the next run
+	cm.Data["namespaces"] = "test-2"
+	_, err = kubeClient.CoreV1().ConfigMaps("jx").Update(context.Background(), cm, metav1.UpdateOptions{})
+	require.NoError(t, err, "failed to update ConfigMap")
+
+	result, err = o.RunWithResult(context.Background())
+	require.NoError(t, err, "failed to run gc command again")
+	require.Len(t, result.Resources, 1, "should garbage collect the namespaces in the updated ConfigMap")
+	assert.Equal(t, "test-2", result.Resources[0].Namespace, "namespace of the resource")
+}
+
+func TestGCNamespaceConfigMapInvalid(t *testing.T) {
+	testCases := []struct {
+		name          string
+		configMap     string
+		allNamespaces bool
+		expected      string
+	}{
+		{name: "not all namespaces", configMap: "gc-namespaces/namespaces", expected: "requires --all-namespaces"},
+		{name: "invalid reference", configMap: "gc-namespaces", allNamespaces: true, expected: "must be name/key"},
+		{name: "missing ConfigMap", configMap: "jx/missing/namespaces", allNamespaces: true, expected: "failed to get the namespaces ConfigMap missing"},
+		{name: "missing key", configMap: "gc-namespaces/other", allNamespaces: true, expected: "does not have the key other"},
+	}
+	for _, tc := range testCases {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "gc-namespaces",
+				Namespace: "jx",
+			},
+			Data: map[string]string{
+				"namespaces": "test-1",
+			},
+		}
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.AllNamespaces = tc.allNamespaces
+		o.NamespaceConfigMap = tc.configMap
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+		o.KubeClient = fake.NewSimpleClientset(cm)
+
+		err := o.Run()
+		require.Error(t, err, "should fail for %s", tc.name)
+		assert.Contains(t, err.Error(), tc.expected, "error for %s", tc.name)
+	}
+}
+
+func TestParseNamespaceList(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, gc.ParseNamespaceList("c\nb, a\n\n,a"), "namespaces")
+	assert.Empty(t, gc.ParseNamespaceList(" \n"), "namespaces of blank text")
+}
+
+func TestGCSinceLastRun(t *testing.T) {
+	ns := "jx"
+	oldTime := time.Now().Add(-5 * time.Hour)
+	keep := []string{"yes", "2099-01-01", ""}
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+		if keep[idx] != "" {
+			labels := u.GetLabels()
+			labels["keep"] = keep[idx]
+			u.SetLabels(labels)
+		}
+	}
+	kubeClient := fake.NewSimpleClientset()
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.StateConfigMap = "gc-state"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, testResources)...)
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+	assert.Equal(t, 1, o.Deleted, "deleted count")
+
+	cm, err := kubeClient.CoreV1().ConfigMaps(ns).Get(o.GetContext(), "gc-state", metav1.GetOptions{})
+	require.NoError(t, err, "should have saved the state ConfigMap")
+	lastRun, err := time.Parse(time.RFC3339, cm.Data["lastRun"])
+	require.NoError(t, err, "failed to parse the lastRun")
+	assert.WithinDuration(t, time.Now(), lastRun, time.Minute, "lastRun")
+	assert.Equal(t, "yes", cm.Data["keptValues"], "should only store the keep label values which always keep resources")
+
+	// lets change the keep label of the last resource so that it is no longer kept
+	keep[2] = "no"
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, testResources)...)
+	_, o = gc.NewCmdGC()
+	o.Namespace = ns
+	o.StateConfigMap = "gc-state"
+	o.SinceLastRun = true
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = kubeClient
+
+	result, err := o.RunWithResult(o.GetContext())
+	require.NoError(t, err, "failed to run gc command since the last run")
+	require.NotNil(t, result, "should have a result")
+	assert.Equal(t, 1, o.Deleted, "deleted count")
+
+	actions := map[string]string{}
+	for _, rr := range result.Resources {
+		actions[rr.Name] = rr.Action
+	}
+	assert.Equal(t, map[string]string{
+		"tf-myrepo-pr456-myctx-2": gc.ActionKeptLabel,
+		"tf-myrepo-pr999-myctx-3": gc.ActionDeleted,
+	}, actions, "should not list the resource kept by the keep label in the last run")
+
+	var selectors []string
+	for _, action := range fakeDynClient.Actions() {
+		list, ok := action.(k8stesting.ListAction)
+		if ok && action.GetResource() == terraforms.TerraformResource {
+			selectors = append(selectors, list.GetListRestrictions().Labels.String())
+		}
+	}
+	require.NotEmpty(t, selectors, "should have listed the resources")
+	for _, selector := range selectors {
+		assert.Contains(t, selector, "keep notin (yes)", "list selector")
+	}
+
+	cm, err = kubeClient.CoreV1().ConfigMaps(ns).Get(o.GetContext(), "gc-state", metav1.GetOptions{})
+	require.NoError(t, err, "should have updated the state ConfigMap")
+	assert.Equal(t, "yes", cm.Data["keptValues"], "should carry over the keep label values of the resources not listed")
+}
+
+func TestGCSinceLastRunCapsState(t *testing.T) {
+	ns := "jx"
+
+	// lets store every capitalisation of true along with a date which does not always keep resources
+	var values []string
+	for i := 0; i < 16; i++ {
+		value := []byte("true")
+		for j := range value {
+			if i&(1<<j) != 0 {
+				value[j] = value[j] - 'a' + 'A'
+			}
+		}
+		values = append(values, string(value))
+	}
+	values = append(values, "2099-01-01")
+
+	kubeClient := fake.NewSimpleClientset()
+	_, o := gc.NewCmdGC()
+	o.Namespace = ns
+	o.StateConfigMap = "gc-state"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	cm, err := kubeClient.CoreV1().ConfigMaps(ns).Get(o.GetContext(), "gc-state", metav1.GetOptions{})
+	require.NoError(t, err, "should have saved the state ConfigMap")
+	cm.Data["keptValues"] = strings.Join(values, "\n")
+	_, err = kubeClient.CoreV1().ConfigMaps(ns).Update(o.GetContext(), cm, metav1.UpdateOptions{})
+	require.NoError(t, err, "failed to update the state ConfigMap")
+
+	_, o = gc.NewCmdGC()
+	o.Namespace = ns
+	o.StateConfigMap = "gc-state"
+	o.SinceLastRun = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = kubeClient
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run gc command since the last run")
+
+	cm, err = kubeClient.CoreV1().ConfigMaps(ns).Get(o.GetContext(), "gc-state", metav1.GetOptions{})
+	require.NoError(t, err, "should have updated the state ConfigMap")
+	saved := strings.Split(cm.Data["keptValues"], "\n")
+	assert.Len(t, saved, 10, "should cap the stored keep label values")
+	assert.NotContains(t, saved, "2099-01-01", "should not store a date")
+}
+
+func TestGCSinceLastRunWithKeepLast(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.StateConfigMap = "gc-state"
+	o.SinceLastRun = true
+	o.KeepLast = 1
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should not allow --since-last-run with --keep-last")
+	assert.Contains(t, err.Error(), "since-last-run", "error")
+}
+
+func TestGCSinceLastRunRequiresStateConfigMap(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.SinceLastRun = true
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should require --state-configmap")
+	assert.Contains(t, err.Error(), "state-configmap", "error")
+}
+
+// fakeReferenceChecker returns the references of each resource name
+type fakeReferenceChecker struct {
+	references map[string][]string
+	err        error
+}
+
+func (c *fakeReferenceChecker) ActiveReferences(_ context.Context, _, name string) ([]string, error) {
+	return c.references[name], c.err
+}
+
+func TestGCProtectIfReferenced(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	resources := append([]string{`apiVersion: tekton.dev/v1beta1
+kind: PipelineRun
+metadata:
+  labels:
+    terraform: tf-myrepo-pr456-myctx-1
+  name: myrepo-pr456-1
+  namespace: jx
+status:
+  conditions:
+  - type: Succeeded
+    status: Unknown
+`, `apiVersion: tekton.dev/v1beta1
+kind: PipelineRun
+metadata:
+  labels:
+    terraform: tf-myrepo-pr999-myctx-3
+  name: myrepo-pr999-1
+  namespace: jx
+status:
+  completionTime: "2024-01-02T15:04:05Z"
+`}, testResources...)
+
+	for _, dryRun := range []bool{false, true} {
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.DryRun = dryRun
+		o.ProtectIfReferenced = true
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, resources)...)
+		o.KubeClient = fake.NewSimpleClientset()
+
+		result, err := o.RunWithResult(o.GetContext())
+		require.NoError(t, err, "failed to run gc command with dry run %v", dryRun)
+
+		actions := map[string]string{}
+		for _, rr := range result.Resources {
+			actions[rr.Name] = rr.Action
+		}
+		deleted := gc.ActionDeleted
+		if dryRun {
+			deleted = gc.ActionWouldDelete
+		}
+		assert.Equal(t, map[string]string{
+			"tf-myrepo-pr456-myctx-1": gc.ActionSkippedReferenced,
+			"tf-myrepo-pr456-myctx-2": deleted,
+			"tf-myrepo-pr999-myctx-3": deleted,
+		}, actions, "actions with dry run %v", dryRun)
+		assert.Equal(t, 2, o.Deleted, "deleted count with dry run %v", dryRun)
+		assert.Equal(t, 1, result.Kept, "kept count with dry run %v", dryRun)
+	}
+}
+
+func TestGCProtectIfReferencedError(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.ProtectIfReferenced = true
+	o.References = &fakeReferenceChecker{err: errors.New("simulated failure")}
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, testResources)...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	result, err := o.RunWithResult(o.GetContext())
+	require.Error(t, err, "should fail if the references cannot be checked")
+	assert.Equal(t, 0, o.Deleted, "should not delete resources which could be referenced")
+	assert.Equal(t, 3, result.Errors, "error count")
+
+	_, o = gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.ProtectIfReferenced = true
+	o.ReferenceResource = "pipelineruns"
+	o.DynamicClient = &panicDynClient{}
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err = o.Run()
+	require.Error(t, err, "should fail for an invalid --reference-resource")
+	assert.Contains(t, err.Error(), "reference-resource", "error")
+}
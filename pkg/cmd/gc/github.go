@@ -0,0 +1,122 @@
+package gc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/root"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultGitHubURL the default GitHub API URL
+	defaultGitHubURL = "https://api.github.com"
+
+	// githubTokenEnvVar the environment variable used for the GitHub token if not specified via a flag
+	githubTokenEnvVar = "GITHUB_TOKEN"
+)
+
+// PullRequestCommenter comments on pull requests
+type PullRequestCommenter interface {
+	// CommentOnPullRequest adds the comment to the given pull request
+	CommentOnPullRequest(ctx context.Context, owner, repo string, number int, comment string) error
+}
+
+// PullRequestRef identifies the pull request a test resource was created for
+type PullRequestRef struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+// PullRequestFromLabels returns the pull request identified by the owner, repo and pr labels
+// of a test resource or nil if the labels do not identify a pull request.
+//
+// The repository and branch labels are used if the repo and pr labels are missing.
+func PullRequestFromLabels(labels map[string]string) *PullRequestRef {
+	owner := labels["owner"]
+	repo := labels["repo"]
+	if repo == "" {
+		repo = labels["repository"]
+	}
+	pr := labels["pr"]
+	if pr == "" {
+		pr = labels["branch"]
+	}
+	if owner == "" || repo == "" || pr == "" {
+		return nil
+	}
+	pr = strings.TrimPrefix(strings.ToLower(pr), "pr-")
+	number, err := strconv.Atoi(pr)
+	if err != nil || number <= 0 {
+		return nil
+	}
+	return &PullRequestRef{Owner: owner, Repo: repo, Number: number}
+}
+
+// GitHubCommenter comments on GitHub pull requests using the REST API
+type GitHubCommenter struct {
+	Token string
+	// URL the GitHub API URL which defaults to https://api.github.com
+	URL        string
+	HTTPClient *http.Client
+}
+
+// CommentOnPullRequest adds the comment to the given pull request
+func (c *GitHubCommenter) CommentOnPullRequest(ctx context.Context, owner, repo string, number int, comment string) error {
+	u := c.URL
+	if u == "" {
+		u = defaultGitHubURL
+	}
+	u = fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments", strings.TrimSuffix(u, "/"), owner, repo, number)
+
+	data, err := json.Marshal(map[string]string{"body": comment})
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal github comment")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create github request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+c.Token)
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to comment on pull request %s/%s#%d", owner, repo, number)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("failed to comment on pull request %s/%s#%d: status %s", owner, repo, number, resp.Status)
+	}
+	return nil
+}
+
+// commentOnPullRequest comments on the pull request of a deleted resource. Failures are only logged
+func (o *Options) commentOnPullRequest(ctx context.Context, kind, ns, name string, labels map[string]string) {
+	if o.Commenter == nil {
+		return
+	}
+	pr := PullRequestFromLabels(labels)
+	if pr == nil {
+		terraforms.Logger(ctx).Debugf("not commenting on a pull request for %s %s in namespace %s as its labels do not identify one", kind, name, ns)
+		return
+	}
+	comment := fmt.Sprintf("The test environment %s `%s` in namespace `%s` has been garbage collected by `%s gc`", kind, name, ns, root.BinaryName)
+	err := o.Commenter.CommentOnPullRequest(ctx, pr.Owner, pr.Repo, pr.Number, comment)
+	if err != nil {
+		terraforms.Logger(ctx).Warnf("failed to comment on pull request %s/%s#%d for %s %s: %s", pr.Owner, pr.Repo, pr.Number, kind, name, err.Error())
+	}
+}
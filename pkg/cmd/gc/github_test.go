@@ -0,0 +1,133 @@
+package gc_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type fakeCommenter struct {
+	Comments []string
+	Err      error
+}
+
+func (c *fakeCommenter) CommentOnPullRequest(ctx context.Context, owner, repo string, number int, comment string) error {
+	c.Comments = append(c.Comments, fmt.Sprintf("%s/%s#%d", owner, repo, number))
+	return c.Err
+}
+
+func TestGCCommentsOnPullRequests(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+		if idx == 2 {
+			labels := u.GetLabels()
+			delete(labels, "owner")
+			u.SetLabels(labels)
+		}
+	}
+
+	for _, failComment := range []bool{false, true} {
+		dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+		fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+		commenter := &fakeCommenter{}
+		if failComment {
+			commenter.Err = errors.New("simulated github failure")
+		}
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.Commenter = commenter
+		o.DynamicClient = fakeDynClient
+		o.KubeClient = fake.NewSimpleClientset()
+
+		err := o.Run()
+		require.NoError(t, err, "failed to run gc command when comment failure is %v", failComment)
+
+		assert.Equal(t, 3, o.Deleted, "deleted resources when comment failure is %v", failComment)
+		assert.Equal(t, []string{"myowner/myrepo#456", "myowner/myrepo#456"}, commenter.Comments, "pull request comments when comment failure is %v", failComment)
+	}
+}
+
+func TestPullRequestFromLabels(t *testing.T) {
+	testCases := []struct {
+		labels   map[string]string
+		expected *gc.PullRequestRef
+	}{
+		{
+			labels:   map[string]string{"owner": "myowner", "repo": "myrepo", "pr": "pr-456"},
+			expected: &gc.PullRequestRef{Owner: "myowner", Repo: "myrepo", Number: 456},
+		},
+		{
+			labels:   map[string]string{"owner": "myowner", "repository": "myrepo", "branch": "PR-12"},
+			expected: &gc.PullRequestRef{Owner: "myowner", Repo: "myrepo", Number: 12},
+		},
+		{
+			labels:   map[string]string{"owner": "myowner", "repo": "myrepo", "branch": "main"},
+			expected: nil,
+		},
+		{
+			labels:   map[string]string{"repo": "myrepo", "pr": "456"},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		got := gc.PullRequestFromLabels(tc.labels)
+		assert.Equal(t, tc.expected, got, "for labels %v", tc.labels)
+	}
+}
+
+func TestGitHubCommenter(t *testing.T) {
+	var paths, auths, bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err, "failed to read request body")
+		m := map[string]string{}
+		err = json.Unmarshal(data, &m)
+		require.NoError(t, err, "failed to parse request body %s", string(data))
+
+		paths = append(paths, r.URL.Path)
+		auths = append(auths, r.Header.Get("Authorization"))
+		bodies = append(bodies, m["body"])
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	c := &gc.GitHubCommenter{Token: "mytoken", URL: server.URL}
+	err := c.CommentOnPullRequest(context.TODO(), "myowner", "myrepo", 456, "hello")
+	require.NoError(t, err, "failed to comment")
+
+	assert.Equal(t, []string{"/repos/myowner/myrepo/issues/456/comments"}, paths, "paths")
+	assert.Equal(t, []string{"token mytoken"}, auths, "authorization headers")
+	assert.Equal(t, []string{"hello"}, bodies, "comment bodies")
+}
+
+func TestGitHubCommenterFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	c := &gc.GitHubCommenter{Token: "mytoken", URL: server.URL}
+	err := c.CommentOnPullRequest(context.TODO(), "myowner", "myrepo", 456, "hello")
+	require.Error(t, err, "should have failed to comment")
+}
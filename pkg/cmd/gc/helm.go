@@ -0,0 +1,32 @@
+package gc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+)
+
+// deleteHelmRelease uninstalls the Helm release with the same name as the deleted resource in its namespace for
+// --delete-helm-release. This is best effort so failures are only logged
+func (o *Options) deleteHelmRelease(ctx context.Context, kind, ns, name string) {
+	if o.DryRun {
+		terraforms.Logger(ctx).Infof("dry-run: would uninstall the helm release %s in namespace %s for %s %s", info(name), ns, kind, name)
+		return
+	}
+	c := &cmdrunner.Command{
+		Name: "helm",
+		Args: []string{"uninstall", name, "--namespace", ns},
+	}
+	_, err := o.CommandRunner(c)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			terraforms.Logger(ctx).Debugf("no helm release %s in namespace %s for %s %s", name, ns, kind, name)
+			return
+		}
+		terraforms.Logger(ctx).Warnf("failed to uninstall the helm release %s in namespace %s for %s %s: %s", info(name), ns, kind, name, err.Error())
+		return
+	}
+	terraforms.Logger(ctx).Infof("uninstalled the helm release %s in namespace %s", info(name), ns)
+}
@@ -0,0 +1,63 @@
+package gc
+
+import (
+	"context"
+	"strings"
+	"text/template"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/pkg/errors"
+)
+
+// HookData the values which can be used in the --pre-delete-hook and --post-delete-hook templates
+type HookData struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+// parseHook parses the hook command template
+func parseHook(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// renderHook returns the hook command with the values of the resource substituted
+func renderHook(name, text string, data *HookData) (string, error) {
+	t, err := parseHook(name, text)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the %s template %s", name, text)
+	}
+	buf := &strings.Builder{}
+	err = t.Execute(buf, data)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to evaluate the %s template %s", name, text)
+	}
+	return buf.String(), nil
+}
+
+// runHook runs the hook command for the resource via the shell. Nothing is run if the hook is empty and the
+// command is only logged in dry run mode
+func (o *Options) runHook(ctx context.Context, name, text, kind, ns, resourceName string) error {
+	if text == "" {
+		return nil
+	}
+	command, err := renderHook(name, text, &HookData{Kind: kind, Name: resourceName, Namespace: ns})
+	if err != nil {
+		return err
+	}
+	if o.DryRun {
+		terraforms.Logger(ctx).Infof("dry-run: would run the %s %s for %s %s", name, info(command), kind, resourceName)
+		return nil
+	}
+	c := &cmdrunner.Command{
+		Name: "sh",
+		Args: []string{"-c", command},
+	}
+	terraforms.Logger(ctx).Debugf("running the %s %s for %s %s in namespace %s", name, command, kind, resourceName, ns)
+	_, err = o.CommandRunner(c)
+	if err != nil {
+		return errors.Wrapf(err, "failed to run the %s %s", name, command)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/root"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	listLong = templates.LongDesc(`
+		Lists the test resources and whether they would be garbage collected
+`)
+
+	listExample = templates.Examples(`
+		%s gc list
+
+		# include the pull request, TTL and active Terraform jobs of each resource
+		%s gc list -o wide
+	`)
+)
+
+// ListOptions the options for the list command
+type ListOptions struct {
+	FilterOptions
+	Output        string
+	KubeClient    kubernetes.Interface
+	DynamicClient dynamic.Interface
+	Ctx           context.Context
+	Out           io.Writer
+}
+
+// NewCmdList creates a command object for the command
+func NewCmdList() (*cobra.Command, *ListOptions) {
+	o := &ListOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "Lists the test resources and whether they would be garbage collected",
+		Long:    listLong,
+		Example: fmt.Sprintf(listExample, root.BinaryName, root.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+
+	if o.Ctx == nil {
+		o.Ctx = cmd.Context()
+	}
+
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "", "the output format. Supported values: wide")
+
+	o.FilterOptions.AddFlags(cmd)
+	return cmd, o
+}
+
+// Run implements the command
+func (o *ListOptions) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate setup")
+	}
+
+	gvrs, err := o.GroupVersionResources()
+	if err != nil {
+		return err
+	}
+
+	ctx := o.GetContext()
+	namespaces, err := o.ListNamespaces(ctx, o.KubeClient)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var candidates []*Candidate
+	kinds := map[*Candidate]string{}
+	for _, gvr := range gvrs {
+		kind := o.ResourceKindName(o.KubeClient, gvr)
+		kindCandidates, err := o.listAllCandidates(ctx, o.DynamicClient, namespaces, gvr, kind, now)
+		if err != nil {
+			return err
+		}
+		for _, c := range kindCandidates {
+			kinds[c] = kind
+		}
+		candidates = append(candidates, kindCandidates...)
+	}
+
+	// lets show the oldest first
+	sort.SliceStable(candidates, func(i, j int) bool {
+		t1 := candidates[i].Resource.GetCreationTimestamp()
+		t2 := candidates[j].Resource.GetCreationTimestamp()
+		return t1.Before(&t2)
+	})
+
+	// lets only show the kind of each resource if several kinds are listed
+	showKind := len(gvrs) > 1
+	wide := o.Output == "wide"
+	header := []string{"NAME", "NAMESPACE", "AGE", "KEEP", "WOULD-GC"}
+	if wide {
+		header = append(header, "PR", "BRANCH", "TTL", "ACTIVE-JOBS")
+	}
+	if showKind {
+		header = append([]string{"KIND"}, header...)
+	}
+	t := table.CreateTable(o.Out)
+	t.AddRow(header...)
+	for _, c := range candidates {
+		r := c.Resource
+		kind := kinds[c]
+		ns := o.resourceNamespace(r)
+		created := r.GetCreationTimestamp()
+		resourceLabels := r.GetLabels()
+		wouldGC := "no"
+		if c.ShouldDelete {
+			wouldGC = "yes"
+		}
+		row := []string{r.GetName(), ns, now.Sub(created.Time).Round(time.Second).String(), o.keepValue(r), wouldGC}
+		if wide {
+			activeJobs, err := terraforms.CountActiveTerraformJobs(ctx, o.KubeClient, ns, r.GetName(), o.jobOptions())
+			if err != nil {
+				return errors.Wrapf(err, "failed to count active jobs for %s %s", kind, r.GetName())
+			}
+			row = append(row, resourceLabels["pr"], resourceLabels["branch"], r.GetAnnotations()[terraforms.AnnotationTTL], strconv.Itoa(activeJobs))
+		}
+		if showKind {
+			row = append([]string{kind}, row...)
+		}
+		t.AddRow(row...)
+	}
+	t.Render()
+	return nil
+}
+
+// Validate validates the options
+func (o *ListOptions) Validate() error {
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	if o.Output != "" && o.Output != "wide" {
+		return options.InvalidOption("output", o.Output, []string{"wide"})
+	}
+	err := o.FilterOptions.Validate()
+	if err != nil {
+		return err
+	}
+	o.KubeClient, o.DynamicClient, err = o.LazyCreateClients(o.KubeClient, o.DynamicClient)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetContext lazily creates a context if it doesn't exist already
+func (o *ListOptions) GetContext() context.Context {
+	if o.Ctx == nil {
+		o.Ctx = context.TODO()
+	}
+	return o.Ctx
+}
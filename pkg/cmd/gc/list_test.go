@@ -0,0 +1,253 @@
+package gc_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestList(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	now := time.Now()
+	ages := []time.Duration{3 * time.Hour, 5 * time.Hour, time.Hour}
+
+	fn := func(idx int, u *unstructured.Unstructured) {
+		if idx == 0 {
+			u.SetLabels(map[string]string{"kind": "jx-test", "keep": "yes"})
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-ages[idx]),
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(scheme, dynObjects...)
+
+	out := &bytes.Buffer{}
+	_, o := gc.NewCmdList()
+	o.Namespace = "jx"
+	o.Out = out
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run list command")
+
+	t.Logf("%s\n", out.String())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4, "lines")
+	assert.Equal(t, []string{"NAME", "NAMESPACE", "AGE", "KEEP", "WOULD-GC"}, strings.Fields(lines[0]), "header")
+	expected := [][]string{
+		{"tf-myrepo-pr456-myctx-2", "jx", "5h0m", "", "yes"},
+		{"tf-myrepo-pr456-myctx-1", "jx", "3h0m", "yes", "no"},
+		{"tf-myrepo-pr999-myctx-3", "jx", "1h0m", "", "no"},
+	}
+	for i, e := range expected {
+		fields := strings.Fields(lines[i+1])
+		if e[3] == "" {
+			e = append(e[0:3], e[4])
+		}
+		require.Len(t, fields, len(e), "fields for line %s", lines[i+1])
+		for j := range e {
+			if j == 2 {
+				assert.True(t, strings.HasPrefix(fields[j], e[j]), "age %s should start with %s", fields[j], e[j])
+				continue
+			}
+			assert.Equal(t, e[j], fields[j], "column %d of line %s", j, lines[i+1])
+		}
+	}
+
+	list, err := fakeDynClient.Resource(terraforms.TerraformResource).Namespace("jx").List(o.GetContext(), metav1.ListOptions{})
+	require.NoError(t, err, "failed to list resources")
+	require.Len(t, list.Items, 3, "should not have removed any resources")
+}
+
+func TestListWide(t *testing.T) {
+	now := time.Now()
+	ttls := []string{"2h", "", ""}
+
+	fn := func(idx int, u *unstructured.Unstructured) {
+		if ttls[idx] != "" {
+			u.SetAnnotations(map[string]string{terraforms.AnnotationTTL: ttls[idx]})
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-time.Duration(3-idx) * time.Hour),
+		})
+	}
+
+	kubeClient := fake.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-1", Namespace: "jx"},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-2", Namespace: "jx"},
+			Status:     batchv1.JobStatus{Succeeded: 1, CompletionTime: &metav1.Time{Time: now}},
+		},
+	)
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	out := &bytes.Buffer{}
+	_, o := gc.NewCmdList()
+	o.Namespace = "jx"
+	o.Output = "wide"
+	o.Out = out
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run list command")
+
+	t.Logf("%s\n", out.String())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4, "lines")
+	assert.Equal(t, []string{"NAME", "NAMESPACE", "AGE", "KEEP", "WOULD-GC", "PR", "BRANCH", "TTL", "ACTIVE-JOBS"}, strings.Fields(lines[0]), "header")
+
+	// the keep and branch columns are empty so are skipped by strings.Fields
+	expected := [][]string{
+		{"tf-myrepo-pr456-myctx-1", "jx", "3h0m", "yes", "pr-456", "2h", "1"},
+		{"tf-myrepo-pr456-myctx-2", "jx", "2h0m", "yes", "pr-456", "0"},
+		{"tf-myrepo-pr999-myctx-3", "jx", "1h0m", "no", "pr-999", "0"},
+	}
+	for i, e := range expected {
+		fields := strings.Fields(lines[i+1])
+		require.Len(t, fields, len(e), "fields for line %s", lines[i+1])
+		for j := range e {
+			if j == 2 {
+				assert.True(t, strings.HasPrefix(fields[j], e[j]), "age %s should start with %s", fields[j], e[j])
+				continue
+			}
+			assert.Equal(t, e[j], fields[j], "column %d of line %s", j, lines[i+1])
+		}
+	}
+}
+
+func TestListWideJobLabel(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-time.Duration(3-idx) * time.Hour),
+		})
+	}
+
+	// the Jobs are not named after the resources so can only be found by their label
+	kubeClient := fake.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-apply-abc", Namespace: "jx", Labels: map[string]string{"terraform": "tf-myrepo-pr999-myctx-3"}},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-apply-def", Namespace: "jx", Labels: map[string]string{"terraform": "tf-myrepo-pr999-myctx-3"}},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-1", Namespace: "jx"},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+	)
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	out := &bytes.Buffer{}
+	_, o := gc.NewCmdList()
+	o.Namespace = "jx"
+	o.Output = "wide"
+	o.JobLabel = "terraform"
+	o.Out = out
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run list command")
+
+	t.Logf("%s\n", out.String())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4, "lines")
+
+	activeJobs := map[string]string{}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		activeJobs[fields[0]] = fields[len(fields)-1]
+	}
+	assert.Equal(t, map[string]string{
+		"tf-myrepo-pr456-myctx-1": "0",
+		"tf-myrepo-pr456-myctx-2": "0",
+		"tf-myrepo-pr999-myctx-3": "2",
+	}, activeJobs, "active Jobs found by label")
+}
+
+func TestListMultipleResources(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "tf.isaaguilar.com", Version: "v1alpha1", Resource: "tfworkspaces"}
+
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-5 * time.Hour),
+		})
+	}
+	workspaceFn := func(idx int, u *unstructured.Unstructured) {
+		u.SetKind("TFWorkspace")
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-time.Hour),
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:1])
+	dynObjects = append(dynObjects, tftests.ParseUnstructureds(t, workspaceFn, testResources[2:])...)
+
+	out := &bytes.Buffer{}
+	_, o := gc.NewCmdList()
+	o.Namespace = "jx"
+	o.Resources = []string{terraforms.TerraformResource.Resource, gvr.Group + "/" + gvr.Version + "/" + gvr.Resource}
+	o.Out = out
+	o.DynamicClient = dynfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		terraforms.TerraformResource: "TerraformList",
+		gvr:                          "TFWorkspaceList",
+	}, dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run list command")
+
+	t.Logf("%s\n", out.String())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3, "lines")
+	assert.Equal(t, []string{"KIND", "NAME", "NAMESPACE", "AGE", "KEEP", "WOULD-GC"}, strings.Fields(lines[0]), "header")
+
+	// the keep column is empty so is skipped by strings.Fields
+	fields := strings.Fields(lines[1])
+	assert.Equal(t, []string{"Terraform", "tf-myrepo-pr456-myctx-1", "jx"}, fields[0:3], "first line")
+	assert.Equal(t, "yes", fields[len(fields)-1], "would gc the old Terraform")
+	fields = strings.Fields(lines[2])
+	assert.Equal(t, []string{"Tfworkspace", "tf-myrepo-pr999-myctx-3", "jx"}, fields[0:3], "second line")
+	assert.Equal(t, "no", fields[len(fields)-1], "would not gc the new TFWorkspace")
+}
+
+func TestListInvalidOutput(t *testing.T) {
+	_, o := gc.NewCmdList()
+	o.Namespace = "jx"
+	o.Output = "yaml"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail with an invalid output format")
+	assert.Contains(t, err.Error(), "yaml", "error message")
+}
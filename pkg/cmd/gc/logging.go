@@ -0,0 +1,56 @@
+package gc
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// LogFormatText logs human readable text
+	LogFormatText = "text"
+
+	// LogFormatJSON additionally logs each action taken on a resource as a JSON line
+	LogFormatJSON = "json"
+)
+
+var logFormats = []string{LogFormatText, LogFormatJSON}
+
+// ActionLogEntry a structured log entry for an action taken on a resource
+type ActionLogEntry struct {
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace"`
+	Age       string    `json:"age"`
+	Action    string    `json:"action"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// logAction writes a structured log entry for the action if using the JSON log format.
+// The caller must hold the resultLock so that concurrent entries are not interleaved
+func (o *Options) logAction(kind string, rr *ResourceResult) {
+	if o.LogFormat != LogFormatJSON {
+		return
+	}
+	entry := ActionLogEntry{
+		Time:      time.Now().UTC(),
+		Kind:      kind,
+		Name:      rr.Name,
+		Namespace: rr.Namespace,
+		Age:       rr.Age,
+		Action:    rr.Action,
+		Error:     rr.Error,
+	}
+	data, err := json.Marshal(&entry)
+	if err != nil {
+		log.Logger().Warnf("failed to marshal log entry for %s: %s", rr.Name, err.Error())
+		return
+	}
+	_, err = fmt.Fprintln(o.LogOut, string(data))
+	if err != nil {
+		log.Logger().Warnf("failed to write log entry for %s: %s", rr.Name, err.Error())
+	}
+}
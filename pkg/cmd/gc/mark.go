@@ -0,0 +1,84 @@
+package gc
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// markResources labels the resources so that they are deleted by a later sweep once the owners have had a chance
+// to add a keep label. Resources which are already marked keep their original timestamp
+func (o *Options) markResources(ctx context.Context, gvr schema.GroupVersionResource, kind string, resources []*unstructured.Unstructured, now time.Time) error {
+	value := strconv.FormatInt(now.Unix(), 10)
+	var errs []error
+	for _, r := range resources {
+		name := r.GetName()
+		ns := o.resourceNamespace(r)
+		if r.GetLabels()[terraforms.LabelMarkedForGC] != "" {
+			log.Logger().Debugf("%s %s in namespace %s is already marked for gc", kind, info(name), ns)
+			o.addResult(r, now, ActionMarked, nil)
+			continue
+		}
+		if o.DryRun {
+			log.Logger().Infof("dry-run: would mark %s %s in namespace %s for gc", kind, info(name), ns)
+			o.addResult(r, now, ActionMarked, nil)
+			continue
+		}
+		client := dynkube.DynamicResource(o.DynamicClient, ns, gvr)
+		err := dynkube.SetLabel(ctx, client, name, terraforms.LabelMarkedForGC, value)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to mark %s %s in namespace %s", kind, name, ns)
+			o.addResult(r, now, ActionError, err)
+			if o.FailFast {
+				return err
+			}
+			errs = append(errs, err)
+			continue
+		}
+		log.Logger().Infof("marked %s %s in namespace %s for gc", kind, info(name), ns)
+		o.addResult(r, now, ActionMarked, nil)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// sweepable returns the resources which were marked for gc longer than the sweep grace period ago. The other
+// resources are kept
+func (o *Options) sweepable(kind string, resources []*unstructured.Unstructured, now time.Time, logKept func(string, ...interface{})) []*unstructured.Unstructured {
+	var answer []*unstructured.Unstructured
+	for _, r := range resources {
+		marked, ok := markedTime(r)
+		if ok && now.Sub(marked) >= o.SweepGracePeriod {
+			answer = append(answer, r)
+			continue
+		}
+		if ok {
+			logKept("not removing %s %s as it was only marked for gc at %s", kind, info(r.GetName()), marked.String())
+		} else {
+			logKept("not removing %s %s as it has not been marked for gc", kind, info(r.GetName()))
+		}
+		o.addResult(r, now, ActionKeptNotMarked, nil)
+	}
+	return answer
+}
+
+// markedTime returns the time the resource was marked for gc if it has a valid marked label
+func markedTime(r *unstructured.Unstructured) (time.Time, bool) {
+	value := r.GetLabels()[terraforms.LabelMarkedForGC]
+	if value == "" {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Logger().Warnf("ignoring invalid %s label %s on %s: %s", terraforms.LabelMarkedForGC, value, r.GetName(), err.Error())
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
@@ -0,0 +1,311 @@
+package gc
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Metrics the Prometheus metrics for gc runs
+type Metrics struct {
+	Deleted    *prometheus.CounterVec
+	Kept       *prometheus.CounterVec
+	Errors     prometheus.Counter
+	Candidates prometheus.Gauge
+	DeletedAge prometheus.Histogram
+	APICall    *prometheus.HistogramVec
+
+	// resourceLabels the resource labels exported as labels on the deleted and kept counters
+	resourceLabels []string
+	// gatherer the registry the metrics are registered with if it can be gathered for --metrics-file
+	gatherer prometheus.Gatherer
+	values   map[string]map[string]bool
+	lock     sync.Mutex
+}
+
+// maxMetricLabelValues the maximum number of distinct values of each resource label exported as a metric label to
+// bound the cardinality of the metrics. Any other values are recorded as otherMetricLabelValue
+const maxMetricLabelValues = 100
+
+// otherMetricLabelValue the metric label value used once maxMetricLabelValues is reached
+const otherMetricLabelValue = "other"
+
+var invalidMetricLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// deletedAgeBuckets the buckets in seconds of the age of deleted resources ranging from 5 minutes to a week
+var deletedAgeBuckets = []float64{
+	(5 * time.Minute).Seconds(),
+	(15 * time.Minute).Seconds(),
+	(30 * time.Minute).Seconds(),
+	time.Hour.Seconds(),
+	(2 * time.Hour).Seconds(),
+	(4 * time.Hour).Seconds(),
+	(8 * time.Hour).Seconds(),
+	(12 * time.Hour).Seconds(),
+	(24 * time.Hour).Seconds(),
+	(48 * time.Hour).Seconds(),
+	(96 * time.Hour).Seconds(),
+	(168 * time.Hour).Seconds(),
+}
+
+// NewMetrics creates the metrics registering them with the given registry
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m, _ := NewMetricsWithLabels(reg, nil)
+	return m
+}
+
+// NewMetricsWithLabels creates the metrics registering them with the given registry. The values of the given
+// resource labels are exported as labels on the deleted and kept counters
+func NewMetricsWithLabels(reg prometheus.Registerer, resourceLabels []string) (*Metrics, error) {
+	labelNames, err := MetricLabelNames(resourceLabels)
+	if err != nil {
+		return nil, err
+	}
+	m := &Metrics{
+		Deleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "jxtest_gc_deleted_total",
+			Help: "The number of test resources garbage collected",
+		}, labelNames),
+		Kept: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "jxtest_gc_kept_total",
+			Help: "The number of test resources kept",
+		}, labelNames),
+		Errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "jxtest_gc_errors_total",
+			Help: "The number of test resources which failed to be garbage collected",
+		}),
+		Candidates: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "jxtest_gc_candidates",
+			Help: "The number of test resources eligible for garbage collection in the last run",
+		}),
+		DeletedAge: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "jxtest_gc_deleted_age_seconds",
+			Help:    "The age in seconds of test resources when they were garbage collected",
+			Buckets: deletedAgeBuckets,
+		}),
+		APICall: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "jxtest_gc_apicall_duration_seconds",
+			Help:    "The duration in seconds of the list and delete calls to the API server and whether they were throttled",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"verb", "throttled"}),
+	}
+	m.resourceLabels = resourceLabels
+	m.gatherer, _ = reg.(prometheus.Gatherer)
+	m.values = map[string]map[string]bool{}
+	if len(labelNames) == 0 {
+		// lets report the counters before anything is deleted or kept
+		m.Deleted.WithLabelValues()
+		m.Kept.WithLabelValues()
+	}
+	reg.MustRegister(m.Deleted, m.Kept, m.Errors, m.Candidates, m.DeletedAge, m.APICall)
+	return m, nil
+}
+
+// MetricLabelNames returns the Prometheus label names for the resource labels replacing any characters which are
+// not valid in a Prometheus label name with an underscore such as app.kubernetes.io/name to app_kubernetes_io_name
+func MetricLabelNames(resourceLabels []string) ([]string, error) {
+	var answer []string
+	for _, l := range resourceLabels {
+		name := invalidMetricLabelChars.ReplaceAllString(l, "_")
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			name = "_" + name
+		}
+		if strings.HasPrefix(name, "__") {
+			return nil, errors.Errorf("the label %s cannot be used as a metric label as names starting with __ are reserved", l)
+		}
+		if stringhelpers.StringArrayIndex(answer, name) >= 0 {
+			return nil, errors.Errorf("the label %s has the same metric label name %s as another label", l, name)
+		}
+		answer = append(answer, name)
+	}
+	return answer, nil
+}
+
+// labelValues returns the values of the exported resource labels of the resource. Once a label has
+// maxMetricLabelValues distinct values any new values are replaced with otherMetricLabelValue
+func (m *Metrics) labelValues(resourceLabels map[string]string) []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	answer := make([]string, 0, len(m.resourceLabels))
+	for _, l := range m.resourceLabels {
+		value := resourceLabels[l]
+		values := m.values[l]
+		if values == nil {
+			values = map[string]bool{}
+			m.values[l] = values
+		}
+		if !values[value] {
+			if len(values) >= maxMetricLabelValues {
+				value = otherMetricLabelValue
+			} else {
+				values[value] = true
+			}
+		}
+		answer = append(answer, value)
+	}
+	return answer
+}
+
+// observe records the action taken on a resource of the given age with the given labels
+func (m *Metrics) observe(action string, age time.Duration, resourceLabels map[string]string) {
+	if m == nil {
+		return
+	}
+	switch {
+	case action == ActionDeleted:
+		m.Deleted.WithLabelValues(m.labelValues(resourceLabels)...).Inc()
+		m.DeletedAge.Observe(age.Seconds())
+	case isKeptAction(action):
+		m.Kept.WithLabelValues(m.labelValues(resourceLabels)...).Inc()
+	case action == ActionError:
+		m.Errors.Inc()
+	}
+}
+
+// setCandidates records the number of resources eligible for garbage collection
+func (m *Metrics) setCandidates(count int) {
+	if m == nil {
+		return
+	}
+	m.Candidates.Set(float64(count))
+}
+
+// observeAPICall records the duration of a call to the API server which started at the given time along with
+// whether it was throttled by the API server returning 429 Too Many Requests
+func (m *Metrics) observeAPICall(verb string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	throttled := apierrors.IsTooManyRequests(err)
+	m.APICall.WithLabelValues(verb, strconv.FormatBool(throttled)).Observe(time.Since(start).Seconds())
+}
+
+// instrument wraps the dynamic client so that the duration of list and delete calls are recorded. The client is
+// returned as is if there are no metrics
+func (m *Metrics) instrument(client dynamic.Interface) dynamic.Interface {
+	if m == nil || client == nil {
+		return client
+	}
+	return &instrumentedClient{Interface: client, metrics: m}
+}
+
+type instrumentedClient struct {
+	dynamic.Interface
+	metrics *Metrics
+}
+
+func (c *instrumentedClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &instrumentedNamespaceableResource{NamespaceableResourceInterface: c.Interface.Resource(gvr), metrics: c.metrics}
+}
+
+type instrumentedNamespaceableResource struct {
+	dynamic.NamespaceableResourceInterface
+	metrics *Metrics
+}
+
+func (r *instrumentedNamespaceableResource) Namespace(ns string) dynamic.ResourceInterface {
+	return &instrumentedResource{ResourceInterface: r.NamespaceableResourceInterface.Namespace(ns), metrics: r.metrics}
+}
+
+func (r *instrumentedNamespaceableResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return instrumentedList(ctx, r.NamespaceableResourceInterface, r.metrics, opts)
+}
+
+func (r *instrumentedNamespaceableResource) Delete(ctx context.Context, name string, options metav1.DeleteOptions, subresources ...string) error {
+	return instrumentedDelete(ctx, r.NamespaceableResourceInterface, r.metrics, name, options, subresources...)
+}
+
+type instrumentedResource struct {
+	dynamic.ResourceInterface
+	metrics *Metrics
+}
+
+func (r *instrumentedResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return instrumentedList(ctx, r.ResourceInterface, r.metrics, opts)
+}
+
+func (r *instrumentedResource) Delete(ctx context.Context, name string, options metav1.DeleteOptions, subresources ...string) error {
+	return instrumentedDelete(ctx, r.ResourceInterface, r.metrics, name, options, subresources...)
+}
+
+func instrumentedList(ctx context.Context, client dynamic.ResourceInterface, m *Metrics, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	start := time.Now()
+	list, err := client.List(ctx, opts)
+	m.observeAPICall("list", start, err)
+	return list, err
+}
+
+func instrumentedDelete(ctx context.Context, client dynamic.ResourceInterface, m *Metrics, name string, options metav1.DeleteOptions, subresources ...string) error {
+	start := time.Now()
+	err := client.Delete(ctx, name, options, subresources...)
+	m.observeAPICall("delete", start, err)
+	return err
+}
+
+// startMetrics creates the metrics if --metrics-address or --metrics-file is specified and starts serving them on
+// --metrics-address. The server is started once and serves until the process exits so that the metrics of the last
+// run can still be scraped and later runs do not need to start it again. Metrics injected by the caller are used as is
+func (o *Options) startMetrics() error {
+	if o.Metrics != nil || (o.MetricsAddress == "" && o.MetricsFile == "") {
+		return nil
+	}
+	reg := prometheus.NewRegistry()
+	var err error
+	o.Metrics, err = NewMetricsWithLabels(reg, o.LabelsAsMetrics)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create metrics")
+	}
+	if o.MetricsAddress != "" {
+		startMetricsServer(o.MetricsAddress, reg)
+	}
+	return nil
+}
+
+// writeMetricsFile writes the metrics to --metrics-file in the Prometheus text format
+func (o *Options) writeMetricsFile() error {
+	if o.MetricsFile == "" || o.Metrics == nil {
+		return nil
+	}
+	if o.Metrics.gatherer == nil {
+		return errors.Errorf("cannot write the metrics to %s as their registry cannot be gathered", o.MetricsFile)
+	}
+	err := prometheus.WriteToTextfile(o.MetricsFile, o.Metrics.gatherer)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write metrics file %s", o.MetricsFile)
+	}
+	return nil
+}
+
+// startMetricsServer starts a HTTP server in the background exposing the metrics in the registry until the process exits
+func startMetricsServer(address string, reg prometheus.Gatherer) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Logger().Warnf("failed to serve metrics on %s: %s", address, err.Error())
+		}
+	}()
+	log.Logger().Infof("serving metrics on %s/metrics", address)
+}
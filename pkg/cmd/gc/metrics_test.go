@@ -0,0 +1,290 @@
+package gc_test
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestGCMetrics(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	now := time.Now()
+	recentTime := now.Add(-1 * time.Hour)
+	oldTime := now.Add(-5 * time.Hour)
+
+	fn := func(idx int, u *unstructured.Unstructured) {
+		t := oldTime
+		if idx > 1 {
+			t = recentTime
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: t,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(scheme, dynObjects...)
+
+	reg := prometheus.NewRegistry()
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Metrics = gc.NewMetrics(reg)
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	families, err := reg.Gather()
+	require.NoError(t, err, "failed to gather metrics")
+
+	values := map[string]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			if m.GetCounter() != nil {
+				values[f.GetName()] = m.GetCounter().GetValue()
+			} else if m.GetGauge() != nil {
+				values[f.GetName()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		"jxtest_gc_deleted_total": 2,
+		"jxtest_gc_kept_total":    1,
+		"jxtest_gc_errors_total":  0,
+		"jxtest_gc_candidates":    2,
+	}, values, "metric values")
+}
+
+func TestGCMetricsServedAfterRun(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{Time: oldTime})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	// lets find a free port for the metrics server
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "failed to find a free port")
+	address := l.Addr().String()
+	require.NoError(t, l.Close(), "failed to close listener")
+
+	metricsFile := filepath.Join(t.TempDir(), "gc.prom")
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.MetricsAddress = address
+	o.MetricsFile = metricsFile
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	for i := 0; i < 2; i++ {
+		err = o.Run()
+		require.NoError(t, err, "failed to run gc command %d", i)
+	}
+
+	data, err := os.ReadFile(metricsFile)
+	require.NoError(t, err, "failed to read metrics file")
+	assert.Contains(t, string(data), "jxtest_gc_deleted_total 3", "metrics file")
+	assert.Contains(t, string(data), "jxtest_gc_candidates 0", "metrics file of the last run")
+
+	// lets check the metrics are still served once the runs have completed
+	var body []byte
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + address + "/metrics") //nolint:noctx
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		body, err = io.ReadAll(resp.Body)
+		return err == nil && resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond, "metrics should be served after the run")
+	assert.Contains(t, string(body), "jxtest_gc_deleted_total 3", "served metrics")
+}
+
+func TestGCMetricsDeletedAge(t *testing.T) {
+	now := time.Now()
+	ages := []time.Duration{3 * time.Hour, 50 * time.Hour, time.Hour}
+
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: now.Add(-ages[idx]),
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	reg := prometheus.NewRegistry()
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Metrics = gc.NewMetrics(reg)
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	families, err := reg.Gather()
+	require.NoError(t, err, "failed to gather metrics")
+
+	var found bool
+	for _, f := range families {
+		if f.GetName() != "jxtest_gc_deleted_age_seconds" {
+			continue
+		}
+		found = true
+		require.Len(t, f.GetMetric(), 1, "metrics")
+		h := f.GetMetric()[0].GetHistogram()
+		require.NotNil(t, h, "histogram")
+		assert.Equal(t, uint64(2), h.GetSampleCount(), "sample count")
+		assert.InDelta(t, (53 * time.Hour).Seconds(), h.GetSampleSum(), 60, "sample sum")
+
+		cumulative := map[float64]uint64{}
+		for _, b := range h.GetBucket() {
+			cumulative[b.GetUpperBound()] = b.GetCumulativeCount()
+		}
+		assert.Equal(t, uint64(1), cumulative[(4*time.Hour).Seconds()], "resources deleted within 4 hours")
+		assert.Equal(t, uint64(2), cumulative[(96*time.Hour).Seconds()], "resources deleted within 4 days")
+	}
+	assert.True(t, found, "should have found the deleted age histogram")
+}
+
+func TestGCMetricsAPICallDuration(t *testing.T) {
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	// lets throttle the first delete so it is retried
+	throttled := false
+	fakeDynClient.PrependReactor("delete", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if throttled {
+			return false, nil, nil
+		}
+		throttled = true
+		return true, nil, apierrors.NewTooManyRequests("slow down", 1)
+	})
+
+	reg := prometheus.NewRegistry()
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.RetryBackoff = time.Millisecond
+	o.Metrics = gc.NewMetrics(reg)
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+	assert.Equal(t, 3, o.Deleted, "deleted count")
+
+	families, err := reg.Gather()
+	require.NoError(t, err, "failed to gather metrics")
+
+	counts := map[string]uint64{}
+	for _, f := range families {
+		if f.GetName() != "jxtest_gc_apicall_duration_seconds" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			counts[labels["verb"]+"/"+labels["throttled"]] = m.GetHistogram().GetSampleCount()
+		}
+	}
+	assert.Equal(t, map[string]uint64{
+		"list/false":   1,
+		"delete/false": 3,
+		"delete/true":  1,
+	}, counts, "API call observations")
+}
+
+func TestGCMetricsLabelsAsMetrics(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-5 * time.Hour)
+		if idx == 2 {
+			created = now.Add(-1 * time.Hour)
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+		labels := u.GetLabels()
+		labels["app.kubernetes.io/team"] = "team-a"
+		u.SetLabels(labels)
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	reg := prometheus.NewRegistry()
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.LabelsAsMetrics = []string{"repo", "app.kubernetes.io/team"}
+	m, err := gc.NewMetricsWithLabels(reg, o.LabelsAsMetrics)
+	require.NoError(t, err, "failed to create metrics")
+	o.Metrics = m
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	families, err := reg.Gather()
+	require.NoError(t, err, "failed to gather metrics")
+
+	values := map[string]float64{}
+	for _, f := range families {
+		if f.GetName() != "jxtest_gc_deleted_total" && f.GetName() != "jxtest_gc_kept_total" {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			key := f.GetName()
+			for _, l := range metric.GetLabel() {
+				key += "," + l.GetName() + "=" + l.GetValue()
+			}
+			values[key] = metric.GetCounter().GetValue()
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		"jxtest_gc_deleted_total,app_kubernetes_io_team=team-a,repo=myrepo": 2,
+		"jxtest_gc_kept_total,app_kubernetes_io_team=team-a,repo=myrepo":    1,
+	}, values, "metric values with label dimensions")
+}
+
+func TestMetricLabelNames(t *testing.T) {
+	names, err := gc.MetricLabelNames([]string{"repo", "app.kubernetes.io/name", "2fa"})
+	require.NoError(t, err, "failed to get metric label names")
+	assert.Equal(t, []string{"repo", "app_kubernetes_io_name", "_2fa"}, names, "metric label names")
+
+	_, err = gc.MetricLabelNames([]string{"app.name", "app/name"})
+	assert.Error(t, err, "should fail if two labels have the same metric label name")
+
+	_, err = gc.MetricLabelNames([]string{"__name"})
+	assert.Error(t, err, "should fail for reserved metric label names")
+}
@@ -0,0 +1,299 @@
+package gc
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultNamespaceConfigMapNamespace the namespace of the --namespace-configmap if it is specified as name/key
+// and no --ns is specified
+const defaultNamespaceConfigMapNamespace = "jx"
+
+// protectedNamespaces the namespaces which are never deleted by --delete-empty-namespace
+var protectedNamespaces = []string{"default", "kube-system", "kube-public", "kube-node-lease"}
+
+// ResolveNamespaces returns the sorted names of the namespaces matching the label selector
+func ResolveNamespaces(ctx context.Context, kubeClient kubernetes.Interface, selector string) ([]string, error) {
+	list, err := kubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list namespaces with selector %s", selector)
+	}
+	var answer []string
+	for i := range list.Items {
+		answer = append(answer, list.Items[i].Name)
+	}
+	sort.Strings(answer)
+	return answer, nil
+}
+
+// ListNamespaces returns the namespaces to garbage collect. If a namespace selector is specified these are the
+// namespaces matching the selector otherwise it is the namespace to query which is empty for all namespaces.
+// If --namespace-configmap is specified only the namespaces listed in the ConfigMap are returned.
+// Any namespaces excluded via --exclude-namespace are omitted
+func (o *FilterOptions) ListNamespaces(ctx context.Context, kubeClient kubernetes.Interface) ([]string, error) {
+	var allowed []string
+	if o.NamespaceConfigMap != "" {
+		var err error
+		allowed, err = o.allowedNamespaces(ctx, kubeClient)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if o.NamespaceSelector == "" {
+		if o.NamespaceConfigMap != "" {
+			return o.filterNamespaces(allowed, nil), nil
+		}
+		ns := o.listNamespace()
+		if o.isExcludedNamespace(ns) {
+			log.Logger().Infof("not querying namespace %s as it is excluded", ns)
+			return nil, nil
+		}
+		return []string{ns}, nil
+	}
+	resolved, err := ResolveNamespaces(ctx, kubeClient, o.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	if o.NamespaceConfigMap == "" {
+		allowed = nil
+	} else if allowed == nil {
+		allowed = []string{}
+	}
+	namespaces := o.filterNamespaces(resolved, allowed)
+	if len(namespaces) == 0 {
+		log.Logger().Infof("no namespaces found with selector %s", o.NamespaceSelector)
+	}
+	return namespaces, nil
+}
+
+// filterNamespaces returns the namespaces which are not excluded and are in the allowed namespaces unless
+// allowed is nil
+func (o *FilterOptions) filterNamespaces(namespaces, allowed []string) []string {
+	var answer []string
+	for _, ns := range namespaces {
+		if o.isExcludedNamespace(ns) {
+			log.Logger().Debugf("not querying namespace %s as it is excluded", ns)
+			continue
+		}
+		if allowed != nil && stringhelpers.StringArrayIndex(allowed, ns) < 0 {
+			log.Logger().Debugf("not querying namespace %s as it is not in the ConfigMap %s", ns, o.NamespaceConfigMap)
+			continue
+		}
+		answer = append(answer, ns)
+	}
+	return answer
+}
+
+// allowedNamespaces returns the sorted namespaces listed in the --namespace-configmap ConfigMap which is read on
+// each run so that changes are picked up by --watch
+func (o *FilterOptions) allowedNamespaces(ctx context.Context, kubeClient kubernetes.Interface) ([]string, error) {
+	ns, name, key, err := o.namespaceConfigMapRef()
+	if err != nil {
+		return nil, err
+	}
+	cm, err := kubeClient.CoreV1().ConfigMaps(ns).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the namespaces ConfigMap %s in namespace %s", name, ns)
+	}
+	value, ok := cm.Data[key]
+	if !ok {
+		return nil, errors.Errorf("the namespaces ConfigMap %s in namespace %s does not have the key %s", name, ns, key)
+	}
+	answer := ParseNamespaceList(value)
+	if len(answer) == 0 {
+		log.Logger().Infof("no namespaces are listed in the key %s of the ConfigMap %s in namespace %s", key, name, ns)
+	}
+	return answer, nil
+}
+
+// namespaceConfigMapRef returns the namespace, name and key of the --namespace-configmap which is specified as
+// name/key in the --ns namespace or namespace/name/key
+func (o *FilterOptions) namespaceConfigMapRef() (string, string, string, error) {
+	paths := strings.Split(o.NamespaceConfigMap, "/")
+	for _, p := range paths {
+		if p == "" {
+			paths = nil
+			break
+		}
+	}
+	switch len(paths) {
+	case 2:
+		ns := o.Namespace
+		if ns == "" {
+			ns = defaultNamespaceConfigMapNamespace
+		}
+		return ns, paths[0], paths[1], nil
+	case 3:
+		return paths[0], paths[1], paths[2], nil
+	default:
+		return "", "", "", options.InvalidOptionf("namespace-configmap", o.NamespaceConfigMap, "must be name/key or namespace/name/key")
+	}
+}
+
+// ParseNamespaceList parses the newline or comma separated namespaces returning them sorted without duplicates
+func ParseNamespaceList(text string) []string {
+	var answer []string
+	for _, line := range strings.Split(text, "\n") {
+		for _, ns := range strings.Split(line, ",") {
+			ns = strings.TrimSpace(ns)
+			if ns != "" && stringhelpers.StringArrayIndex(answer, ns) < 0 {
+				answer = append(answer, ns)
+			}
+		}
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+// deleteOwnedNamespaces deletes the namespaces which have an owner reference to the deleted resource for
+// --cascade-namespaces. Protected and excluded namespaces along with the namespace of the resource are never deleted
+func (o *Options) deleteOwnedNamespaces(ctx context.Context, kind, ns string, r *unstructured.Unstructured) error {
+	name := r.GetName()
+	namespaces, err := terraforms.ListOwnedNamespaces(ctx, o.KubeClient, r.GetUID())
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the namespaces owned by %s %s in namespace %s", kind, name, ns)
+	}
+	for _, owned := range namespaces {
+		if owned == ns || o.isExcludedNamespace(owned) || stringhelpers.StringArrayIndex(protectedNamespaces, owned) >= 0 {
+			terraforms.Logger(ctx).Warnf("not deleting namespace %s owned by %s %s as it is protected or excluded", info(owned), kind, name)
+			continue
+		}
+		if o.DryRun {
+			terraforms.Logger(ctx).Infof("dry-run: would delete namespace %s as it is owned by %s %s", info(owned), kind, name)
+			continue
+		}
+		err = o.retry(ctx, name, func() error {
+			return o.KubeClient.CoreV1().Namespaces().Delete(ctx, owned, metav1.DeleteOptions{})
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete namespace %s owned by %s %s", owned, kind, name)
+		}
+		terraforms.Logger(ctx).Infof("deleted namespace %s as it is owned by %s %s", info(owned), kind, name)
+	}
+	return nil
+}
+
+// deleteEmptyNamespaces deletes the namespaces resources were deleted from in this run which no longer contain any
+// resources if they match the --empty-namespace-selector. With --recheck-empty-namespaces any matching namespaces
+// older than the cutoff are also checked so that a namespace whose last resource was still terminating on a previous
+// run is deleted later. In dry run mode the resources which would have been deleted are ignored
+func (o *Options) deleteEmptyNamespaces(ctx context.Context, batches []*resourceBatch, queried []string, cutoff time.Time) error {
+	selector, err := labels.Parse(o.EmptyNamespaceSelector)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse empty namespace selector %s", o.EmptyNamespaceSelector)
+	}
+
+	// lets find the resources deleted in each namespace in this run
+	deleted := map[string]map[string]bool{}
+	for i := range o.Result.Resources {
+		rr := &o.Result.Resources[i]
+		if rr.Action != ActionDeleted && rr.Action != ActionWouldDelete {
+			continue
+		}
+		if deleted[rr.Namespace] == nil {
+			deleted[rr.Namespace] = map[string]bool{}
+		}
+		deleted[rr.Namespace][rr.Kind+"/"+rr.Name] = true
+	}
+	var namespaces []string
+	for ns := range deleted {
+		namespaces = append(namespaces, ns)
+	}
+
+	if o.RecheckEmptyNamespaces {
+		// lets also check the older matching namespaces in the queried namespaces which may be empty now
+		allNamespaces := stringhelpers.StringArrayIndex(queried, "") >= 0
+		list, err := o.KubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: o.EmptyNamespaceSelector})
+		if err != nil {
+			return errors.Wrapf(err, "failed to list namespaces with selector %s", o.EmptyNamespaceSelector)
+		}
+		for i := range list.Items {
+			namespace := &list.Items[i]
+			ns := namespace.Name
+			if deleted[ns] != nil || !namespace.CreationTimestamp.Time.Before(cutoff) {
+				continue
+			}
+			if allNamespaces || stringhelpers.StringArrayIndex(queried, ns) >= 0 {
+				namespaces = append(namespaces, ns)
+			}
+		}
+	}
+	sort.Strings(namespaces)
+
+	for _, ns := range namespaces {
+		if ns == "" || o.isExcludedNamespace(ns) || stringhelpers.StringArrayIndex(protectedNamespaces, ns) >= 0 {
+			log.Logger().Debugf("not deleting namespace %s as it is protected or excluded", ns)
+			continue
+		}
+		namespace, err := o.KubeClient.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return errors.Wrapf(err, "failed to get namespace %s", ns)
+		}
+		if !selector.Matches(labels.Set(namespace.Labels)) {
+			log.Logger().Debugf("not deleting namespace %s as it does not match the selector %s", ns, o.EmptyNamespaceSelector)
+			continue
+		}
+
+		remaining, kind, err := o.remainingResources(ctx, ns, batches, deleted[ns])
+		if err != nil {
+			return err
+		}
+		if remaining > 0 {
+			log.Logger().Infof("not deleting namespace %s as it still contains %d %s resources", info(ns), remaining, kind)
+			continue
+		}
+		kinds := batchKinds(batches)
+		if o.DryRun {
+			log.Logger().Infof("dry-run: would delete namespace %s as it no longer contains any %s resources", info(ns), kinds)
+			continue
+		}
+		err = o.KubeClient.CoreV1().Namespaces().Delete(ctx, ns, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete namespace %s", ns)
+		}
+		log.Logger().Infof("deleted namespace %s as it no longer contains any %s resources", info(ns), kinds)
+	}
+	return nil
+}
+
+// remainingResources returns the number of resources of the first kind which remain in the namespace along with
+// the kind. Terminating resources are counted as their destroy Jobs may still be running in the namespace. In dry
+// run mode the resources which would have been deleted are ignored
+func (o *Options) remainingResources(ctx context.Context, ns string, batches []*resourceBatch, deleted map[string]bool) (int, string, error) {
+	for _, b := range batches {
+		list, err := dynkube.DynamicResource(o.DynamicClient, ns, b.gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return 0, b.kind, errors.Wrapf(err, "failed to list %s resources in namespace %s", b.kind, ns)
+		}
+		remaining := 0
+		for i := range list.Items {
+			r := &list.Items[i]
+			if !o.DryRun || !deleted[r.GetKind()+"/"+r.GetName()] {
+				remaining++
+			}
+		}
+		if remaining > 0 {
+			return remaining, b.kind, nil
+		}
+	}
+	return 0, "", nil
+}
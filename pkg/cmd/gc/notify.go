@@ -0,0 +1,99 @@
+package gc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/root"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+)
+
+// Notifier notifies of the result of a gc run once it completes
+type Notifier interface {
+	Notify(ctx context.Context, result *RunResult) error
+}
+
+// NoopNotifier a Notifier which does nothing used when no notifiers are configured
+type NoopNotifier struct{}
+
+// Notify does nothing
+func (n NoopNotifier) Notify(ctx context.Context, result *RunResult) error {
+	return nil
+}
+
+// SlackNotifier posts a summary of the deleted resources to a Slack incoming webhook
+type SlackNotifier struct {
+	WebhookURL string
+	// NotifyEmpty posts a message even if no resources were deleted
+	NotifyEmpty bool
+	HTTPClient  *http.Client
+}
+
+// Notify posts a message summarising the resources deleted in the given result
+func (n *SlackNotifier) Notify(ctx context.Context, result *RunResult) error {
+	var names []string
+	for _, r := range result.Resources {
+		if r.Action == ActionDeleted {
+			names = append(names, r.Namespace+"/"+r.Name)
+		}
+	}
+	if len(names) == 0 && !n.NotifyEmpty {
+		return nil
+	}
+
+	text := fmt.Sprintf("%s gc deleted %d test resources", root.BinaryName, len(names))
+	if len(names) > 0 {
+		text += ": " + strings.Join(names, ", ")
+	}
+	data, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal slack message")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.WebhookURL, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create slack request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to post slack message")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("failed to post slack message: status %s", resp.Status)
+	}
+	return nil
+}
+
+// notifiers returns the registered notifiers along with a SlackNotifier if a Slack webhook is configured
+func (o *Options) notifiers() []Notifier {
+	answer := append([]Notifier{}, o.Notifiers...)
+	if o.SlackWebhook != "" {
+		answer = append(answer, &SlackNotifier{WebhookURL: o.SlackWebhook, NotifyEmpty: o.SlackNotifyEmpty})
+	}
+	if len(answer) == 0 {
+		answer = append(answer, NoopNotifier{})
+	}
+	return answer
+}
+
+// notify notifies each of the notifiers of the result. Failures are only logged
+func (o *Options) notify(ctx context.Context) {
+	for _, n := range o.notifiers() {
+		err := n.Notify(ctx, o.Result)
+		if err != nil {
+			log.Logger().Warnf("failed to notify %T: %s", n, err.Error())
+		}
+	}
+}
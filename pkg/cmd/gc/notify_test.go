@@ -0,0 +1,129 @@
+package gc_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGCSlackNotification(t *testing.T) {
+	var messages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err, "failed to read request body")
+		m := map[string]string{}
+		err = json.Unmarshal(data, &m)
+		require.NoError(t, err, "failed to parse request body %s", string(data))
+		messages = append(messages, m["text"])
+	}))
+	defer server.Close()
+
+	oldTime := time.Now().Add(-5 * time.Hour)
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: oldTime,
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources[0:2])
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.SlackWebhook = server.URL
+	o.DynamicClient = fakeDynClient
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run gc command")
+
+	require.Len(t, messages, 1, "should have posted a slack message")
+	assert.Equal(t, "jx-test gc deleted 2 test resources: jx/tf-myrepo-pr456-myctx-1, jx/tf-myrepo-pr456-myctx-2", messages[0], "slack message")
+
+	// now nothing is left to delete so there should be no more messages
+	err = o.Run()
+	require.NoError(t, err, "failed to run gc command")
+	require.Len(t, messages, 1, "should not have posted a slack message when nothing was deleted")
+
+	o.SlackNotifyEmpty = true
+	err = o.Run()
+	require.NoError(t, err, "failed to run gc command")
+	require.Len(t, messages, 2, "should have posted a slack message when nothing was deleted")
+	assert.Equal(t, "jx-test gc deleted 0 test resources", messages[1], "slack message")
+}
+
+func TestSlackNotifierFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	n := &gc.SlackNotifier{WebhookURL: server.URL, NotifyEmpty: true}
+	err := n.Notify(context.TODO(), &gc.RunResult{})
+	require.Error(t, err, "should have failed to notify")
+}
+
+// fakeNotifier records the results it is notified of
+type fakeNotifier struct {
+	results []gc.RunResult
+	err     error
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, result *gc.RunResult) error {
+	n.results = append(n.results, *result)
+	return n.err
+}
+
+func TestGCNotifiers(t *testing.T) {
+	now := time.Now()
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := now.Add(-5 * time.Hour)
+		if idx == 2 {
+			created = now
+		}
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created,
+		})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	notifier := &fakeNotifier{}
+	failing := &fakeNotifier{err: errors.Errorf("simulated failure")}
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Notifiers = []gc.Notifier{failing, notifier}
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.NoError(t, err, "a failing notifier should not fail the run")
+
+	require.Len(t, notifier.results, 1, "should have notified once")
+	result := notifier.results[0]
+	assert.Equal(t, 2, result.Candidates, "candidates")
+	assert.Equal(t, 2, result.Deleted, "deleted")
+	assert.Equal(t, 1, result.Kept, "kept")
+	assert.Equal(t, 0, result.Errors, "errors")
+	assert.Len(t, result.Resources, 3, "resources")
+	assert.Len(t, failing.results, 1, "should have notified the failing notifier once")
+}
+
+func TestNoopNotifier(t *testing.T) {
+	err := gc.NoopNotifier{}.Notify(context.Background(), &gc.RunResult{})
+	assert.NoError(t, err, "the no-op notifier should not fail")
+}
@@ -0,0 +1,92 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// permissionVerbs the verbs gc needs on each resource
+var permissionVerbs = []string{"list", "delete"}
+
+// jobsGroupResource the Jobs created by the Terraform operator which gc deletes along with the Terraform resources
+var jobsGroupResource = schema.GroupResource{Group: "batch", Resource: "jobs"}
+
+// checkPermissions checks via SelfSubjectAccessReview calls that gc can list and delete the resources and their
+// Jobs in each namespace, reporting the missing permissions without modifying anything
+func (o *Options) checkPermissions(ctx context.Context) error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate setup")
+	}
+	gvrs, err := o.GroupVersionResources()
+	if err != nil {
+		return err
+	}
+	namespaces, err := o.ListNamespaces(ctx, o.KubeClient)
+	if err != nil {
+		return err
+	}
+	resources := []schema.GroupResource{}
+	for _, gvr := range gvrs {
+		resources = append(resources, gvr.GroupResource())
+	}
+	resources = append(resources, jobsGroupResource)
+
+	var missing []string
+	for _, ns := range namespaces {
+		where := "in namespace " + ns
+		if ns == "" {
+			where = "in all namespaces"
+		}
+		for _, gr := range resources {
+			for _, verb := range permissionVerbs {
+				allowed, reason, err := o.canI(ctx, ns, gr, verb)
+				if err != nil {
+					return err
+				}
+				if allowed {
+					log.Logger().Infof("can %s %s %s", verb, info(gr.String()), where)
+					continue
+				}
+				text := fmt.Sprintf("%s %s %s", verb, gr.String(), where)
+				if reason != "" {
+					text += ": " + reason
+				}
+				log.Logger().Warnf("cannot %s", text)
+				missing = append(missing, text)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("missing %d permissions needed to garbage collect: %s", len(missing), strings.Join(missing, ", "))
+	}
+	log.Logger().Infof("has all the permissions needed to garbage collect")
+	return nil
+}
+
+// canI returns whether the current user can perform the verb on the resource in the namespace along with the
+// reason if it cannot
+func (o *Options) canI(ctx context.Context, ns string, gr schema.GroupResource, verb string) (bool, string, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: ns,
+				Verb:      verb,
+				Group:     gr.Group,
+				Resource:  gr.Resource,
+			},
+		},
+	}
+	review, err := o.KubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, "", errors.Wrapf(err, "failed to check if allowed to %s %s", verb, gr.String())
+	}
+	return review.Status.Allowed, review.Status.Reason, nil
+}
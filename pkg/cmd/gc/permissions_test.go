@@ -0,0 +1,78 @@
+package gc_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestGCCheckPermissions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		denied  map[string]bool
+		missing []string
+	}{
+		{
+			name: "allowed",
+		},
+		{
+			name:    "cannot delete jobs",
+			denied:  map[string]bool{"delete jobs": true},
+			missing: []string{"delete jobs.batch in namespace jx: RBAC denied"},
+		},
+		{
+			name:   "cannot list or delete terraforms",
+			denied: map[string]bool{"list terraforms": true, "delete terraforms": true},
+			missing: []string{
+				"list terraforms.tf.isaaguilar.com in namespace jx: RBAC denied",
+				"delete terraforms.tf.isaaguilar.com in namespace jx: RBAC denied",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		var reviews []authorizationv1.ResourceAttributes
+		kubeClient := fake.NewSimpleClientset()
+		kubeClient.PrependReactor("create", "selfsubjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+			review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			attrs := review.Spec.ResourceAttributes
+			reviews = append(reviews, *attrs)
+			if tc.denied[attrs.Verb+" "+attrs.Resource] {
+				review.Status.Reason = "RBAC denied"
+			} else {
+				review.Status.Allowed = true
+			}
+			return true, review, nil
+		})
+
+		_, o := gc.NewCmdGC()
+		o.Namespace = "jx"
+		o.CheckPermissions = true
+		o.KubeClient = kubeClient
+		// lets check the resources are not garbage collected
+		o.DynamicClient = &panicDynClient{}
+
+		err := o.Run()
+		if len(tc.missing) == 0 {
+			require.NoError(t, err, "should have all permissions for %s", tc.name)
+		} else {
+			require.Error(t, err, "should be missing permissions for %s", tc.name)
+			for _, m := range tc.missing {
+				assert.Contains(t, err.Error(), m, "missing permission for %s", tc.name)
+			}
+		}
+
+		require.Len(t, reviews, 4, "access reviews for %s", tc.name)
+		for _, r := range reviews {
+			assert.Equal(t, "jx", r.Namespace, "review namespace for %s", tc.name)
+		}
+		assert.Equal(t, "tf.isaaguilar.com", reviews[0].Group, "review group for %s", tc.name)
+		assert.Equal(t, "batch", reviews[2].Group, "review group for %s", tc.name)
+	}
+}
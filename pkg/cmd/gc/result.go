@@ -0,0 +1,326 @@
+package gc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OutputJSON writes the result of the run as a single JSON document once the run completes
+	OutputJSON = "json"
+
+	// OutputJSONLines streams the result of each resource as a JSON object per line as it is processed
+	OutputJSONLines = "jsonl"
+
+	// OutputName writes the name of each deleted resource per line once the run completes like kubectl -o name
+	OutputName = "name"
+)
+
+var outputFormats = []string{OutputJSON, OutputJSONLines, OutputName}
+
+const (
+	// ActionDeleted the resource was deleted
+	ActionDeleted = "deleted"
+
+	// ActionWouldDelete the resource would have been deleted if not running in dry run mode
+	ActionWouldDelete = "would-delete"
+
+	// ActionKeptLabel the resource was kept as it has a keep label or annotation
+	ActionKeptLabel = terraforms.ReasonKeptLabel
+
+	// ActionKeptTooYoung the resource was kept as it is not old enough to be garbage collected
+	ActionKeptTooYoung = terraforms.ReasonKeptTooYoung
+
+	// ActionKeptLast the resource was kept as it is one of the most recent resources for its context
+	ActionKeptLast = "kept-last"
+
+	// ActionKeptRetention the resource was kept as it is the newest resource in one of the --retention buckets
+	ActionKeptRetention = "kept-retention"
+
+	// ActionKeptMinAge the resource was kept as it is younger than --min-age
+	ActionKeptMinAge = "kept-min-age"
+
+	// ActionKeptDeclined the resource was kept as its deletion was declined at the confirmation prompt
+	ActionKeptDeclined = "kept-declined"
+
+	// ActionMarked the resource was labelled to be deleted by a later sweep
+	ActionMarked = "marked"
+
+	// ActionKeptNotMarked the resource was kept by a sweep as it was not marked for long enough
+	ActionKeptNotMarked = "kept-not-marked"
+
+	// ActionSkippedActiveJob the resource was not deleted as its Terraform Job was still active or did not finish in time
+	ActionSkippedActiveJob = "skipped-active-job"
+
+	// ActionSkippedReferenced the resource was not deleted as it is referenced by an active resource such as a PipelineRun
+	ActionSkippedReferenced = "skipped-referenced"
+
+	// ActionSkippedTerminating the resource was not processed as it is already being deleted
+	ActionSkippedTerminating = "skipped-terminating"
+
+	// ActionRemovedFinalizers the finalizers were removed from the resource as it was stuck terminating
+	ActionRemovedFinalizers = "removed-finalizers"
+
+	// ActionError the resource could not be deleted
+	ActionError = "error"
+)
+
+// RunResult the results of a garbage collection run
+type RunResult struct {
+	// Selector the label selector used to find the resources
+	Selector string `json:"selector"`
+
+	// Cutoff resources created before this time are garbage collected unless they have a TTL annotation
+	Cutoff time.Time `json:"cutoff"`
+
+	// Candidates the number of resources which were eligible for garbage collection
+	Candidates int `json:"candidates"`
+
+	// Deleted the number of resources deleted or which would be deleted in dry run mode
+	Deleted int `json:"deleted"`
+
+	// Kept the number of resources which were kept
+	Kept int `json:"kept"`
+
+	// Errors the number of resources which could not be deleted
+	Errors int `json:"errors"`
+
+	// Duration how long the run took
+	Duration time.Duration `json:"-"`
+
+	// DurationSeconds how long the run took in seconds
+	DurationSeconds float64 `json:"durationSeconds"`
+
+	// Kinds the number of resources of each kind deleted, kept and failed when garbage collecting several kinds
+	Kinds []KindResult `json:"kinds,omitempty"`
+
+	// Resources the results for each resource processed grouped by kind
+	Resources []ResourceResult `json:"resources"`
+}
+
+// KindResult the results of garbage collecting the resources of a kind
+type KindResult struct {
+	Kind    string `json:"kind"`
+	Deleted int    `json:"deleted"`
+	Kept    int    `json:"kept"`
+	Errors  int    `json:"errors"`
+}
+
+// ResourceResult the result of processing a single resource
+type ResourceResult struct {
+	Kind              string    `json:"kind,omitempty"`
+	Name              string    `json:"name"`
+	Namespace         string    `json:"namespace"`
+	CreationTimestamp time.Time `json:"creationTimestamp"`
+	Age               string    `json:"age"`
+	Action            string    `json:"action"`
+	Error             string    `json:"error,omitempty"`
+}
+
+// addResult records the action taken for the given resource. It is safe to call from concurrent deletion workers
+// as the deleted count and resource results are updated together under the resultLock so that the summary and
+// metrics reflect every worker
+func (o *Options) addResult(r *unstructured.Unstructured, now time.Time, action string, err error) {
+	created := r.GetCreationTimestamp().Time
+	rr := ResourceResult{
+		Kind:              r.GetKind(),
+		Name:              r.GetName(),
+		Namespace:         o.resourceNamespace(r),
+		CreationTimestamp: created,
+		Age:               now.Sub(created).Round(time.Second).String(),
+		Action:            action,
+	}
+	if err != nil {
+		rr.Error = err.Error()
+	}
+
+	o.resultLock.Lock()
+	if action == ActionDeleted || action == ActionWouldDelete {
+		o.Deleted++
+	}
+	o.Result.Resources = append(o.Result.Resources, rr)
+	o.logAction(r.GetKind(), &rr)
+	if o.Output == OutputJSONLines {
+		o.streamResult(&rr)
+	}
+	o.resultLock.Unlock()
+
+	o.Metrics.observe(action, now.Sub(created), r.GetLabels())
+}
+
+// completeResult updates the counts and duration of the result
+func (o *Options) completeResult(start time.Time) {
+	o.resultLock.Lock()
+	defer o.resultLock.Unlock()
+
+	r := o.Result
+	r.Deleted = o.Deleted
+	r.Kept = 0
+	r.Errors = 0
+	r.Kinds = nil
+	sort.SliceStable(r.Resources, func(i, j int) bool {
+		return r.Resources[i].Kind < r.Resources[j].Kind
+	})
+	var kr *KindResult
+	for i := range r.Resources {
+		rr := &r.Resources[i]
+		if kr == nil || kr.Kind != rr.Kind {
+			r.Kinds = append(r.Kinds, KindResult{Kind: rr.Kind})
+			kr = &r.Kinds[len(r.Kinds)-1]
+		}
+		switch {
+		case rr.Action == ActionDeleted || rr.Action == ActionWouldDelete:
+			kr.Deleted++
+		case isKeptAction(rr.Action):
+			r.Kept++
+			kr.Kept++
+		case rr.Action == ActionError:
+			r.Errors++
+			kr.Errors++
+		}
+	}
+	if len(r.Kinds) < 2 {
+		// lets only group the counts when garbage collecting several kinds
+		r.Kinds = nil
+	}
+	r.Duration = time.Since(start)
+	r.DurationSeconds = r.Duration.Seconds()
+}
+
+// isKeptAction returns true if the action means the resource was kept
+func isKeptAction(action string) bool {
+	switch action {
+	case ActionKeptLabel, ActionKeptTooYoung, ActionKeptLast, ActionKeptRetention, ActionKeptMinAge, ActionKeptDeclined, ActionKeptNotMarked, ActionSkippedActiveJob, ActionSkippedReferenced, ActionSkippedTerminating:
+		return true
+	}
+	return false
+}
+
+// logSummary logs how long the run took along with the number of resources deleted, kept and failed
+func (o *Options) logSummary() {
+	r := o.Result
+	rate := 0.0
+	if r.DurationSeconds > 0 {
+		rate = float64(r.Deleted) / r.DurationSeconds
+	}
+	prefix := ""
+	if o.DryRun {
+		prefix = "dry-run: "
+	}
+	logf := log.Logger().Infof
+	if o.ReportOnlyErrors {
+		// lets still report the summary as only warnings are logged
+		logf = log.Logger().Warnf
+	}
+	logf("%sgc completed in %s: deleted %d, kept %d, errors %d (%.2f deletions per second)", prefix, r.Duration.Round(time.Millisecond).String(), r.Deleted, r.Kept, r.Errors, rate)
+	for _, kr := range r.Kinds {
+		logf("%s%s: deleted %d, kept %d, errors %d", prefix, kr.Kind, kr.Deleted, kr.Kept, kr.Errors)
+	}
+}
+
+// writeResult writes the result in the output format if one is specified
+func (o *Options) writeResult() error {
+	if o.template != nil {
+		return o.writeTemplate()
+	}
+	if o.Output == OutputName {
+		return o.writeNames()
+	}
+	if o.Output != OutputJSON {
+		return nil
+	}
+	data, err := json.MarshalIndent(o.Result, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal result to JSON")
+	}
+	_, err = fmt.Fprintln(o.Out, string(data))
+	if err != nil {
+		return errors.Wrapf(err, "failed to write result")
+	}
+	return nil
+}
+
+// writeNames writes the name of each resource which was deleted, or would be deleted in dry run mode, per line.
+// The names are prefixed with the namespace if querying multiple namespaces
+func (o *Options) writeNames() error {
+	for i := range o.Result.Resources {
+		rr := &o.Result.Resources[i]
+		if rr.Action != ActionDeleted && rr.Action != ActionWouldDelete {
+			continue
+		}
+		name := rr.Name
+		if o.multiNamespace() {
+			name = rr.Namespace + "/" + name
+		}
+		_, err := fmt.Fprintln(o.Out, name)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write result")
+		}
+	}
+	return nil
+}
+
+// streamResult writes the result of a single resource as a line of JSON. It must be called with the result lock
+// held so that lines from concurrent deletions are not interleaved
+func (o *Options) streamResult(rr *ResourceResult) {
+	data, err := json.Marshal(rr)
+	if err == nil {
+		_, err = fmt.Fprintln(o.Out, string(data))
+	}
+	if err != nil {
+		log.Logger().Warnf("failed to write result for %s: %s", rr.Name, err.Error())
+	}
+}
+
+// writeReportFile writes the result to the --report-file as YAML if the file has a .yaml or .yml extension or as
+// JSON otherwise. Failures are only logged unless --strict is specified
+func (o *Options) writeReportFile() error {
+	if o.ReportFile == "" {
+		return nil
+	}
+	err := writeReport(o.ReportFile, o.Result)
+	if err != nil {
+		if o.Strict {
+			return err
+		}
+		log.Logger().Warnf("%s", err.Error())
+		return nil
+	}
+	log.Logger().Infof("wrote the gc report to %s", info(o.ReportFile))
+	return nil
+}
+
+func writeReport(path string, result *RunResult) error {
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(result)
+	default:
+		data, err = json.MarshalIndent(result, "", "  ")
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal the gc report")
+	}
+	err = os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create the directory for the gc report file %s", path)
+	}
+	err = os.WriteFile(path, data, files.DefaultFileWritePermissions)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write the gc report file %s", path)
+	}
+	return nil
+}
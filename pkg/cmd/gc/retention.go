@@ -0,0 +1,142 @@
+package gc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// RetentionHourly keeps the newest resource created in each of the last N hours
+	RetentionHourly = "hourly"
+
+	// RetentionDaily keeps the newest resource created in each of the last N days
+	RetentionDaily = "daily"
+
+	// RetentionWeekly keeps the newest resource created in each of the last N weeks starting on a Monday
+	RetentionWeekly = "weekly"
+
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+var retentionPeriods = []string{RetentionHourly, RetentionDaily, RetentionWeekly}
+
+// RetentionPolicy keeps the newest resource in each hourly, daily and weekly bucket in a similar way to backup
+// snapshot retention policies. Buckets are aligned to UTC hours, days and weeks
+type RetentionPolicy struct {
+	Hourly int
+	Daily  int
+	Weekly int
+}
+
+// ParseRetention parses a retention expression such as hourly=24,daily=7
+func ParseRetention(text string) (*RetentionPolicy, error) {
+	p := &RetentionPolicy{}
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		values := strings.SplitN(part, "=", 2)
+		if len(values) != 2 {
+			return nil, errors.Errorf("%s should be of the form period=count such as hourly=24", part)
+		}
+		period := strings.TrimSpace(values[0])
+		count, err := strconv.Atoi(strings.TrimSpace(values[1]))
+		if err != nil || count < 1 {
+			return nil, errors.Errorf("the count of %s should be a positive integer but was %s", period, values[1])
+		}
+		switch period {
+		case RetentionHourly:
+			p.Hourly = count
+		case RetentionDaily:
+			p.Daily = count
+		case RetentionWeekly:
+			p.Weekly = count
+		default:
+			return nil, errors.Errorf("unknown retention period %s. Supported values: %s", period, strings.Join(retentionPeriods, ", "))
+		}
+	}
+	if p.Hourly == 0 && p.Daily == 0 && p.Weekly == 0 {
+		return nil, errors.Errorf("no retention periods specified")
+	}
+	return p, nil
+}
+
+// Retained returns whether each of the given creation times is the newest in one of the retained buckets
+func (p *RetentionPolicy) Retained(created []time.Time, now time.Time) []bool {
+	answer := make([]bool, len(created))
+
+	// lets process the newest first so the first resource in each bucket is the one to keep
+	indices := make([]int, len(created))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return created[indices[j]].Before(created[indices[i]])
+	})
+
+	retainBuckets(answer, created, indices, now, p.Hourly, time.Hour, hourStart)
+	retainBuckets(answer, created, indices, now, p.Daily, day, dayStart)
+	retainBuckets(answer, created, indices, now, p.Weekly, week, weekStart)
+	return answer
+}
+
+// retainBuckets marks the newest time in each of the count buckets of the given period ending at the bucket
+// containing now. The indices must be ordered from the newest to the oldest time
+func retainBuckets(answer []bool, created []time.Time, indices []int, now time.Time, count int, period time.Duration, bucketStart func(time.Time) time.Time) {
+	if count <= 0 {
+		return
+	}
+	current := bucketStart(now)
+	seen := map[int64]bool{}
+	for _, i := range indices {
+		bucket := int64(current.Sub(bucketStart(created[i])) / period)
+		if bucket < 0 {
+			// lets treat any resources created in the future due to clock skew as being in the current bucket
+			bucket = 0
+		}
+		if bucket >= int64(count) || seen[bucket] {
+			continue
+		}
+		seen[bucket] = true
+		answer[i] = true
+	}
+}
+
+func hourStart(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
+
+func dayStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func weekStart(t time.Time) time.Time {
+	t = dayStart(t)
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -daysSinceMonday)
+}
+
+// applyRetention keeps the candidates which are retained by the --retention policy regardless of their age
+func (o *FilterOptions) applyRetention(candidates []*Candidate, now time.Time) {
+	if o.retention == nil {
+		return
+	}
+	created := make([]time.Time, len(candidates))
+	for i, c := range candidates {
+		created[i] = c.Resource.GetCreationTimestamp().Time
+	}
+	retained := o.retention.Retained(created, now)
+	for i, c := range candidates {
+		if retained[i] && c.ShouldDelete {
+			c.ShouldDelete = false
+			c.Reason = ActionKeptRetention
+		}
+	}
+}
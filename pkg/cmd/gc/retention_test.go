@@ -0,0 +1,187 @@
+package gc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseRetention(t *testing.T) {
+	testCases := []struct {
+		text     string
+		expected *gc.RetentionPolicy
+	}{
+		{text: "hourly=24", expected: &gc.RetentionPolicy{Hourly: 24}},
+		{text: "hourly=24,daily=7", expected: &gc.RetentionPolicy{Hourly: 24, Daily: 7}},
+		{text: " daily = 7 , weekly=4 ", expected: &gc.RetentionPolicy{Daily: 7, Weekly: 4}},
+		{text: ""},
+		{text: "hourly"},
+		{text: "hourly=0"},
+		{text: "hourly=-1"},
+		{text: "hourly=abc"},
+		{text: "monthly=3"},
+	}
+
+	for _, tc := range testCases {
+		got, err := gc.ParseRetention(tc.text)
+		if tc.expected == nil {
+			assert.Error(t, err, "should fail to parse %q", tc.text)
+			continue
+		}
+		require.NoError(t, err, "failed to parse %q", tc.text)
+		assert.Equal(t, tc.expected, got, "for %q", tc.text)
+	}
+}
+
+func TestRetentionPolicyRetained(t *testing.T) {
+	// a Wednesday afternoon
+	now := time.Date(2024, 3, 13, 15, 30, 0, 0, time.UTC)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	testCases := []struct {
+		name     string
+		policy   gc.RetentionPolicy
+		created  []time.Time
+		expected []bool
+	}{
+		{
+			name:   "hourly keeps the newest in each of the last hours",
+			policy: gc.RetentionPolicy{Hourly: 3},
+			created: []time.Time{
+				at(13, 15, 10),
+				at(13, 15, 20),
+				at(13, 14, 50),
+				at(13, 13, 5),
+				at(13, 12, 59),
+			},
+			expected: []bool{false, true, true, true, false},
+		},
+		{
+			name:   "hourly treats future resources as in the current hour",
+			policy: gc.RetentionPolicy{Hourly: 1},
+			created: []time.Time{
+				at(13, 15, 20),
+				at(13, 16, 40),
+			},
+			expected: []bool{false, true},
+		},
+		{
+			name:   "daily keeps the newest in each of the last days",
+			policy: gc.RetentionPolicy{Daily: 2},
+			created: []time.Time{
+				at(13, 1, 0),
+				at(13, 9, 0),
+				at(12, 23, 59),
+				at(12, 0, 0),
+				at(11, 23, 59),
+			},
+			expected: []bool{false, true, true, false, false},
+		},
+		{
+			name:   "weekly buckets start on a Monday",
+			policy: gc.RetentionPolicy{Weekly: 2},
+			created: []time.Time{
+				at(11, 0, 30),
+				at(10, 23, 0),
+				at(4, 0, 0),
+				at(3, 23, 0),
+			},
+			expected: []bool{true, true, false, false},
+		},
+		{
+			name:   "combined periods keep the union",
+			policy: gc.RetentionPolicy{Hourly: 2, Daily: 3},
+			created: []time.Time{
+				at(13, 15, 0),
+				at(13, 14, 30),
+				at(13, 14, 0),
+				at(13, 8, 0),
+				at(12, 20, 0),
+				at(12, 10, 0),
+				at(11, 5, 0),
+				at(10, 5, 0),
+			},
+			expected: []bool{true, true, false, false, true, false, true, false},
+		},
+		{
+			name:   "buckets are aligned to UTC",
+			policy: gc.RetentionPolicy{Daily: 1},
+			created: []time.Time{
+				// 2024-03-13T01:00:00Z
+				time.Date(2024, 3, 12, 20, 0, 0, 0, time.FixedZone("EST", -5*60*60)),
+				// 2024-03-12T23:00:00Z
+				time.Date(2024, 3, 13, 1, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+			},
+			expected: []bool{true, false},
+		},
+		{
+			name:     "no resources",
+			policy:   gc.RetentionPolicy{Hourly: 24},
+			expected: []bool{},
+		},
+	}
+
+	for _, tc := range testCases {
+		got := tc.policy.Retained(tc.created, now)
+		assert.Equal(t, tc.expected, got, "for %s", tc.name)
+	}
+}
+
+func TestGCRetention(t *testing.T) {
+	// lets put the first two resources in the same hour so only the newest is retained
+	bucket := time.Now().UTC().Add(-5 * time.Hour).Truncate(time.Hour)
+	created := []time.Time{
+		bucket.Add(10 * time.Minute),
+		bucket.Add(20 * time.Minute),
+		bucket.Add(-24 * time.Hour),
+	}
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetCreationTimestamp(metav1.Time{
+			Time: created[idx],
+		})
+	}
+
+	dynObjects := tftests.ParseUnstructureds(t, fn, testResources)
+
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Retention = "hourly=24"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = fake.NewSimpleClientset()
+
+	result, err := o.RunWithResult(o.GetContext())
+	require.NoError(t, err, "failed to run gc command")
+	assert.Equal(t, 2, o.Deleted, "deleted count")
+
+	actions := map[string]string{}
+	for _, r := range result.Resources {
+		actions[r.Name] = r.Action
+	}
+	assert.Equal(t, map[string]string{
+		"tf-myrepo-pr456-myctx-1": gc.ActionDeleted,
+		"tf-myrepo-pr456-myctx-2": gc.ActionKeptRetention,
+		"tf-myrepo-pr999-myctx-3": gc.ActionDeleted,
+	}, actions, "actions")
+}
+
+func TestGCInvalidRetention(t *testing.T) {
+	_, o := gc.NewCmdGC()
+	o.Namespace = "jx"
+	o.Retention = "monthly=3"
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+	o.KubeClient = fake.NewSimpleClientset()
+
+	err := o.Run()
+	require.Error(t, err, "should fail with an invalid retention")
+	assert.Contains(t, err.Error(), "monthly", "error message")
+}
@@ -0,0 +1,49 @@
+package gc
+
+import (
+	"context"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// retry invokes the given function retrying any transient failures up to Retries times with an exponential backoff.
+// A NotFound error is treated as success as the resource has already been removed. No more attempts are made
+// once the context is done
+func (o *Options) retry(ctx context.Context, name string, fn func() error) error {
+	backoff := o.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || apierrors.IsNotFound(errors.Cause(err)) {
+			return nil
+		}
+		if attempt >= o.Retries || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+		terraforms.Logger(ctx).Warnf("attempt %d for %s failed so retrying in %s: %s", attempt+1, name, backoff.String(), err.Error())
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// isRetryable returns true if the error is likely to be transient
+func isRetryable(err error) bool {
+	err = errors.Cause(err)
+	if _, ok := err.(apierrors.APIStatus); !ok {
+		// errors which are not from the API server such as failing to run kubectl may be transient
+		return true
+	}
+	return apierrors.IsTimeout(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsConflict(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsUnexpectedServerError(err)
+}
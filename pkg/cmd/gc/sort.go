@@ -0,0 +1,43 @@
+package gc
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// SortOldestFirst deletes the oldest resources first
+	SortOldestFirst = "oldest-first"
+
+	// SortNewestFirst deletes the newest resources first
+	SortNewestFirst = "newest-first"
+
+	// SortName deletes the resources in order of their namespace and name
+	SortName = "name"
+)
+
+var sortOrders = []string{SortOldestFirst, SortNewestFirst, SortName}
+
+// sortResources sorts the resources to delete using the --sort order. Resources created at the same time are
+// sorted by namespace and name so that the order is predictable
+func (o *Options) sortResources(resources []*unstructured.Unstructured) {
+	byName := func(i, j int) bool {
+		if resources[i].GetNamespace() != resources[j].GetNamespace() {
+			return resources[i].GetNamespace() < resources[j].GetNamespace()
+		}
+		return resources[i].GetName() < resources[j].GetName()
+	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		t1 := resources[i].GetCreationTimestamp().Time
+		t2 := resources[j].GetCreationTimestamp().Time
+		switch {
+		case o.Sort == SortName || t1.Equal(t2):
+			return byName(i, j)
+		case o.Sort == SortNewestFirst:
+			return t1.After(t2)
+		default:
+			return t1.Before(t2)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package gc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// stateKeyLastRun the key in the --state-configmap of the time of the last run
+	stateKeyLastRun = "lastRun"
+	// stateKeyKeep the key in the --state-configmap of the keep label and annotation used by the last run
+	stateKeyKeep = "keep"
+	// stateKeyKeptValues the key in the --state-configmap of the keep label values which kept resources with a
+	// line per value
+	stateKeyKeptValues = "keptValues"
+
+	// maxStateKeptValues the maximum number of keep label values stored in the --state-configmap so that the state
+	// and the label selector built from it stay small
+	maxStateKeptValues = 10
+)
+
+// runState the state of the last run stored in the --state-configmap so that --since-last-run does not list the
+// resources kept by a keep label
+type runState struct {
+	ns      string
+	name    string
+	lastRun time.Time
+	// previous the keep label values which kept resources in the last run
+	previous []string
+	// kept the keep label values which kept resources in this run
+	kept map[string]bool
+}
+
+// loadState loads the state of the last run from the --state-configmap. If there is no ConfigMap yet an empty
+// state is returned so that all resources are listed
+func (o *Options) loadState(ctx context.Context) (*runState, error) {
+	ns, name, err := o.stateConfigMapRef()
+	if err != nil {
+		return nil, err
+	}
+	s := &runState{ns: ns, name: name, kept: map[string]bool{}}
+	cm, err := o.KubeClient.CoreV1().ConfigMaps(ns).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Logger().Infof("no state ConfigMap %s found in namespace %s so listing all resources", info(name), ns)
+			return s, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get the state ConfigMap %s in namespace %s", name, ns)
+	}
+	value := cm.Data[stateKeyLastRun]
+	if value != "" {
+		s.lastRun, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the %s value %s of the state ConfigMap %s in namespace %s", stateKeyLastRun, value, name, ns)
+		}
+	}
+	if cm.Data[stateKeyKeep] != o.stateKeep() {
+		log.Logger().Infof("the keep label or annotation has changed since the last run so listing all resources")
+		return s, nil
+	}
+	for _, line := range strings.Split(cm.Data[stateKeyKeptValues], "\n") {
+		line = strings.TrimSpace(line)
+		if isAlwaysKeptValue(line) && len(s.previous) < maxStateKeptValues {
+			s.previous = append(s.previous, line)
+		}
+	}
+	return s, nil
+}
+
+// saveState stores the time of this run and the keep label values which kept resources in the --state-configmap
+// replacing the state of the last run
+func (o *Options) saveState(ctx context.Context, s *runState, now time.Time) error {
+	var values []string
+	for value := range s.kept {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	if len(values) > maxStateKeptValues {
+		values = values[:maxStateKeptValues]
+	}
+	data := map[string]string{
+		stateKeyLastRun:    now.UTC().Format(time.RFC3339),
+		stateKeyKeep:       o.stateKeep(),
+		stateKeyKeptValues: strings.Join(values, "\n"),
+	}
+
+	configMaps := o.KubeClient.CoreV1().ConfigMaps(s.ns)
+	cm, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get the state ConfigMap %s in namespace %s", s.name, s.ns)
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.ns,
+			},
+			Data: data,
+		}
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create the state ConfigMap %s in namespace %s", s.name, s.ns)
+		}
+		return nil
+	}
+	cm.Data = data
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to update the state ConfigMap %s in namespace %s", s.name, s.ns)
+	}
+	return nil
+}
+
+// stateConfigMapRef returns the namespace and name of the --state-configmap which is specified as name in the
+// --ns namespace or namespace/name
+func (o *Options) stateConfigMapRef() (string, string, error) {
+	paths := strings.Split(o.StateConfigMap, "/")
+	switch {
+	case len(paths) == 1 && paths[0] != "":
+		ns := o.Namespace
+		if ns == "" {
+			ns = defaultNamespaceConfigMapNamespace
+		}
+		return ns, paths[0], nil
+	case len(paths) == 2 && paths[0] != "" && paths[1] != "":
+		return paths[0], paths[1], nil
+	default:
+		return "", "", options.InvalidOptionf("state-configmap", o.StateConfigMap, "must be name or namespace/name")
+	}
+}
+
+// stateKeep returns the keep label and annotation which decide which resources are kept so that the kept
+// resources are only skipped if they have not changed
+func (o *Options) stateKeep() string {
+	return fmt.Sprintf("%s,%s", o.keepLabel(), o.ProtectAnnotation)
+}
+
+// sinceLastRunSelector returns the label selector used with --since-last-run so that the resources whose keep
+// label has a value which kept resources in the last run are not listed. As the keep label wins over the age and
+// TTL of a resource they would be kept again. Resources kept until a date or by the protect annotation are still
+// listed. The values are carried over to this run as their resources are not seen. It returns an empty selector
+// if there is no previous run
+func (o *Options) sinceLastRunSelector() string {
+	s := o.state
+	if s == nil || s.lastRun.IsZero() || len(s.previous) == 0 {
+		return ""
+	}
+	for _, value := range s.previous {
+		s.kept[value] = true
+	}
+	selector := fmt.Sprintf("%s notin (%s)", o.keepLabel(), strings.Join(s.previous, ","))
+	log.Logger().Infof("not listing the resources kept by the %s label in the last run at %s using the selector %s", o.keepLabel(), s.lastRun.Format(time.RFC3339), selector)
+	return selector
+}
+
+// recordKept records the value of the keep label of a resource kept by it in the state if the value always keeps
+// the resource
+func (o *Options) recordKept(r *unstructured.Unstructured) {
+	if o.state == nil {
+		return
+	}
+	value := r.GetLabels()[o.keepLabel()]
+	if isAlwaysKeptValue(value) {
+		o.state.kept[value] = true
+	}
+}
+
+// isAlwaysKeptValue returns true if the keep label value keeps the resource regardless of when it is evaluated
+// unlike a date after which the resource is no longer kept
+func isAlwaysKeptValue(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "yes", "1":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package gc
+
+import (
+	"text/template"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/pkg/errors"
+)
+
+// TemplateData the data the --template is executed against. DeletedResources, KeptResources and ErrorResources are
+// the results of the resources grouped by the action taken. They are named so that they do not hide the fields of
+// the RunResult, such as the Deleted count, which are also available
+type TemplateData struct {
+	*RunResult
+
+	// DeletedResources the resources deleted or which would be deleted in dry run mode
+	DeletedResources []ResourceResult
+
+	// KeptResources the resources which were kept
+	KeptResources []ResourceResult
+
+	// ErrorResources the resources which could not be deleted
+	ErrorResources []ResourceResult
+}
+
+// NewTemplateData creates the data the --template is executed against for the result
+func NewTemplateData(r *RunResult) *TemplateData {
+	data := &TemplateData{RunResult: r}
+	for i := range r.Resources {
+		rr := r.Resources[i]
+		switch {
+		case rr.Action == ActionDeleted || rr.Action == ActionWouldDelete:
+			data.DeletedResources = append(data.DeletedResources, rr)
+		case isKeptAction(rr.Action):
+			data.KeptResources = append(data.KeptResources, rr)
+		case rr.Action == ActionError:
+			data.ErrorResources = append(data.ErrorResources, rr)
+		}
+	}
+	return data
+}
+
+// parseTemplate parses the --template so that any syntax errors are reported before garbage collecting
+func (o *Options) parseTemplate() error {
+	if o.Template == "" {
+		o.template = nil
+		return nil
+	}
+	if o.Output != "" {
+		return options.InvalidOptionf("template", o.Template, "cannot be used with --output %s", o.Output)
+	}
+	t, err := template.New("template").Option("missingkey=error").Parse(o.Template)
+	if err != nil {
+		return options.InvalidOptionf("template", o.Template, "could not be parsed: %s", err.Error())
+	}
+	o.template = t
+	return nil
+}
+
+// writeTemplate writes the result of the run using the --template
+func (o *Options) writeTemplate() error {
+	err := o.template.Execute(o.Out, NewTemplateData(o.Result))
+	if err != nil {
+		return errors.Wrapf(err, "failed to write the result using the template %s", o.Template)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package gc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// watch runs as a controller keeping an informer cache of the resources matching the selector and garbage collecting
+// them every --interval until the context is cancelled. A failed run is logged and retried on the next tick
+func (o *Options) watch(ctx context.Context) error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate setup")
+	}
+
+	// lets not prompt on each tick
+	o.Yes = true
+
+	// lets serve metrics for the lifetime of the controller rather than each run
+	err = o.startMetrics()
+	if err != nil {
+		return err
+	}
+
+	gvrs, err := o.GroupVersionResources()
+	if err != nil {
+		return err
+	}
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(o.DynamicClient, 0, o.listNamespace(), func(opts *metav1.ListOptions) {
+		opts.LabelSelector = o.Selector()
+		opts.FieldSelector = o.FieldSelector
+	})
+	listers := map[schema.GroupVersionResource]cache.GenericLister{}
+	var resources []string
+	for _, gvr := range gvrs {
+		informer := factory.ForResource(gvr)
+		listers[gvr] = informer.Lister()
+		resources = append(resources, gvr.Resource)
+	}
+	factory.Start(ctx.Done())
+	for gvr, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return errors.Errorf("failed to sync the informer cache of %s", gvr.String())
+		}
+	}
+	o.listers = listers
+	defer func() {
+		o.listers = nil
+	}()
+
+	names := strings.Join(resources, ", ")
+	log.Logger().Infof("watching %s resources with selector %s and garbage collecting them every %s", names, info(o.Selector()), o.Interval.String())
+
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
+	for {
+		_, err = o.RunWithResult(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Logger().Warnf("failed to garbage collect: %s", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Logger().Infof("stopped watching %s resources", names)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package keep
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/jenkins-x-plugins/jx-test/pkg/root"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	info = termcolor.ColorInfo
+
+	cmdLong = templates.LongDesc(`
+		Protects test resources from being garbage collected so they can be investigated
+
+		Without --for the resource is labelled with the --keep-label label until it is removed via --remove. With --for
+		the --protect-annotation annotation is set to the time the window ends after which the resource can be garbage
+		collected again
+`)
+
+	cmdExample = templates.Examples(`
+		# keep a test resource until the keep label is removed
+		%s keep tf-myrepo-pr456-myctx-1
+
+		# keep a test resource for the next day
+		%s keep tf-myrepo-pr456-myctx-1 --for 24h
+
+		# let a test resource be garbage collected again
+		%s keep tf-myrepo-pr456-myctx-1 --remove
+
+		# keep a resource of another kind using the same keep label as gc
+		%s keep my-workspace --resource tf.isaaguilar.com/v1alpha1/tfworkspaces --keep-label example.com/keep
+	`)
+)
+
+// Options the options for the command. The kinds of resource, keep label and protect annotation and the clients
+// use the same options as gc so that the resources are kept the way gc expects
+type Options struct {
+	gc.FilterOptions
+	Names         []string
+	For           time.Duration
+	Remove        bool
+	KubeClient    kubernetes.Interface
+	DynamicClient dynamic.Interface
+	Ctx           context.Context
+}
+
+// NewCmdKeep creates a command object for the command
+func NewCmdKeep() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "keep <name>...",
+		Short:   "Protects test resources from being garbage collected",
+		Long:    cmdLong,
+		Example: fmt.Sprintf(cmdExample, root.BinaryName, root.BinaryName, root.BinaryName, root.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			o.Names = args
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+
+	if o.Ctx == nil {
+		o.Ctx = cmd.Context()
+	}
+
+	cmd.Flags().DurationVarP(&o.For, "for", "", 0, "only keeps the resources for the given duration such as 24h by setting the --protect-annotation annotation. If not specified the resources are kept until --remove is used")
+	cmd.Flags().BoolVarP(&o.Remove, "remove", "", false, "removes the --keep-label label and --protect-annotation annotation so the resources can be garbage collected again")
+
+	o.FilterOptions.AddClientFlags(cmd)
+	o.FilterOptions.AddResourceFlags(cmd)
+	o.FilterOptions.AddKeepFlags(cmd)
+	return cmd, o
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate setup")
+	}
+
+	gvrs, err := o.GroupVersionResources()
+	if err != nil {
+		return err
+	}
+	ctx := o.GetContext()
+	labels, annotations, description := o.keepMetadata(time.Now())
+	for _, name := range o.Names {
+		kind, err := o.patchResource(ctx, gvrs, name, labels, annotations)
+		if err != nil {
+			return err
+		}
+		log.Logger().Infof("%s %s in namespace %s %s", kind, info(name), o.Namespace, description)
+	}
+	return nil
+}
+
+// patchResource patches the labels and annotations of the named resource returning its kind. If several kinds of
+// resource are specified via --resource the first kind with a resource of that name is patched
+func (o *Options) patchResource(ctx context.Context, gvrs []schema.GroupVersionResource, name string, labels, annotations map[string]interface{}) (string, error) {
+	var kinds []string
+	for _, gvr := range gvrs {
+		kind := o.ResourceKindName(o.KubeClient, gvr)
+		client := dynkube.DynamicResource(o.DynamicClient, o.Namespace, gvr)
+		err := dynkube.PatchMetadata(ctx, client, name, labels, annotations)
+		if err == nil {
+			return kind, nil
+		}
+		if !apierrors.IsNotFound(errors.Cause(err)) {
+			return "", errors.Wrapf(err, "failed to update %s %s in namespace %s", kind, name, o.Namespace)
+		}
+		kinds = append(kinds, kind)
+	}
+	return "", errors.Errorf("%s %s does not exist in namespace %s", strings.Join(kinds, " or "), name, o.Namespace)
+}
+
+// keepMetadata returns the labels and annotations to patch along with a description of the change. Each patch
+// clears the other keep key so that a previous keep does not outlive the new one
+func (o *Options) keepMetadata(now time.Time) (map[string]interface{}, map[string]interface{}, string) {
+	labels := map[string]interface{}{o.KeepLabel: nil}
+	annotations := map[string]interface{}{}
+	if o.ProtectAnnotation != "" {
+		annotations[o.ProtectAnnotation] = nil
+	}
+	switch {
+	case o.Remove:
+		return labels, annotations, "can now be garbage collected"
+	case o.For > 0:
+		until := now.Add(o.For).UTC().Format(time.RFC3339)
+		annotations[o.ProtectAnnotation] = until
+		return labels, annotations, "will be kept until " + until
+	default:
+		labels[o.KeepLabel] = "true"
+		return labels, annotations, "will be kept until the keep is removed"
+	}
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	if len(o.Names) == 0 {
+		return options.MissingOption("name")
+	}
+	if o.For < 0 {
+		return options.InvalidOptionf("for", o.For, "the duration should be positive")
+	}
+	if o.Remove && o.For > 0 {
+		return options.InvalidOptionf("remove", o.Remove, "cannot be used with --for")
+	}
+	if o.KeepLabel == "" {
+		o.KeepLabel = terraforms.LabelKeep
+	}
+	if o.For > 0 && o.ProtectAnnotation == "" {
+		return options.InvalidOptionf("for", o.For, "requires --protect-annotation")
+	}
+	err := o.FilterOptions.Validate()
+	if err != nil {
+		return err
+	}
+	o.KubeClient, o.DynamicClient, err = o.LazyCreateClients(o.KubeClient, o.DynamicClient)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetContext lazily creates a context if it doesn't exist already
+func (o *Options) GetContext() context.Context {
+	if o.Ctx == nil {
+		o.Ctx = context.TODO()
+	}
+	return o.Ctx
+}
@@ -0,0 +1,208 @@
+package keep_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/keep"
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testResource = `apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+  name: tf-myrepo-pr456-myctx-1
+  namespace: jx
+`
+
+func TestKeep(t *testing.T) {
+	ns := "jx"
+	name := "tf-myrepo-pr456-myctx-1"
+	dynObjects := tftests.ParseUnstructureds(t, nil, []string{testResource})
+	dynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	run := func(fn func(o *keep.Options)) *unstructured.Unstructured {
+		_, o := keep.NewCmdKeep()
+		o.Names = []string{name}
+		o.Namespace = ns
+		o.KubeClient = fake.NewSimpleClientset()
+		o.DynamicClient = dynClient
+		if fn != nil {
+			fn(o)
+		}
+		err := o.Run()
+		require.NoError(t, err, "failed to run keep command")
+		return getResource(t, dynClient, ns, name)
+	}
+
+	u := run(nil)
+	assert.Equal(t, "true", u.GetLabels()[terraforms.LabelKeep], "keep label")
+	assert.Empty(t, u.GetAnnotations()[terraforms.AnnotationKeep], "keep annotation")
+	kept, err := terraforms.IsKept(u.GetLabels(), u.GetAnnotations())
+	require.NoError(t, err, "failed to check keep")
+	assert.True(t, kept, "should be kept")
+
+	u = run(func(o *keep.Options) {
+		o.For = 24 * time.Hour
+	})
+	_, hasLabel := u.GetLabels()[terraforms.LabelKeep]
+	assert.False(t, hasLabel, "should have cleared the keep label")
+	until, err := time.Parse(time.RFC3339, u.GetAnnotations()[terraforms.AnnotationKeep])
+	require.NoError(t, err, "failed to parse keep annotation")
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), until, time.Minute, "keep annotation")
+	kept, err = terraforms.IsKept(u.GetLabels(), u.GetAnnotations())
+	require.NoError(t, err, "failed to check keep")
+	assert.True(t, kept, "should be kept")
+
+	u = run(func(o *keep.Options) {
+		o.Remove = true
+	})
+	assert.Equal(t, map[string]string{"kind": "jx-test"}, u.GetLabels(), "labels")
+	assert.Empty(t, u.GetAnnotations(), "annotations")
+	kept, err = terraforms.IsKept(u.GetLabels(), u.GetAnnotations())
+	require.NoError(t, err, "failed to check keep")
+	assert.False(t, kept, "should no longer be kept")
+}
+
+func TestKeepInvalidOptions(t *testing.T) {
+	testCases := []struct {
+		name string
+		fn   func(o *keep.Options)
+	}{
+		{name: "missing name", fn: func(o *keep.Options) { o.Names = nil }},
+		{name: "negative duration", fn: func(o *keep.Options) { o.For = -time.Hour }},
+		{name: "for and remove", fn: func(o *keep.Options) { o.For = time.Hour; o.Remove = true }},
+	}
+	for _, tc := range testCases {
+		_, o := keep.NewCmdKeep()
+		o.Names = []string{"tf-myrepo-pr456-myctx-1"}
+		o.Namespace = "jx"
+		o.KubeClient = fake.NewSimpleClientset()
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+		tc.fn(o)
+
+		err := o.Run()
+		assert.Error(t, err, "should fail for %s", tc.name)
+	}
+}
+
+func TestKeepMissingResource(t *testing.T) {
+	_, o := keep.NewCmdKeep()
+	o.Names = []string{"does-not-exist"}
+	o.Namespace = "jx"
+	o.KubeClient = fake.NewSimpleClientset()
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme())
+
+	err := o.Run()
+	require.Error(t, err, "should fail for a resource which does not exist")
+}
+
+func TestKeepCustomResourceAndKeys(t *testing.T) {
+	ns := "jx"
+	name := "tf-myrepo-pr456-myctx-1"
+	gvr := schema.GroupVersionResource{Group: "tf.isaaguilar.com", Version: "v1alpha1", Resource: "tfworkspaces"}
+	fn := func(idx int, u *unstructured.Unstructured) {
+		u.SetKind("TFWorkspace")
+	}
+	dynClient := dynfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		terraforms.TerraformResource: "TerraformList",
+		gvr:                          "TFWorkspaceList",
+	}, tftests.ParseUnstructureds(t, fn, []string{testResource})...)
+
+	run := func(fn func(o *keep.Options)) *unstructured.Unstructured {
+		_, o := keep.NewCmdKeep()
+		o.Names = []string{name}
+		o.Namespace = ns
+		o.Resources = []string{terraforms.TerraformResource.Resource, gvr.Group + "/" + gvr.Version + "/" + gvr.Resource}
+		o.KeepLabel = "example.com/keep"
+		o.ProtectAnnotation = "example.com/protect"
+		o.KubeClient = fake.NewSimpleClientset()
+		o.DynamicClient = dynClient
+		if fn != nil {
+			fn(o)
+		}
+		err := o.Run()
+		require.NoError(t, err, "failed to run keep command")
+		u, err := dynkube.DynamicResource(dynClient, ns, gvr).Get(context.Background(), name, metav1.GetOptions{})
+		require.NoError(t, err, "failed to get TFWorkspace %s", name)
+		return u
+	}
+
+	u := run(nil)
+	assert.Equal(t, map[string]string{"kind": "jx-test", "example.com/keep": "true"}, u.GetLabels(), "labels")
+	kept, err := terraforms.IsKeptWithKeys(u.GetLabels(), u.GetAnnotations(), "example.com/keep", "example.com/protect")
+	require.NoError(t, err, "failed to check keep")
+	assert.True(t, kept, "should be kept")
+
+	u = run(func(o *keep.Options) {
+		o.For = time.Hour
+	})
+	assert.Equal(t, map[string]string{"kind": "jx-test"}, u.GetLabels(), "should have cleared the keep label")
+	_, err = time.Parse(time.RFC3339, u.GetAnnotations()["example.com/protect"])
+	require.NoError(t, err, "failed to parse the protect annotation")
+	assert.Empty(t, u.GetAnnotations()[terraforms.AnnotationKeep], "should not use the default annotation")
+
+	u = run(func(o *keep.Options) {
+		o.Remove = true
+	})
+	assert.Equal(t, map[string]string{"kind": "jx-test"}, u.GetLabels(), "labels")
+	assert.Empty(t, u.GetAnnotations(), "annotations")
+}
+
+func TestKeepKubeConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(path, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: cluster-a
+  cluster:
+    server: https://cluster-a.example.com
+contexts:
+- name: context-a
+  context:
+    cluster: cluster-a
+    namespace: jx
+current-context: ""
+`), 0600)
+	require.NoError(t, err, "failed to write kube config %s", path)
+
+	// lets make sure the ambient kube config is not used
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "does-not-exist"))
+
+	dynClient := tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, nil, []string{testResource})...)
+	_, o := keep.NewCmdKeep()
+	o.Names = []string{"tf-myrepo-pr456-myctx-1"}
+	o.KubeConfig = path
+	o.KubeContext = "context-a"
+	o.KubeClient = fake.NewSimpleClientset()
+	o.DynamicClient = dynClient
+
+	err = o.Run()
+	require.NoError(t, err, "failed to run keep command")
+	assert.Equal(t, "jx", o.Namespace, "should use the namespace of the --context")
+
+	u := getResource(t, dynClient, "jx", "tf-myrepo-pr456-myctx-1")
+	assert.Equal(t, "true", u.GetLabels()[terraforms.LabelKeep], "keep label")
+}
+
+func getResource(t *testing.T, dynClient dynamic.Interface, ns, name string) *unstructured.Unstructured {
+	u, err := dynkube.DynamicResource(dynClient, ns, terraforms.TerraformResource).Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err, "failed to get Terraform %s", name)
+	return u
+}
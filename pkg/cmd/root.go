@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/completion"
 	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/create"
 	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/keep"
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/status"
 	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/version"
 	"github.com/jenkins-x-plugins/jx-test/pkg/root"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras"
@@ -22,8 +25,11 @@ func Main() *cobra.Command {
 			}
 		},
 	}
+	cmd.AddCommand(cobras.SplitCommand(completion.NewCmdCompletion()))
 	cmd.AddCommand(cobras.SplitCommand(create.NewCmdCreate()))
 	cmd.AddCommand(cobras.SplitCommand(gc.NewCmdGC()))
+	cmd.AddCommand(cobras.SplitCommand(keep.NewCmdKeep()))
+	cmd.AddCommand(cobras.SplitCommand(status.NewCmdStatus()))
 	cmd.AddCommand(cobras.SplitCommand(version.NewCmdVersion()))
 	return cmd
 }
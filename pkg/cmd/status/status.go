@@ -0,0 +1,137 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/gc"
+	"github.com/jenkins-x-plugins/jx-test/pkg/root"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/templates"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	cmdLong = templates.LongDesc(`
+		Reports the status of the Terraform Jobs for each test resource
+`)
+
+	cmdExample = templates.Examples(`
+		%s status
+	`)
+)
+
+// Options the options for the command. The resources are found using the same filter options as gc so that both
+// commands report on the same resources and Jobs
+type Options struct {
+	gc.FilterOptions
+	FailOnError   bool
+	KubeClient    kubernetes.Interface
+	DynamicClient dynamic.Interface
+	Ctx           context.Context
+	Out           io.Writer
+}
+
+// NewCmdStatus creates a command object for the command
+func NewCmdStatus() (*cobra.Command, *Options) {
+	o := &Options{}
+
+	cmd := &cobra.Command{
+		Use:     "status",
+		Short:   "Reports the status of the Terraform Jobs for each test resource",
+		Long:    cmdLong,
+		Example: fmt.Sprintf(cmdExample, root.BinaryName),
+		Run: func(cmd *cobra.Command, args []string) {
+			err := o.Run()
+			helper.CheckErr(err)
+		},
+	}
+
+	if o.Ctx == nil {
+		o.Ctx = cmd.Context()
+	}
+
+	cmd.Flags().BoolVarP(&o.FailOnError, "fail-on-error", "", false, "returns an error if any Terraform Job has failed")
+
+	o.FilterOptions.AddFlags(cmd)
+	return cmd, o
+}
+
+// Run implements the command
+func (o *Options) Run() error {
+	err := o.Validate()
+	if err != nil {
+		return errors.Wrapf(err, "failed to validate setup")
+	}
+
+	ctx := o.GetContext()
+	resources, err := o.ListResources(ctx, o.KubeClient, o.DynamicClient)
+	if err != nil && !apierrors.IsNotFound(errors.Cause(err)) {
+		return errors.Wrapf(err, "failed to list the resources with selector %s", o.Selector())
+	}
+
+	failed := 0
+	t := table.CreateTable(o.Out)
+	t.AddRow("NAME", "NAMESPACE", "JOB", "STATUS")
+	for _, r := range resources {
+		name := r.GetName()
+		resourceNS := r.GetNamespace()
+		if resourceNS == "" {
+			resourceNS = o.Namespace
+		}
+		jobList, err := terraforms.ListTerraformJobsWithOptions(ctx, o.KubeClient, resourceNS, name, terraforms.JobOptions{JobLabel: o.JobLabel})
+		if err != nil {
+			return errors.Wrapf(err, "failed to list Terraform Jobs for %s in namespace %s", name, resourceNS)
+		}
+		if len(jobList) == 0 {
+			t.AddRow(name, resourceNS, "", "none")
+			continue
+		}
+		for j := range jobList {
+			job := &jobList[j]
+			status := terraforms.JobStatus(job)
+			if status == terraforms.JobStatusFailed {
+				failed++
+			}
+			t.AddRow(name, resourceNS, job.Name, status)
+		}
+	}
+	t.Render()
+
+	if o.FailOnError && failed > 0 {
+		return errors.Errorf("%d Terraform Jobs have failed", failed)
+	}
+	return nil
+}
+
+// Validate validates the options
+func (o *Options) Validate() error {
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	err := o.FilterOptions.Validate()
+	if err != nil {
+		return err
+	}
+	o.KubeClient, o.DynamicClient, err = o.LazyCreateClients(o.KubeClient, o.DynamicClient)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetContext lazily creates a context if it doesn't exist already
+func (o *Options) GetContext() context.Context {
+	if o.Ctx == nil {
+		o.Ctx = context.TODO()
+	}
+	return o.Ctx
+}
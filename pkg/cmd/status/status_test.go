@@ -0,0 +1,142 @@
+package status_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/status"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var (
+	testResources = []string{
+		`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+  name: tf-myrepo-pr456-myctx-1
+  namespace: jx
+`,
+		`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+  name: tf-myrepo-pr456-myctx-2
+  namespace: jx
+`,
+		`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+  name: tf-myrepo-pr999-myctx-3
+  namespace: jx
+`,
+	}
+)
+
+func TestStatus(t *testing.T) {
+	ns := "jx"
+
+	for _, failOnError := range []bool{false, true} {
+		kubeClient := fake.NewSimpleClientset(
+			&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-1", Namespace: ns},
+				Status:     batchv1.JobStatus{Active: 1},
+			},
+			&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-2", Namespace: ns},
+				Status: batchv1.JobStatus{
+					Failed: 1,
+					Conditions: []batchv1.JobCondition{
+						{
+							Type:   batchv1.JobFailed,
+							Status: corev1.ConditionTrue,
+						},
+					},
+				},
+			},
+		)
+
+		dynObjects := tftests.ParseUnstructureds(t, nil, testResources)
+
+		out := &bytes.Buffer{}
+		_, o := status.NewCmdStatus()
+		o.Namespace = ns
+		o.FailOnError = failOnError
+		o.Out = out
+		o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+		o.KubeClient = kubeClient
+
+		err := o.Run()
+		if failOnError {
+			require.Error(t, err, "should fail as a Job has failed")
+		} else {
+			require.NoError(t, err, "failed to run status command")
+		}
+
+		rows := map[string][]string{}
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			fields := strings.Fields(line)
+			rows[fields[0]] = fields
+		}
+		t.Logf("got output:\n%s\n", out.String())
+
+		assert.Equal(t, []string{"tf-myrepo-pr456-myctx-1", ns, "tf-myrepo-pr456-myctx-1", "active"}, rows["tf-myrepo-pr456-myctx-1"], "active Job")
+		assert.Equal(t, []string{"tf-myrepo-pr456-myctx-2", ns, "tf-myrepo-pr456-myctx-2", "failed"}, rows["tf-myrepo-pr456-myctx-2"], "failed Job")
+		assert.Equal(t, []string{"tf-myrepo-pr999-myctx-3", ns, "none"}, rows["tf-myrepo-pr999-myctx-3"], "no Job")
+	}
+}
+
+func TestStatusFilterOptions(t *testing.T) {
+	ns := "jx"
+
+	kubeClient := fake.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "tf-apply-abc",
+				Namespace: ns,
+				Labels:    map[string]string{"terraform": "tf-other-1"},
+			},
+			Status: batchv1.JobStatus{Active: 1},
+		},
+	)
+
+	dynObjects := tftests.ParseUnstructureds(t, nil, append([]string{
+		`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: other-test
+  name: tf-other-1
+  namespace: jx
+`,
+	}, testResources...))
+
+	out := &bytes.Buffer{}
+	_, o := status.NewCmdStatus()
+	o.Namespace = ns
+	o.KindLabelValue = "other-test"
+	o.JobLabel = "terraform"
+	o.Out = out
+	o.DynamicClient = tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	o.KubeClient = kubeClient
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run status command")
+
+	t.Logf("got output:\n%s\n", out.String())
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2, "should only show the header and the resource matching the kind label value")
+	assert.Equal(t, []string{"tf-other-1", ns, "tf-apply-abc", "active"}, strings.Fields(lines[1]), "Job found by label")
+}
@@ -1,9 +1,17 @@
 package version
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/root"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cobras/helper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/options"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -25,9 +33,21 @@ const (
 	TestVersion = "1.0.0-SNAPSHOT"
 )
 
-// ShowOptions the options for viewing running PRs
+// Options the options for the command
 type Options struct {
 	Verbose bool
+	Output  string
+	Out     io.Writer
+}
+
+// Info the build information of the binary
+type Info struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Branch    string `json:"branch,omitempty"`
+	BuildDate string `json:"buildDate,omitempty"`
+	GoVersion string `json:"goVersion,omitempty"`
 }
 
 // NewCmdVersion creates a command object for the "version" command
@@ -42,16 +62,54 @@ func NewCmdVersion() (*cobra.Command, *Options) {
 			helper.CheckErr(err)
 		},
 	}
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "", "the output format. Supported values: json")
 	return cmd, o
 }
 
 // Run implements the command
 func (o *Options) Run() error {
-	v := GetVersion()
-	log.Logger().Infof("version: %s", termcolor.ColorInfo(v))
-	return nil
+	if o.Out == nil {
+		o.Out = os.Stdout
+	}
+	info := GetInfo()
+	switch o.Output {
+	case "":
+		log.Logger().Infof("%s version: %s", info.Name, termcolor.ColorInfo(info.Version))
+		if info.Revision != "" {
+			log.Logger().Infof("git commit: %s", info.Revision)
+		}
+		if info.BuildDate != "" {
+			log.Logger().Infof("build date: %s", info.BuildDate)
+		}
+		return nil
+	case "json":
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal version to JSON")
+		}
+		_, err = fmt.Fprintln(o.Out, string(data))
+		if err != nil {
+			return errors.Wrapf(err, "failed to write version")
+		}
+		return nil
+	default:
+		return options.InvalidOption("output", o.Output, []string{"json"})
+	}
+}
+
+// GetInfo returns the build information
+func GetInfo() *Info {
+	return &Info{
+		Name:      root.BinaryName,
+		Version:   GetVersion(),
+		Revision:  Revision,
+		Branch:    Branch,
+		BuildDate: BuildDate,
+		GoVersion: GoVersion,
+	}
 }
 
+// GetVersion returns the version of the binary or TestVersion if it was not set at build time
 func GetVersion() string {
 	if Version != "" {
 		return Version
@@ -0,0 +1,49 @@
+package version_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/cmd/version"
+	"github.com/jenkins-x-plugins/jx-test/pkg/root"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion(t *testing.T) {
+	_, o := version.NewCmdVersion()
+
+	var err error
+	output := log.CaptureOutput(func() {
+		err = o.Run()
+	})
+	require.NoError(t, err, "failed to run version command")
+	assert.Contains(t, output, version.TestVersion, "should include the version")
+	assert.Contains(t, output, root.BinaryName, "should include the binary name")
+}
+
+func TestVersionJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	_, o := version.NewCmdVersion()
+	o.Output = "json"
+	o.Out = out
+
+	err := o.Run()
+	require.NoError(t, err, "failed to run version command")
+
+	info := &version.Info{}
+	err = json.Unmarshal(out.Bytes(), info)
+	require.NoError(t, err, "failed to parse output %s", out.String())
+	assert.Equal(t, root.BinaryName, info.Name, "name")
+	assert.Equal(t, version.TestVersion, info.Version, "version")
+}
+
+func TestVersionInvalidOutput(t *testing.T) {
+	_, o := version.NewCmdVersion()
+	o.Output = "yaml"
+
+	err := o.Run()
+	assert.Error(t, err, "should fail with an unsupported output format")
+}
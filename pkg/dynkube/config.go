@@ -0,0 +1,16 @@
+package dynkube
+
+import (
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// NewClientConfig creates the client config for the given kube config file and context. If the file is empty the
+// default loading rules are used such as $KUBECONFIG and if the context is empty the current context is used
+func NewClientConfig(kubeConfig, kubeContext string) clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeConfig != "" {
+		rules.ExplicitPath = kubeConfig
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+}
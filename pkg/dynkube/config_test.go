@@ -0,0 +1,64 @@
+package dynkube_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster-a
+  cluster:
+    server: https://cluster-a.example.com
+- name: cluster-b
+  cluster:
+    server: https://cluster-b.example.com
+contexts:
+- name: context-a
+  context:
+    cluster: cluster-a
+    namespace: ns-a
+- name: context-b
+  context:
+    cluster: cluster-b
+    namespace: ns-b
+current-context: context-a
+`
+
+func TestNewClientConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(path, []byte(testKubeConfig), 0600)
+	require.NoError(t, err, "failed to write kube config %s", path)
+
+	testCases := []struct {
+		context   string
+		host      string
+		namespace string
+	}{
+		{context: "", host: "https://cluster-a.example.com", namespace: "ns-a"},
+		{context: "context-b", host: "https://cluster-b.example.com", namespace: "ns-b"},
+	}
+	for _, tc := range testCases {
+		config := dynkube.NewClientConfig(path, tc.context)
+
+		restConfig, err := config.ClientConfig()
+		require.NoError(t, err, "failed to load rest config for context %q", tc.context)
+		assert.Equal(t, tc.host, restConfig.Host, "host for context %q", tc.context)
+
+		ns, _, err := config.Namespace()
+		require.NoError(t, err, "failed to find namespace for context %q", tc.context)
+		assert.Equal(t, tc.namespace, ns, "namespace for context %q", tc.context)
+	}
+
+	_, err = dynkube.NewClientConfig(path, "does-not-exist").ClientConfig()
+	assert.Error(t, err, "should fail for a missing context")
+
+	_, err = dynkube.NewClientConfig(filepath.Join(t.TempDir(), "missing"), "").ClientConfig()
+	assert.Error(t, err, "should fail for a missing kube config file")
+}
@@ -1,11 +1,23 @@
 package dynkube
 
 import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
-	"strings"
 )
 
+// DeletionPollInterval the interval between checks of whether a deleted resource has gone
+var DeletionPollInterval = 2 * time.Second
+
 // DynamicResource creates the client interface
 func DynamicResource(dynamicClient dynamic.Interface, ns string, gvr schema.GroupVersionResource) dynamic.ResourceInterface {
 	var client dynamic.ResourceInterface
@@ -17,6 +29,115 @@ func DynamicResource(dynamicClient dynamic.Interface, ns string, gvr schema.Grou
 	return client
 }
 
+// DeleteResource deletes the resource with the given name treating a resource which is not found as success
+// as it may have already been removed by something else
+func DeleteResource(ctx context.Context, client dynamic.ResourceInterface, name string, opts metav1.DeleteOptions) error {
+	err := client.Delete(ctx, name, opts)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete resource %s", name)
+	}
+	return nil
+}
+
+// WaitForDeletion waits for the resource with the given name to be removed which may take some time after it is
+// deleted if it has finalizers. If the resource still exists after the timeout an error is returned for which
+// IsWaitTimeout returns true. If the given context is done first its error is returned instead
+func WaitForDeletion(ctx context.Context, client dynamic.ResourceInterface, name string, timeout time.Duration) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := wait.PollImmediateUntilWithContext(waitCtx, DeletionPollInterval, func(ctx context.Context) (bool, error) {
+		_, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, errors.Wrapf(err, "failed to query resource %s", name)
+		}
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		if ctx.Err() != nil {
+			return errors.Wrapf(ctx.Err(), "stopped waiting for resource %s to be removed", name)
+		}
+		return errors.Wrapf(err, "resource %s was not removed within %s", name, timeout.String())
+	}
+	return err
+}
+
+// IsWaitTimeout returns true if the error is due to a resource not being removed in time
+func IsWaitTimeout(err error) bool {
+	return errors.Cause(err) == wait.ErrWaitTimeout
+}
+
+// RemoveFinalizers removes all of the finalizers from the resource with the given name so that it can be removed
+// if it is stuck terminating
+func RemoveFinalizers(ctx context.Context, client dynamic.ResourceInterface, name string) error {
+	patch := []byte(`{"metadata":{"finalizers":null}}`)
+	_, err := client.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to remove finalizers from resource %s", name)
+	}
+	return nil
+}
+
+// SetLabel adds or updates the label on the resource with the given name
+func SetLabel(ctx context.Context, client dynamic.ResourceInterface, name, key, value string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]string{key: value},
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal label patch")
+	}
+	_, err = client.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to set label %s on resource %s", key, name)
+	}
+	return nil
+}
+
+// SetAnnotations adds or updates the annotations on the resource with the given name
+func SetAnnotations(ctx context.Context, client dynamic.ResourceInterface, name string, annotations map[string]string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal annotations patch")
+	}
+	_, err = client.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to set annotations on resource %s", name)
+	}
+	return nil
+}
+
+// PatchMetadata merges the given labels and annotations into the resource with the given name. A nil value removes
+// the label or annotation
+func PatchMetadata(ctx context.Context, client dynamic.ResourceInterface, name string, labels, annotations map[string]interface{}) error {
+	metadata := map[string]interface{}{}
+	if len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": metadata,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal metadata patch")
+	}
+	_, err = client.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to patch the labels and annotations of resource %s", name)
+	}
+	return nil
+}
+
 // ToSelector converts the given labels into a selector string
 func ToSelector(labels map[string]string) string {
 	if labels == nil {
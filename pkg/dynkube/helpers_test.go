@@ -0,0 +1,136 @@
+package dynkube_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDeleteResource(t *testing.T) {
+	ctx := context.Background()
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme())
+	client := dynkube.DynamicResource(fakeDynClient, "jx", terraforms.TerraformResource)
+
+	err := dynkube.DeleteResource(ctx, client, "does-not-exist", metav1.DeleteOptions{})
+	require.NoError(t, err, "should ignore a resource which is not found")
+
+	fakeDynClient.PrependReactor("delete", "terraforms", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(terraforms.TerraformResource.GroupResource(), "tf-myrepo-pr456-myctx-1", errors.New("rbac denied"))
+	})
+	err = dynkube.DeleteResource(ctx, client, "tf-myrepo-pr456-myctx-1", metav1.DeleteOptions{})
+	require.Error(t, err, "should fail if the resource cannot be deleted")
+	assert.Contains(t, err.Error(), "tf-myrepo-pr456-myctx-1", "error should mention the resource name")
+	assert.True(t, apierrors.IsForbidden(errors.Cause(err)), "should wrap the forbidden error")
+}
+
+func TestWaitForDeletion(t *testing.T) {
+	ctx := context.Background()
+	dynkube.DeletionPollInterval = 10 * time.Millisecond
+
+	dynObjects := tftests.ParseUnstructureds(t, nil, []string{`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  name: tf-terminating
+  namespace: jx
+`})
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	client := dynkube.DynamicResource(fakeDynClient, "jx", terraforms.TerraformResource)
+
+	err := dynkube.WaitForDeletion(ctx, client, "does-not-exist", time.Second)
+	require.NoError(t, err, "should not wait for a resource which is not found")
+
+	err = dynkube.WaitForDeletion(ctx, client, "tf-terminating", 50*time.Millisecond)
+	require.Error(t, err, "should time out waiting for a resource which still exists")
+	assert.True(t, dynkube.IsWaitTimeout(err), "should be a timeout error but got %s", err.Error())
+
+	// lets check the parent context being done is not mistaken for the resource not being removed in time
+	parentCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err = dynkube.WaitForDeletion(parentCtx, client, "tf-terminating", time.Minute)
+	require.Error(t, err, "should stop waiting when the parent context is done")
+	assert.False(t, dynkube.IsWaitTimeout(err), "should not be a timeout error but got %s", err.Error())
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "should be a deadline exceeded error but got %s", err.Error())
+}
+
+func TestRemoveFinalizers(t *testing.T) {
+	ctx := context.Background()
+	dynObjects := tftests.ParseUnstructureds(t, nil, []string{`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  name: tf-stuck
+  namespace: jx
+  finalizers:
+  - finalizer.tf.isaaguilar.com
+`})
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	client := dynkube.DynamicResource(fakeDynClient, "jx", terraforms.TerraformResource)
+
+	err := dynkube.RemoveFinalizers(ctx, client, "tf-stuck")
+	require.NoError(t, err, "failed to remove finalizers")
+
+	u, err := client.Get(ctx, "tf-stuck", metav1.GetOptions{})
+	require.NoError(t, err, "failed to get resource")
+	assert.Empty(t, u.GetFinalizers(), "finalizers")
+
+	err = dynkube.RemoveFinalizers(ctx, client, "does-not-exist")
+	require.NoError(t, err, "should ignore a resource which is not found")
+}
+
+func TestSetLabel(t *testing.T) {
+	ctx := context.Background()
+	dynObjects := tftests.ParseUnstructureds(t, nil, []string{`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  name: tf-label
+  namespace: jx
+  labels:
+    kind: jx-test
+`})
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	client := dynkube.DynamicResource(fakeDynClient, "jx", terraforms.TerraformResource)
+
+	err := dynkube.SetLabel(ctx, client, "tf-label", "jx-test/marked-for-gc", "1700000000")
+	require.NoError(t, err, "failed to set label")
+
+	u, err := client.Get(ctx, "tf-label", metav1.GetOptions{})
+	require.NoError(t, err, "failed to get resource")
+	assert.Equal(t, map[string]string{"kind": "jx-test", "jx-test/marked-for-gc": "1700000000"}, u.GetLabels(), "labels")
+
+	err = dynkube.SetLabel(ctx, client, "does-not-exist", "foo", "bar")
+	require.Error(t, err, "should fail for a resource which is not found")
+}
+
+func TestSetAnnotations(t *testing.T) {
+	ctx := context.Background()
+	dynObjects := tftests.ParseUnstructureds(t, nil, []string{`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  name: tf-annotate
+  namespace: jx
+  annotations:
+    jx-test/ttl: 24h
+`})
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+	client := dynkube.DynamicResource(fakeDynClient, "jx", terraforms.TerraformResource)
+
+	err := dynkube.SetAnnotations(ctx, client, "tf-annotate", map[string]string{"jx-test/gc-reason": "expired"})
+	require.NoError(t, err, "failed to set annotations")
+
+	u, err := client.Get(ctx, "tf-annotate", metav1.GetOptions{})
+	require.NoError(t, err, "failed to get resource")
+	assert.Equal(t, map[string]string{"jx-test/ttl": "24h", "jx-test/gc-reason": "expired"}, u.GetAnnotations(), "annotations")
+
+	err = dynkube.SetAnnotations(ctx, client, "does-not-exist", map[string]string{"foo": "bar"})
+	require.Error(t, err, "should fail for a resource which is not found")
+}
@@ -0,0 +1,66 @@
+package dynkube
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// DiscoverKind returns the kind of the given resource using the discovery API
+func DiscoverKind(client discovery.DiscoveryInterface, gvr schema.GroupVersionResource) (string, error) {
+	groupVersion := gvr.GroupVersion().String()
+	list, err := client.ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to discover the resources for %s", groupVersion)
+	}
+	for _, r := range list.APIResources {
+		if r.Name == gvr.Resource {
+			return r.Kind, nil
+		}
+	}
+	return "", errors.Errorf("could not find resource %s in %s", gvr.Resource, groupVersion)
+}
+
+// ResourceExists returns true if the given resource is served by the API server. False is returned without an
+// error if the group version is not registered or does not contain the resource
+func ResourceExists(client discovery.DiscoveryInterface, gvr schema.GroupVersionResource) (bool, error) {
+	groupVersion := gvr.GroupVersion().String()
+	list, err := client.ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to discover the resources for %s", groupVersion)
+	}
+	for _, r := range list.APIResources {
+		if r.Name == gvr.Resource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// KindForResource guesses the kind from the plural resource name such as terraforms to Terraform. As word
+// boundaries cannot be inferred prefer DiscoverKind for multi word kinds
+func KindForResource(resource string) string {
+	singular := resource
+	switch {
+	case strings.HasSuffix(resource, "ies"):
+		singular = strings.TrimSuffix(resource, "ies") + "y"
+	case strings.HasSuffix(resource, "sses"), strings.HasSuffix(resource, "uses"), strings.HasSuffix(resource, "xes"),
+		strings.HasSuffix(resource, "ches"), strings.HasSuffix(resource, "shes"):
+		singular = strings.TrimSuffix(resource, "es")
+	case strings.HasSuffix(resource, "s") && !strings.HasSuffix(resource, "ss") && !strings.HasSuffix(resource, "us"):
+		singular = strings.TrimSuffix(resource, "s")
+	}
+	r, size := utf8.DecodeRuneInString(singular)
+	if r == utf8.RuneError {
+		return singular
+	}
+	return string(unicode.ToUpper(r)) + singular[size:]
+}
@@ -0,0 +1,73 @@
+package dynkube_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKindForResource(t *testing.T) {
+	testCases := map[string]string{
+		"terraforms":     "Terraform",
+		"policies":       "Policy",
+		"ingresses":      "Ingress",
+		"ingressclasses": "Ingressclass",
+		"patches":        "Patch",
+		"statuses":       "Status",
+		"mytestclusters": "Mytestcluster",
+	}
+	for resource, expected := range testCases {
+		assert.Equal(t, expected, dynkube.KindForResource(resource), "kind for %s", resource)
+	}
+}
+
+func TestDiscoverKind(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "infra.example.com", Version: "v1beta1", Resource: "testclusters"}
+
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.Fake.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: terraforms.TerraformResource.GroupVersion().String(),
+			APIResources: []metav1.APIResource{{Name: "terraforms", Kind: "Terraform"}},
+		},
+		{
+			GroupVersion: gvr.GroupVersion().String(),
+			APIResources: []metav1.APIResource{{Name: "testclusters", Kind: "TestCluster"}},
+		},
+	}
+
+	kind, err := dynkube.DiscoverKind(kubeClient.Discovery(), gvr)
+	require.NoError(t, err, "failed to discover kind")
+	assert.Equal(t, "TestCluster", kind, "kind for multi word resource")
+
+	kind, err = dynkube.DiscoverKind(kubeClient.Discovery(), terraforms.TerraformResource)
+	require.NoError(t, err, "failed to discover kind")
+	assert.Equal(t, "Terraform", kind, "kind for terraforms")
+
+	_, err = dynkube.DiscoverKind(kubeClient.Discovery(), schema.GroupVersionResource{Group: "other.example.com", Version: "v1", Resource: "things"})
+	assert.Error(t, err, "should fail for an unknown group version")
+}
+
+func TestResourceExists(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.Fake.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: terraforms.TerraformResource.GroupVersion().String(),
+			APIResources: []metav1.APIResource{{Name: "terraforms", Kind: "Terraform"}},
+		},
+	}
+
+	exists, err := dynkube.ResourceExists(kubeClient.Discovery(), terraforms.TerraformResource)
+	require.NoError(t, err, "failed to check resource exists")
+	assert.True(t, exists, "terraforms should exist")
+
+	exists, err = dynkube.ResourceExists(kubeClient.Discovery(), schema.GroupVersionResource{Group: "tf.isaaguilar.com", Version: "v1alpha1", Resource: "things"})
+	require.NoError(t, err, "failed to check resource exists")
+	assert.False(t, exists, "things should not exist")
+}
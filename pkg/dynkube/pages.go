@@ -0,0 +1,36 @@
+package dynkube
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// ListPages lists the resources a page at a time of at most pageSize items invoking the callback for each page
+// before the next page is fetched so the pages are not accumulated. A pageSize of zero or less lists all the
+// resources in a single page
+func ListPages(ctx context.Context, client dynamic.ResourceInterface, opts metav1.ListOptions, pageSize int64, fn func(list *unstructured.UnstructuredList) error) error {
+	if pageSize > 0 {
+		opts.Limit = pageSize
+	}
+	for {
+		list, err := client.List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if list == nil {
+			return nil
+		}
+		err = fn(list)
+		if err != nil {
+			return errors.Wrapf(err, "failed to process page")
+		}
+		opts.Continue = list.GetContinue()
+		if opts.Continue == "" {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package dynkube_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// pagedClient returns the names a page at a time using the Limit and Continue list options
+type pagedClient struct {
+	dynamic.ResourceInterface
+	names []string
+	calls []metav1.ListOptions
+}
+
+func (c *pagedClient) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	c.calls = append(c.calls, opts)
+	start := 0
+	if opts.Continue != "" {
+		start, _ = strconv.Atoi(opts.Continue)
+	}
+	end := len(c.names)
+	if opts.Limit > 0 && start+int(opts.Limit) < end {
+		end = start + int(opts.Limit)
+	}
+	list := &unstructured.UnstructuredList{}
+	for _, name := range c.names[start:end] {
+		u := unstructured.Unstructured{}
+		u.SetName(name)
+		list.Items = append(list.Items, u)
+	}
+	if end < len(c.names) {
+		list.SetContinue(strconv.Itoa(end))
+	}
+	return list, nil
+}
+
+func TestListPages(t *testing.T) {
+	client := &pagedClient{names: []string{"a", "b", "c", "d", "e"}}
+
+	var pages [][]string
+	err := dynkube.ListPages(context.Background(), client, metav1.ListOptions{LabelSelector: "kind=jx-test"}, 2, func(list *unstructured.UnstructuredList) error {
+		var names []string
+		for _, r := range list.Items {
+			names = append(names, r.GetName())
+		}
+		pages = append(pages, names)
+		return nil
+	})
+	require.NoError(t, err, "failed to list pages")
+
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, pages, "pages")
+	require.Len(t, client.calls, 3, "list calls")
+	for _, opts := range client.calls {
+		assert.Equal(t, int64(2), opts.Limit, "limit")
+		assert.Equal(t, "kind=jx-test", opts.LabelSelector, "selector")
+	}
+}
+
+func TestListPagesNoLimit(t *testing.T) {
+	client := &pagedClient{names: []string{"a", "b", "c"}}
+
+	count := 0
+	err := dynkube.ListPages(context.Background(), client, metav1.ListOptions{}, 0, func(list *unstructured.UnstructuredList) error {
+		count += len(list.Items)
+		return nil
+	})
+	require.NoError(t, err, "failed to list pages")
+	assert.Equal(t, 3, count, "items")
+	assert.Len(t, client.calls, 1, "list calls")
+}
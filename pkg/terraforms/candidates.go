@@ -0,0 +1,123 @@
+package terraforms
+
+import (
+	"context"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/dynkube"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// ReasonKeptLabel the resource is kept as it has a keep label or annotation
+	ReasonKeptLabel = "kept-label"
+
+	// ReasonKeptTooYoung the resource is kept as it is not old enough to be garbage collected
+	ReasonKeptTooYoung = "kept-too-young"
+)
+
+var (
+	// ListPageSize the maximum number of Terraform resources fetched in each list request by ListTestTerraforms
+	ListPageSize int64 = 500
+)
+
+// Candidate a test Terraform resource along with whether it should be garbage collected
+type Candidate struct {
+	Name         string
+	Namespace    string
+	Created      time.Time
+	Labels       map[string]string
+	ShouldDelete bool
+	// Reason the reason the resource is kept such as ReasonKeptLabel or ReasonKeptTooYoung
+	Reason string
+}
+
+// ListTestTerraforms lists the Terraform resources in the namespace matching the selector and evaluates whether
+// each one should be garbage collected as it was created before the cutoff time
+func ListTestTerraforms(ctx context.Context, client dynamic.Interface, ns, selector string, cutoff time.Time) ([]Candidate, error) {
+	now := time.Now()
+	resources := dynkube.DynamicResource(client, ns, TerraformResource)
+	var answer []Candidate
+	err := dynkube.ListPages(ctx, resources, metav1.ListOptions{LabelSelector: selector}, ListPageSize, func(list *unstructured.UnstructuredList) error {
+		for i := range list.Items {
+			answer = append(answer, EvaluateCandidate(&list.Items[i], LabelKeep, AnnotationKeep, cutoff, now))
+		}
+		return nil
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to list Terraform resources in namespace %s with selector %s", ns, selector)
+	}
+	return answer, nil
+}
+
+// EvaluateCandidate returns whether the given resource should be garbage collected.
+//
+// Resources with the keep label or keep annotation are kept. Otherwise the resource is garbage collected if it was created before the
+// cutoff time or before its AnnotationTTL annotation if it has one.
+func EvaluateCandidate(r *unstructured.Unstructured, keepLabel, keepAnnotation string, cutoff, now time.Time) Candidate {
+	created := r.GetCreationTimestamp()
+	c := Candidate{
+		Name:      r.GetName(),
+		Namespace: r.GetNamespace(),
+		Created:   created.Time,
+		Labels:    r.GetLabels(),
+	}
+
+	keep, err := IsKeptWithKeys(c.Labels, r.GetAnnotations(), keepLabel, keepAnnotation)
+	if err != nil {
+		log.Logger().Warnf("%s %s: %s", r.GetKind(), info(c.Name), err.Error())
+	}
+	if keep {
+		expired, ok := ttlExpired(r, now)
+		if ok {
+			log.Logger().Warnf("%s %s has a keep label or annotation but its %s annotation expired at %s so it is kept as the keep label wins. Remove the keep label if you intended the TTL to apply",
+				r.GetKind(), info(c.Name), AnnotationTTL, expired.Format(time.RFC3339))
+		}
+		c.Reason = ReasonKeptLabel
+		return c
+	}
+
+	if !created.Before(&metav1.Time{Time: ResourceCutoff(r, cutoff, now)}) {
+		c.Reason = ReasonKeptTooYoung
+		return c
+	}
+	c.ShouldDelete = true
+	return c
+}
+
+// ttlExpired returns the time the AnnotationTTL annotation on the resource expired and true if it is in the past
+func ttlExpired(r *unstructured.Unstructured, now time.Time) (time.Time, bool) {
+	ttl := r.GetAnnotations()[AnnotationTTL]
+	if ttl == "" {
+		return time.Time{}, false
+	}
+	d, err := time.ParseDuration(ttl)
+	if err != nil {
+		return time.Time{}, false
+	}
+	expired := r.GetCreationTimestamp().Add(d)
+	return expired, expired.Before(now)
+}
+
+// ResourceCutoff returns the time before which the resource must have been created to be garbage collected
+// taking into account any TTL annotation on the resource
+func ResourceCutoff(r *unstructured.Unstructured, cutoff, now time.Time) time.Time {
+	ttl := r.GetAnnotations()[AnnotationTTL]
+	if ttl == "" {
+		return cutoff
+	}
+	d, err := time.ParseDuration(ttl)
+	if err != nil {
+		log.Logger().Warnf("ignoring invalid %s annotation %s on %s: %s", AnnotationTTL, ttl, r.GetName(), err.Error())
+		return cutoff
+	}
+	return now.Add(d * -1)
+}
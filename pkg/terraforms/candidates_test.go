@@ -0,0 +1,137 @@
+package terraforms_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestListTestTerraforms(t *testing.T) {
+	ns := "jx"
+	now := time.Now()
+	oldTime := now.Add(-5 * time.Hour)
+	recentTime := now.Add(-1 * time.Hour)
+
+	resources := []string{
+		`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+  name: tf-old
+  namespace: jx
+`,
+		`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+  name: tf-recent
+  namespace: jx
+`,
+		`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: jx-test
+    keep: "yes"
+  name: tf-keep
+  namespace: jx
+`,
+		`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  annotations:
+    jx-test/ttl: 30m
+  labels:
+    kind: jx-test
+  name: tf-short-ttl
+  namespace: jx
+`,
+		`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  labels:
+    kind: something-else
+  name: tf-not-a-test
+  namespace: jx
+`,
+	}
+
+	fn := func(idx int, u *unstructured.Unstructured) {
+		created := oldTime
+		if idx == 1 || idx == 3 {
+			created = recentTime
+		}
+		u.SetCreationTimestamp(metav1.Time{Time: created})
+	}
+	dynObjects := tftests.ParseUnstructureds(t, fn, resources)
+	fakeDynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	candidates, err := terraforms.ListTestTerraforms(context.Background(), fakeDynClient, ns, "kind=jx-test", now.Add(-2*time.Hour))
+	require.NoError(t, err, "failed to list test Terraforms")
+
+	results := map[string]string{}
+	for _, c := range candidates {
+		assert.Equal(t, ns, c.Namespace, "namespace of %s", c.Name)
+		result := c.Reason
+		if c.ShouldDelete {
+			result = "delete"
+		}
+		results[c.Name] = result
+	}
+	assert.Equal(t, map[string]string{
+		"tf-old":       "delete",
+		"tf-recent":    terraforms.ReasonKeptTooYoung,
+		"tf-keep":      terraforms.ReasonKeptLabel,
+		"tf-short-ttl": "delete",
+	}, results, "candidates")
+}
+
+func TestEvaluateCandidateKeepLabelWithExpiredTTL(t *testing.T) {
+	now := time.Now()
+	cutoff := now.Add(-2 * time.Hour)
+
+	testCases := []struct {
+		name        string
+		ttl         string
+		expectWarns bool
+	}{
+		{name: "tf-expired-ttl", ttl: "30m", expectWarns: true},
+		{name: "tf-future-ttl", ttl: "3h"},
+		{name: "tf-no-ttl"},
+	}
+	for _, tc := range testCases {
+		r := &unstructured.Unstructured{}
+		r.SetKind("Terraform")
+		r.SetName(tc.name)
+		r.SetNamespace("jx")
+		r.SetCreationTimestamp(metav1.Time{Time: now.Add(-1 * time.Hour)})
+		r.SetLabels(map[string]string{"kind": "jx-test", terraforms.LabelKeep: "true"})
+		if tc.ttl != "" {
+			r.SetAnnotations(map[string]string{terraforms.AnnotationTTL: tc.ttl})
+		}
+
+		var c terraforms.Candidate
+		output := log.CaptureOutput(func() {
+			c = terraforms.EvaluateCandidate(r, terraforms.LabelKeep, terraforms.AnnotationKeep, cutoff, now)
+		})
+		assert.False(t, c.ShouldDelete, "should keep %s", tc.name)
+		assert.Equal(t, terraforms.ReasonKeptLabel, c.Reason, "reason for %s", tc.name)
+		if tc.expectWarns {
+			assert.Contains(t, output, "has a keep label or annotation but its jx-test/ttl annotation expired", "should warn for %s", tc.name)
+			assert.Contains(t, output, "the keep label wins", "should explain keep wins for %s", tc.name)
+		} else {
+			assert.NotContains(t, output, "the keep label wins", "should not warn for %s", tc.name)
+		}
+	}
+}
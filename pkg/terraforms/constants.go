@@ -4,8 +4,49 @@ import "k8s.io/apimachinery/pkg/runtime/schema"
 
 const (
 
+	// LabelKind the label on a Terraform resource used to identify the kind of resource such as a test
+	LabelKind = "kind"
+
 	// LabelValueKindTest the kind label value for tests
 	LabelValueKindTest = "jx-test"
+
+	// LabelKeep the label on a Terraform resource to prevent it being garbage collected
+	LabelKeep = "keep"
+
+	// AnnotationKeep the annotation on a Terraform resource to prevent it being garbage collected
+	AnnotationKeep = "jx-test/keep"
+
+	// AnnotationTTL the annotation on a Terraform resource to override the maximum age before it is garbage collected
+	// using the time.ParseDuration syntax such as 24h
+	AnnotationTTL = "jx-test/ttl"
+
+	// LabelMarkedForGC the label on a Terraform resource which has been marked for garbage collection with the
+	// value being the unix time in seconds when it was marked
+	LabelMarkedForGC = "jx-test/marked-for-gc"
+
+	// AnnotationGCReason the annotation added to a Terraform resource just before it is garbage collected recording
+	// why it was selected for deletion
+	AnnotationGCReason = "jx-test/gc-reason"
+
+	// AnnotationGCTime the annotation added to a Terraform resource just before it is garbage collected recording
+	// when it was selected for deletion in RFC 3339 format
+	AnnotationGCTime = "jx-test/gc-time"
+
+	// LabelTerraform the default label on Kubernetes resources which are owned by a Terraform resource with the
+	// value being the name of the Terraform resource
+	LabelTerraform = "terraform"
+
+	// JobStatusActive the Terraform Job is still running
+	JobStatusActive = "active"
+
+	// JobStatusSucceeded the Terraform Job completed successfully
+	JobStatusSucceeded = "succeeded"
+
+	// JobStatusFailed the Terraform Job failed
+	JobStatusFailed = "failed"
+
+	// JobStatusPending the Terraform Job has not started yet
+	JobStatusPending = "pending"
 )
 
 var (
@@ -13,3 +54,8 @@ var (
 	// see:
 	TerraformResource = schema.GroupVersionResource{Group: "tf.isaaguilar.com", Version: "v1alpha1", Resource: "terraforms"}
 )
+
+// KindSelector returns the label selector for resources with the given kind label value
+func KindSelector(value string) string {
+	return LabelKind + "=" + value
+}
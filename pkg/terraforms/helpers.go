@@ -4,8 +4,9 @@ import (
 	"context"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jobs"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
-	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -15,30 +16,136 @@ var (
 	info = termcolor.ColorInfo
 )
 
+// JobOptions the options for deleting the active Terraform Jobs of a Terraform resource
+type JobOptions struct {
+	// DryRun only logs the Jobs and Pods which would be deleted
+	DryRun bool
+
+	// JobLabel the label key whose value is the name of the Terraform resource used to find its Jobs. If not
+	// specified the Job with the same name as the Terraform resource is used
+	JobLabel string
+
+	// GracePeriodSeconds the termination grace period of the deleted Jobs and Pods. If nil the API default is used
+	GracePeriodSeconds *int64
+}
+
+// deleteOptions returns the options used to delete Jobs and Pods
+func (o *JobOptions) deleteOptions() metav1.DeleteOptions {
+	return metav1.DeleteOptions{GracePeriodSeconds: o.GracePeriodSeconds}
+}
+
 // DeleteActiveTerraformJobs deletes any non completed apply Terraform Jobs as we are about to remove the
 // Terraform resource
 func DeleteActiveTerraformJobs(ctx context.Context, kubeClient kubernetes.Interface, ns, name string) error {
+	return DeleteActiveTerraformJobsWithOptions(ctx, kubeClient, ns, name, JobOptions{})
+}
+
+// DeleteActiveTerraformJobsWithOptions deletes any non completed apply Terraform Jobs as we are about to remove the
+// Terraform resource. In dry run mode the Jobs and Pods are only logged
+func DeleteActiveTerraformJobsWithOptions(ctx context.Context, kubeClient kubernetes.Interface, ns, name string, opts JobOptions) error {
+	jobList, err := ListTerraformJobsWithOptions(ctx, kubeClient, ns, name, opts)
+	if err != nil {
+		return err
+	}
 	jobInterface := kubeClient.BatchV1().Jobs(ns)
-	job, err := jobInterface.Get(ctx, name, metav1.GetOptions{})
-	if err != nil && apierrors.IsNotFound(err) {
-		return deleteTerraformPods(ctx, kubeClient, ns, name)
+	for i := range jobList {
+		job := &jobList[i]
+		if jobs.IsJobFinished(job) {
+			continue
+		}
+		if opts.DryRun {
+			Logger(ctx).Infof("dry-run: would delete terraform apply Job %s in namespace %s as has not finished", info(job.Name), ns)
+			continue
+		}
+		Logger(ctx).Infof("deleting terraform apply Job %s in namespace %s as has not finished and we are about to delete the Terraform resource", info(job.Name), ns)
+		err = jobInterface.Delete(ctx, job.Name, opts.deleteOptions())
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete Job %s in namespace %s", job.Name, ns)
+		}
+		Logger(ctx).Infof("deleted terraform apply Job %s in namespace %s", info(job.Name), ns)
+	}
+	if opts.JobLabel == "" {
+		return deleteTerraformPods(ctx, kubeClient, ns, name, opts)
 	}
+	for i := range jobList {
+		err = deleteTerraformPods(ctx, kubeClient, ns, jobList[i].Name, opts)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountActiveTerraformJobs returns the number of non completed Terraform Jobs for the given Terraform resource name
+func CountActiveTerraformJobs(ctx context.Context, kubeClient kubernetes.Interface, ns, name string, opts JobOptions) (int, error) {
+	jobList, err := ListTerraformJobsWithOptions(ctx, kubeClient, ns, name, opts)
 	if err != nil {
-		return errors.Wrapf(err, "failed to query Job %s in namespace %s", name, ns)
+		return 0, err
 	}
-	if job == nil || jobs.IsJobFinished(job) {
-		return deleteTerraformPods(ctx, kubeClient, ns, name)
+	count := 0
+	for i := range jobList {
+		if !jobs.IsJobFinished(&jobList[i]) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ListTerraformJobsWithOptions lists the Terraform Jobs for the given Terraform resource name using the
+// JobOptions.JobLabel label if specified
+func ListTerraformJobsWithOptions(ctx context.Context, kubeClient kubernetes.Interface, ns, name string, opts JobOptions) ([]batchv1.Job, error) {
+	if opts.JobLabel == "" {
+		return ListTerraformJobs(ctx, kubeClient, ns, name)
 	}
-	log.Logger().Infof("deleting terraform apply Job %s in namespace %s as has not finished and we are about to delete the Terraform resource", info(name), ns)
-	err = jobInterface.Delete(ctx, name, metav1.DeleteOptions{})
+	selector := opts.JobLabel + "=" + name
+	jobList, err := kubeClient.BatchV1().Jobs(ns).List(ctx, metav1.ListOptions{
+		LabelSelector: selector,
+	})
 	if err != nil {
-		return errors.Wrapf(err, "failed to delete Job %s in namespace %s", name, ns)
+		return nil, errors.Wrapf(err, "failed to list Jobs in namespace %s with selector %s", ns, selector)
 	}
-	log.Logger().Infof("deleted terraform apply Job %s in namespace %s", info(name), ns)
-	return deleteTerraformPods(ctx, kubeClient, ns, name)
+	return jobList.Items, nil
 }
 
-func deleteTerraformPods(ctx context.Context, kubeClient kubernetes.Interface, ns, name string) error {
+// ListTerraformJobs lists the Terraform Jobs for the given Terraform resource name
+func ListTerraformJobs(ctx context.Context, kubeClient kubernetes.Interface, ns, name string) ([]batchv1.Job, error) {
+	job, err := kubeClient.BatchV1().Jobs(ns).Get(ctx, name, metav1.GetOptions{})
+	if err != nil && apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query Job %s in namespace %s", name, ns)
+	}
+	if job == nil {
+		return nil, nil
+	}
+	return []batchv1.Job{*job}, nil
+}
+
+// JobStatus returns the status of the Job which is one of JobStatusActive, JobStatusSucceeded, JobStatusFailed
+// or JobStatusPending
+func JobStatus(job *batchv1.Job) string {
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobFailed:
+			return JobStatusFailed
+		case batchv1.JobComplete:
+			return JobStatusSucceeded
+		}
+	}
+	if job.Status.Active > 0 {
+		return JobStatusActive
+	}
+	if job.Status.CompletionTime != nil {
+		return JobStatusSucceeded
+	}
+	return JobStatusPending
+}
+
+func deleteTerraformPods(ctx context.Context, kubeClient kubernetes.Interface, ns, name string, opts JobOptions) error {
 	selector := "job-name=" + name
 	podInterface := kubeClient.CoreV1().Pods(ns)
 	podList, err := podInterface.List(ctx, metav1.ListOptions{
@@ -56,11 +163,15 @@ func deleteTerraformPods(ctx context.Context, kubeClient kubernetes.Interface, n
 
 	for _, pod := range podList.Items {
 		name := pod.Name
-		err = podInterface.Delete(ctx, name, metav1.DeleteOptions{})
+		if opts.DryRun {
+			Logger(ctx).Infof("dry-run: would delete terraform apply Pod %s in namespace %s", info(name), ns)
+			continue
+		}
+		err = podInterface.Delete(ctx, name, opts.deleteOptions())
 		if err != nil {
 			return errors.Wrapf(err, "failed to delete pod %s", name)
 		}
-		log.Logger().Infof("deleted terraform apply Pod %s in namespace %s", info(name), ns)
+		Logger(ctx).Infof("deleted terraform apply Pod %s in namespace %s", info(name), ns)
 	}
 	return nil
 }
@@ -0,0 +1,267 @@
+package terraforms_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	batchv1client "k8s.io/client-go/kubernetes/typed/batch/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+func TestListTerraformJobs(t *testing.T) {
+	ctx := context.Background()
+	ns := "jx"
+	kubeClient := fake.NewSimpleClientset(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "tf-myrepo-pr456-myctx-1", Namespace: ns},
+	})
+
+	jobList, err := terraforms.ListTerraformJobs(ctx, kubeClient, ns, "tf-myrepo-pr456-myctx-1")
+	require.NoError(t, err, "failed to list Jobs")
+	require.Len(t, jobList, 1, "Jobs")
+	assert.Equal(t, "tf-myrepo-pr456-myctx-1", jobList[0].Name, "Job name")
+
+	jobList, err = terraforms.ListTerraformJobs(ctx, kubeClient, ns, "missing")
+	require.NoError(t, err, "failed to list Jobs")
+	assert.Empty(t, jobList, "Jobs for a missing resource")
+}
+
+func TestCountActiveTerraformJobs(t *testing.T) {
+	ctx := context.Background()
+	ns := "jx"
+	kubeClient := fake.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "active", Namespace: ns},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "complete", Namespace: ns},
+			Status:     batchv1.JobStatus{Succeeded: 1, CompletionTime: &metav1.Time{}},
+		},
+	)
+
+	for name, expected := range map[string]int{"active": 1, "complete": 0, "missing": 0} {
+		count, err := terraforms.CountActiveTerraformJobs(ctx, kubeClient, ns, name, terraforms.JobOptions{})
+		require.NoError(t, err, "failed to count active Jobs for %s", name)
+		assert.Equal(t, expected, count, "active Jobs for %s", name)
+	}
+}
+
+func TestJobStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   batchv1.JobStatus
+		expected string
+	}{
+		{name: "pending", status: batchv1.JobStatus{}, expected: terraforms.JobStatusPending},
+		{name: "active", status: batchv1.JobStatus{Active: 1}, expected: terraforms.JobStatusActive},
+		{name: "completion-time", status: batchv1.JobStatus{Succeeded: 1, CompletionTime: &metav1.Time{}}, expected: terraforms.JobStatusSucceeded},
+		{
+			name: "complete-condition",
+			status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+			},
+			expected: terraforms.JobStatusSucceeded,
+		},
+		{
+			name: "failed-condition",
+			status: batchv1.JobStatus{
+				Failed:     1,
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+			},
+			expected: terraforms.JobStatusFailed,
+		},
+		{
+			name: "false-condition",
+			status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionFalse}},
+			},
+			expected: terraforms.JobStatusPending,
+		},
+	}
+
+	for _, tc := range testCases {
+		job := &batchv1.Job{Status: tc.status}
+		assert.Equal(t, tc.expected, terraforms.JobStatus(job), "status for %s", tc.name)
+	}
+}
+
+func TestDeleteActiveTerraformJobsDryRun(t *testing.T) {
+	ctx := context.Background()
+	ns := "jx"
+	name := "tf-myrepo-pr456-myctx-1"
+	kubeClient := fake.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name + "-abcde", Namespace: ns, Labels: map[string]string{"job-name": name}},
+		},
+	)
+
+	var err error
+	output := log.CaptureOutput(func() {
+		err = terraforms.DeleteActiveTerraformJobsWithOptions(ctx, kubeClient, ns, name, terraforms.JobOptions{DryRun: true})
+	})
+	require.NoError(t, err, "failed to delete active Jobs")
+
+	assert.Contains(t, output, "dry-run: would delete terraform apply Job "+name, "should log the Job")
+	assert.Contains(t, output, "dry-run: would delete terraform apply Pod "+name+"-abcde", "should log the Pod")
+
+	_, err = kubeClient.BatchV1().Jobs(ns).Get(ctx, name, metav1.GetOptions{})
+	require.NoError(t, err, "should not have deleted the Job")
+	_, err = kubeClient.CoreV1().Pods(ns).Get(ctx, name+"-abcde", metav1.GetOptions{})
+	require.NoError(t, err, "should not have deleted the Pod")
+
+	err = terraforms.DeleteActiveTerraformJobsWithOptions(ctx, kubeClient, ns, name, terraforms.JobOptions{})
+	require.NoError(t, err, "failed to delete active Jobs")
+
+	_, err = kubeClient.BatchV1().Jobs(ns).Get(ctx, name, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "should have deleted the Job but got %v", err)
+	_, err = kubeClient.CoreV1().Pods(ns).Get(ctx, name+"-abcde", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "should have deleted the Pod but got %v", err)
+}
+
+func TestDeleteActiveTerraformJobsWithJobLabel(t *testing.T) {
+	ctx := context.Background()
+	ns := "jx"
+	name := "tf-myrepo-pr456-myctx-1"
+	jobLabels := func(value string) map[string]string {
+		return map[string]string{"example.com/terraform": value}
+	}
+	kubeClient := fake.NewSimpleClientset(
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "apply-abcde", Namespace: ns, Labels: jobLabels(name)},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "apply-fghij", Namespace: ns, Labels: jobLabels("another")},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+			Status:     batchv1.JobStatus{Active: 1},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "apply-abcde-12345", Namespace: ns, Labels: map[string]string{"job-name": "apply-abcde"}},
+		},
+	)
+	opts := terraforms.JobOptions{JobLabel: "example.com/terraform"}
+
+	jobList, err := terraforms.ListTerraformJobsWithOptions(ctx, kubeClient, ns, name, opts)
+	require.NoError(t, err, "failed to list Jobs")
+	require.Len(t, jobList, 1, "Jobs")
+	assert.Equal(t, "apply-abcde", jobList[0].Name, "Job name")
+
+	count, err := terraforms.CountActiveTerraformJobs(ctx, kubeClient, ns, name, opts)
+	require.NoError(t, err, "failed to count active Jobs")
+	assert.Equal(t, 1, count, "active Jobs")
+
+	err = terraforms.DeleteActiveTerraformJobsWithOptions(ctx, kubeClient, ns, name, opts)
+	require.NoError(t, err, "failed to delete active Jobs")
+
+	_, err = kubeClient.BatchV1().Jobs(ns).Get(ctx, "apply-abcde", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "should have deleted the labelled Job but got %v", err)
+	_, err = kubeClient.CoreV1().Pods(ns).Get(ctx, "apply-abcde-12345", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "should have deleted the Pod of the labelled Job but got %v", err)
+
+	for _, jobName := range []string{"apply-fghij", name} {
+		_, err = kubeClient.BatchV1().Jobs(ns).Get(ctx, jobName, metav1.GetOptions{})
+		assert.NoError(t, err, "should not have deleted Job %s", jobName)
+	}
+}
+
+func TestDeleteActiveTerraformJobsGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	ns := "jx"
+	name := "tf-myrepo-pr456-myctx-1"
+
+	for _, gracePeriod := range []*int64{nil, int64Ptr(0), int64Ptr(30)} {
+		kubeClient := &deleteOptionsKubeClient{
+			Interface: fake.NewSimpleClientset(
+				&batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+					Status:     batchv1.JobStatus{Active: 1},
+				},
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: name + "-abcde", Namespace: ns, Labels: map[string]string{"job-name": name}},
+				},
+			),
+			deleteOptions: map[string]metav1.DeleteOptions{},
+		}
+
+		err := terraforms.DeleteActiveTerraformJobsWithOptions(ctx, kubeClient, ns, name, terraforms.JobOptions{GracePeriodSeconds: gracePeriod})
+		require.NoError(t, err, "failed to delete active Jobs")
+
+		require.Len(t, kubeClient.deleteOptions, 2, "delete calls")
+		for key, opts := range kubeClient.deleteOptions {
+			assert.Equal(t, gracePeriod, opts.GracePeriodSeconds, "GracePeriodSeconds when deleting %s", key)
+		}
+	}
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+// deleteOptionsKubeClient records the options passed when deleting Jobs and Pods as the fake client ignores them
+type deleteOptionsKubeClient struct {
+	kubernetes.Interface
+	deleteOptions map[string]metav1.DeleteOptions
+}
+
+func (c *deleteOptionsKubeClient) BatchV1() batchv1client.BatchV1Interface {
+	return &deleteOptionsBatchV1{BatchV1Interface: c.Interface.BatchV1(), client: c}
+}
+
+func (c *deleteOptionsKubeClient) CoreV1() corev1client.CoreV1Interface {
+	return &deleteOptionsCoreV1{CoreV1Interface: c.Interface.CoreV1(), client: c}
+}
+
+type deleteOptionsBatchV1 struct {
+	batchv1client.BatchV1Interface
+	client *deleteOptionsKubeClient
+}
+
+func (b *deleteOptionsBatchV1) Jobs(ns string) batchv1client.JobInterface {
+	return &deleteOptionsJobs{JobInterface: b.BatchV1Interface.Jobs(ns), client: b.client}
+}
+
+type deleteOptionsJobs struct {
+	batchv1client.JobInterface
+	client *deleteOptionsKubeClient
+}
+
+func (j *deleteOptionsJobs) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	j.client.deleteOptions["Job/"+name] = opts
+	return j.JobInterface.Delete(ctx, name, opts)
+}
+
+type deleteOptionsCoreV1 struct {
+	corev1client.CoreV1Interface
+	client *deleteOptionsKubeClient
+}
+
+func (c *deleteOptionsCoreV1) Pods(ns string) corev1client.PodInterface {
+	return &deleteOptionsPods{PodInterface: c.CoreV1Interface.Pods(ns), client: c.client}
+}
+
+type deleteOptionsPods struct {
+	corev1client.PodInterface
+	client *deleteOptionsKubeClient
+}
+
+func (p *deleteOptionsPods) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	p.client.deleteOptions["Pod/"+name] = opts
+	return p.PodInterface.Delete(ctx, name, opts)
+}
@@ -0,0 +1,61 @@
+package terraforms
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// IsKept returns true if the keep label or the keep annotation is set on the given labels or annotations to prevent
+// the resource being garbage collected.
+//
+// A value of false, no or 0 explicitly allows the resource to be garbage collected. A timestamp value, either
+// RFC3339 or a date of the form 2006-01-02 (which is valid as a label value), keeps the resource until that time.
+// Any other non empty value keeps the resource; if the value cannot be understood an error is returned too.
+func IsKept(labels, annotations map[string]string) (bool, error) {
+	return IsKeptWithKeys(labels, annotations, LabelKeep, AnnotationKeep)
+}
+
+// IsKeptWithKeys returns true if either the given keep label key or the keep annotation key is set using the same
+// values as IsKept. An empty key is ignored
+func IsKeptWithKeys(labels, annotations map[string]string, labelKey, annotationKey string) (bool, error) {
+	var labelKept, annotationKept bool
+	var labelErr, annotationErr error
+	if labelKey != "" {
+		labelKept, labelErr = isKeptValue("label", labelKey, labels[labelKey])
+	}
+	if annotationKey != "" {
+		annotationKept, annotationErr = isKeptValue("annotation", annotationKey, annotations[annotationKey])
+	}
+	err := labelErr
+	if err == nil {
+		err = annotationErr
+	}
+	return labelKept || annotationKept, err
+}
+
+// IsKeptWithLabel returns true if the given keep label key is set on the given labels using the same values as IsKept
+func IsKeptWithLabel(labels map[string]string, labelKey string) (bool, error) {
+	return isKeptValue("label", labelKey, labels[labelKey])
+}
+
+func isKeptValue(kind, key, value string) (bool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false, nil
+	}
+	switch strings.ToLower(value) {
+	case "false", "no", "0":
+		return false, nil
+	case "true", "yes", "1":
+		return true, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return time.Now().Before(t), nil
+		}
+	}
+	return true, errors.Errorf("could not parse %s %s value %s as a boolean or timestamp", key, kind, value)
+}
@@ -0,0 +1,88 @@
+package terraforms_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsKept(t *testing.T) {
+	now := time.Now()
+	future := now.Add(24 * time.Hour).UTC()
+	past := now.Add(-24 * time.Hour).UTC()
+
+	testCases := []struct {
+		value    string
+		expected bool
+		hasError bool
+	}{
+		{value: "", expected: false},
+		{value: "yes", expected: true},
+		{value: "true", expected: true},
+		{value: "TRUE", expected: true},
+		{value: "1", expected: true},
+		{value: "false", expected: false},
+		{value: "no", expected: false},
+		{value: "0", expected: false},
+		{value: future.Format(time.RFC3339), expected: true},
+		{value: past.Format(time.RFC3339), expected: false},
+		{value: future.AddDate(0, 0, 1).Format("2006-01-02"), expected: true},
+		{value: past.AddDate(0, 0, -1).Format("2006-01-02"), expected: false},
+		{value: "whatever", expected: true, hasError: true},
+	}
+
+	for _, tc := range testCases {
+		labels := map[string]string{}
+		if tc.value != "" {
+			labels[terraforms.LabelKeep] = tc.value
+		}
+		got, err := terraforms.IsKept(labels, nil)
+		if tc.hasError {
+			assert.Error(t, err, "for keep value %q", tc.value)
+		} else {
+			assert.NoError(t, err, "for keep value %q", tc.value)
+		}
+		assert.Equal(t, tc.expected, got, "for keep value %q", tc.value)
+	}
+
+	got, err := terraforms.IsKept(nil, nil)
+	assert.NoError(t, err, "for nil labels")
+	assert.False(t, got, "for nil labels")
+}
+
+func TestIsKeptWithKeys(t *testing.T) {
+	testCases := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expected    bool
+	}{
+		{name: "none", expected: false},
+		{name: "label only", labels: map[string]string{terraforms.LabelKeep: "yes"}, expected: true},
+		{name: "annotation only", annotations: map[string]string{terraforms.AnnotationKeep: "true"}, expected: true},
+		{name: "both", labels: map[string]string{terraforms.LabelKeep: "yes"}, annotations: map[string]string{terraforms.AnnotationKeep: "true"}, expected: true},
+		{name: "label false annotation true", labels: map[string]string{terraforms.LabelKeep: "false"}, annotations: map[string]string{terraforms.AnnotationKeep: "true"}, expected: true},
+		{name: "both false", labels: map[string]string{terraforms.LabelKeep: "no"}, annotations: map[string]string{terraforms.AnnotationKeep: "0"}, expected: false},
+		{name: "annotation as label", labels: map[string]string{terraforms.AnnotationKeep: "yes"}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		got, err := terraforms.IsKept(tc.labels, tc.annotations)
+		assert.NoError(t, err, "for %s", tc.name)
+		assert.Equal(t, tc.expected, got, "for %s", tc.name)
+	}
+
+	got, err := terraforms.IsKeptWithKeys(nil, map[string]string{"my/keep": "yes"}, terraforms.LabelKeep, "my/keep")
+	assert.NoError(t, err, "for a custom annotation")
+	assert.True(t, got, "for a custom annotation")
+
+	got, err = terraforms.IsKeptWithKeys(nil, map[string]string{terraforms.AnnotationKeep: "yes"}, terraforms.LabelKeep, "")
+	assert.NoError(t, err, "for a disabled annotation")
+	assert.False(t, got, "for a disabled annotation")
+
+	got, err = terraforms.IsKept(nil, map[string]string{terraforms.AnnotationKeep: "whatever"})
+	assert.Error(t, err, "for an invalid annotation value")
+	assert.True(t, got, "for an invalid annotation value")
+}
@@ -0,0 +1,58 @@
+package terraforms
+
+import (
+	"context"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+// Log the logging methods used when acting on a Terraform resource
+type Log interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+type logPrefixKey struct{}
+
+// WithLogPrefix returns a context whose Logger prefixes each line with the given prefix such as the name of the
+// resource being deleted so that the output of resources deleted concurrently can be told apart
+func WithLogPrefix(ctx context.Context, prefix string) context.Context {
+	return context.WithValue(ctx, logPrefixKey{}, prefix)
+}
+
+// Logger returns the logger to use for the given context which prefixes each line if WithLogPrefix was used
+func Logger(ctx context.Context) Log {
+	prefix, _ := ctx.Value(logPrefixKey{}).(string)
+	if prefix == "" {
+		return log.Logger()
+	}
+	return &prefixLogger{prefix: prefix}
+}
+
+// prefixLogger prefixes each line with the prefix. The logger is resolved on each call so that any change to the
+// log output such as by log.CaptureOutput is used
+type prefixLogger struct {
+	prefix string
+}
+
+func (l *prefixLogger) args(args []interface{}) []interface{} {
+	return append([]interface{}{l.prefix}, args...)
+}
+
+func (l *prefixLogger) Debugf(format string, args ...interface{}) {
+	log.Logger().Debugf("%s: "+format, l.args(args)...)
+}
+
+func (l *prefixLogger) Infof(format string, args ...interface{}) {
+	log.Logger().Infof("%s: "+format, l.args(args)...)
+}
+
+func (l *prefixLogger) Warnf(format string, args ...interface{}) {
+	log.Logger().Warnf("%s: "+format, l.args(args)...)
+}
+
+func (l *prefixLogger) Errorf(format string, args ...interface{}) {
+	log.Logger().Errorf("%s: "+format, l.args(args)...)
+}
@@ -0,0 +1,64 @@
+package terraforms
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ListOrphanedTerraformJobs lists the Jobs in the namespace created by the Terraform Operator whose owning Terraform
+// resource no longer exists. This can happen if a Terraform resource is deleted with the orphan propagation policy
+func ListOrphanedTerraformJobs(ctx context.Context, kubeClient kubernetes.Interface, dynClient dynamic.Interface, ns string) ([]batchv1.Job, error) {
+	jobList, err := kubeClient.BatchV1().Jobs(ns).List(ctx, metav1.ListOptions{})
+	if err != nil && apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list Jobs in namespace %s", ns)
+	}
+
+	var answer []batchv1.Job
+	exists := map[string]bool{}
+	for i := range jobList.Items {
+		job := &jobList.Items[i]
+		name := TerraformOwnerName(job.OwnerReferences)
+		if name == "" {
+			continue
+		}
+		key := job.Namespace + "/" + name
+		found, ok := exists[key]
+		if !ok {
+			_, err = dynClient.Resource(TerraformResource).Namespace(job.Namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return nil, errors.Wrapf(err, "failed to query Terraform %s in namespace %s", name, job.Namespace)
+			}
+			found = err == nil
+			exists[key] = found
+		}
+		if !found {
+			answer = append(answer, *job)
+		}
+	}
+	return answer, nil
+}
+
+// TerraformOwnerName returns the name of the Terraform resource in the owner references or an empty string
+// if the owner references do not include a Terraform resource
+func TerraformOwnerName(refs []metav1.OwnerReference) string {
+	for _, ref := range refs {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+		if gv.Group == TerraformResource.Group && ref.Kind == "Terraform" {
+			return ref.Name
+		}
+	}
+	return ""
+}
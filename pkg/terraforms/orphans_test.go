@@ -0,0 +1,55 @@
+package terraforms_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestListOrphanedTerraformJobs(t *testing.T) {
+	ctx := context.Background()
+	ns := "jx"
+
+	dynObjects := tftests.ParseUnstructureds(t, nil, []string{`apiVersion: tf.isaaguilar.com/v1alpha1
+kind: Terraform
+metadata:
+  name: tf-exists
+  namespace: jx
+`})
+	dynClient := tftests.NewFakeDynClient(runtime.NewScheme(), dynObjects...)
+
+	kubeClient := fake.NewSimpleClientset(
+		terraformJob(ns, "tf-exists"),
+		terraformJob(ns, "tf-missing"),
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "not-terraform", Namespace: ns}},
+	)
+
+	jobList, err := terraforms.ListOrphanedTerraformJobs(ctx, kubeClient, dynClient, ns)
+	require.NoError(t, err, "failed to list orphaned Jobs")
+	require.Len(t, jobList, 1, "orphaned Jobs")
+	assert.Equal(t, "tf-missing", jobList[0].Name, "orphaned Job")
+}
+
+func terraformJob(ns, name string) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "tf.isaaguilar.com/v1alpha1",
+					Kind:       "Terraform",
+					Name:       name,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,106 @@
+package terraforms
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DeleteOwnedResources deletes the Secrets, ConfigMaps and PersistentVolumeClaims labelled with the LabelTerraform
+// label for the given Terraform resource name
+func DeleteOwnedResources(ctx context.Context, kubeClient kubernetes.Interface, ns, name string) error {
+	return DeleteOwnedResourcesWithLabel(ctx, kubeClient, ns, LabelTerraform, name)
+}
+
+// DeleteOwnedResourcesWithLabel deletes the Secrets, ConfigMaps and PersistentVolumeClaims with the given label key
+// whose value is the given Terraform resource name
+func DeleteOwnedResourcesWithLabel(ctx context.Context, kubeClient kubernetes.Interface, ns, labelKey, name string) error {
+	selector := labelKey + "=" + name
+	listOptions := metav1.ListOptions{
+		LabelSelector: selector,
+	}
+
+	secretInterface := kubeClient.CoreV1().Secrets(ns)
+	secretList, err := secretInterface.List(ctx, listOptions)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to list Secrets in namespace %s with selector %s", ns, selector)
+	}
+	if secretList != nil {
+		for i := range secretList.Items {
+			err = deleteOwned(ctx, "Secret", ns, secretList.Items[i].Name, secretInterface.Delete)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	configMapInterface := kubeClient.CoreV1().ConfigMaps(ns)
+	configMapList, err := configMapInterface.List(ctx, listOptions)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to list ConfigMaps in namespace %s with selector %s", ns, selector)
+	}
+	if configMapList != nil {
+		for i := range configMapList.Items {
+			err = deleteOwned(ctx, "ConfigMap", ns, configMapList.Items[i].Name, configMapInterface.Delete)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	pvcInterface := kubeClient.CoreV1().PersistentVolumeClaims(ns)
+	pvcList, err := pvcInterface.List(ctx, listOptions)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to list PersistentVolumeClaims in namespace %s with selector %s", ns, selector)
+	}
+	if pvcList != nil {
+		for i := range pvcList.Items {
+			err = deleteOwned(ctx, "PersistentVolumeClaim", ns, pvcList.Items[i].Name, pvcInterface.Delete)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ListOwnedNamespaces returns the sorted names of the namespaces which have an owner reference to the resource
+// with the given UID
+func ListOwnedNamespaces(ctx context.Context, kubeClient kubernetes.Interface, uid types.UID) ([]string, error) {
+	if uid == "" {
+		return nil, nil
+	}
+	list, err := kubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list namespaces")
+	}
+	var answer []string
+	for i := range list.Items {
+		ns := &list.Items[i]
+		for _, ref := range ns.OwnerReferences {
+			if ref.UID == uid {
+				answer = append(answer, ns.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(answer)
+	return answer, nil
+}
+
+func deleteOwned(ctx context.Context, kind, ns, name string, deleteFn func(context.Context, string, metav1.DeleteOptions) error) error {
+	err := deleteFn(ctx, name, metav1.DeleteOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to delete %s %s in namespace %s", kind, name, ns)
+	}
+	Logger(ctx).Infof("deleted owned %s %s in namespace %s", kind, info(name), ns)
+	return nil
+}
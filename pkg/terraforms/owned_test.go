@@ -0,0 +1,95 @@
+package terraforms_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeleteOwnedResources(t *testing.T) {
+	ctx := context.Background()
+	ns := "jx"
+	name := "tf-myrepo-pr456-myctx-1"
+
+	owned := map[string]string{terraforms.LabelTerraform: name}
+	other := map[string]string{terraforms.LabelTerraform: "tf-myrepo-pr999-myctx-3"}
+
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "owned-secret", Namespace: ns, Labels: owned}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other-secret", Namespace: ns, Labels: other}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owned-cm", Namespace: ns, Labels: owned}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unlabelled-cm", Namespace: ns}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "owned-pvc", Namespace: ns, Labels: owned}},
+	)
+
+	err := terraforms.DeleteOwnedResources(ctx, kubeClient, ns, name)
+	require.NoError(t, err, "failed to delete owned resources")
+
+	secrets, err := kubeClient.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Secrets")
+	require.Len(t, secrets.Items, 1, "remaining Secrets")
+	assert.Equal(t, "other-secret", secrets.Items[0].Name, "remaining Secret")
+
+	configMaps, err := kubeClient.CoreV1().ConfigMaps(ns).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err, "failed to list ConfigMaps")
+	require.Len(t, configMaps.Items, 1, "remaining ConfigMaps")
+	assert.Equal(t, "unlabelled-cm", configMaps.Items[0].Name, "remaining ConfigMap")
+
+	pvcs, err := kubeClient.CoreV1().PersistentVolumeClaims(ns).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err, "failed to list PersistentVolumeClaims")
+	assert.Empty(t, pvcs.Items, "remaining PersistentVolumeClaims")
+}
+
+func TestDeleteOwnedResourcesWithLabel(t *testing.T) {
+	ctx := context.Background()
+	ns := "jx"
+	name := "tf-myrepo-pr456-myctx-1"
+
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "owned-secret", Namespace: ns, Labels: map[string]string{"tf-owner": name}}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "default-label-secret", Namespace: ns, Labels: map[string]string{terraforms.LabelTerraform: name}}},
+	)
+
+	err := terraforms.DeleteOwnedResourcesWithLabel(ctx, kubeClient, ns, "tf-owner", name)
+	require.NoError(t, err, "failed to delete owned resources")
+
+	secrets, err := kubeClient.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err, "failed to list Secrets")
+	require.Len(t, secrets.Items, 1, "remaining Secrets")
+	assert.Equal(t, "default-label-secret", secrets.Items[0].Name, "remaining Secret")
+}
+
+func TestListOwnedNamespaces(t *testing.T) {
+	ctx := context.Background()
+	uid := types.UID("tf-uid")
+	ownedBy := func(name string, uid types.UID) *corev1.Namespace {
+		return &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Terraform", Name: "tf-myrepo-pr456-myctx-1", UID: uid}},
+			},
+		}
+	}
+
+	kubeClient := fake.NewSimpleClientset(
+		ownedBy("owned-b", uid),
+		ownedBy("owned-a", uid),
+		ownedBy("other", "other-uid"),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unowned"}},
+	)
+
+	namespaces, err := terraforms.ListOwnedNamespaces(ctx, kubeClient, uid)
+	require.NoError(t, err, "failed to list owned namespaces")
+	assert.Equal(t, []string{"owned-a", "owned-b"}, namespaces, "owned namespaces")
+
+	namespaces, err = terraforms.ListOwnedNamespaces(ctx, kubeClient, "")
+	require.NoError(t, err, "failed to list owned namespaces")
+	assert.Empty(t, namespaces, "owned namespaces for an empty UID")
+}
@@ -0,0 +1,128 @@
+package terraforms
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DestroyPlanner summarises the cloud resources which would be destroyed if a Terraform resource is deleted
+type DestroyPlanner interface {
+	// PlanDestroy returns the resources which would be destroyed for the given Terraform resource or nil if
+	// there is no plan available
+	PlanDestroy(ctx context.Context, ns, name string) (*DestroyPlan, error)
+}
+
+// DestroyPlan the cloud resources which would be destroyed
+type DestroyPlan struct {
+	// Resources the number of resources of each Terraform resource type such as google_container_cluster
+	Resources map[string]int
+}
+
+// Total returns the total number of resources which would be destroyed
+func (p *DestroyPlan) Total() int {
+	total := 0
+	for _, count := range p.Resources {
+		total += count
+	}
+	return total
+}
+
+// String returns a summary of the resource counts ordered by resource type
+func (p *DestroyPlan) String() string {
+	var types []string
+	for k := range p.Resources {
+		types = append(types, k)
+	}
+	sort.Strings(types)
+	var parts []string
+	for _, k := range types {
+		parts = append(parts, fmt.Sprintf("%s: %d", k, p.Resources[k]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// StatePlanner reads the Terraform state stored in a Secret by the kubernetes backend to find the resources
+// which would be destroyed. The Secret is named tfstate-{workspace}-{name}-state to match the secret_suffix
+// of the customBackend in the Terraform resource
+type StatePlanner struct {
+	KubeClient kubernetes.Interface
+	// Workspace the Terraform workspace which defaults to default
+	Workspace string
+}
+
+// SecretName returns the name of the Secret containing the Terraform state for the given Terraform resource
+func (p *StatePlanner) SecretName(name string) string {
+	workspace := p.Workspace
+	if workspace == "" {
+		workspace = "default"
+	}
+	return fmt.Sprintf("tfstate-%s-%s-state", workspace, name)
+}
+
+// PlanDestroy returns the managed resources in the stored Terraform state or nil if there is no state
+func (p *StatePlanner) PlanDestroy(ctx context.Context, ns, name string) (*DestroyPlan, error) {
+	secretName := p.SecretName(name)
+	secret, err := p.KubeClient.CoreV1().Secrets(ns).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get Secret %s in namespace %s", secretName, ns)
+	}
+	data := secret.Data["tfstate"]
+	if len(data) == 0 {
+		return nil, nil
+	}
+	plan, err := ParseStatePlan(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the Terraform state in Secret %s in namespace %s", secretName, ns)
+	}
+	return plan, nil
+}
+
+// terraformState the parts of the Terraform state file needed to count the managed resources
+type terraformState struct {
+	Resources []struct {
+		Mode      string            `json:"mode"`
+		Type      string            `json:"type"`
+		Instances []json.RawMessage `json:"instances"`
+	} `json:"resources"`
+}
+
+// ParseStatePlan parses the optionally gzipped Terraform state returning the managed resources it contains
+func ParseStatePlan(data []byte) (*DestroyPlan, error) {
+	if len(data) > 1 && data[0] == 0x1f && data[1] == 0x8b {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read gzipped state")
+		}
+		data, err = ioutil.ReadAll(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decompress state")
+		}
+	}
+	state := &terraformState{}
+	err := json.Unmarshal(data, state)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal state")
+	}
+	plan := &DestroyPlan{Resources: map[string]int{}}
+	for _, r := range state.Resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		plan.Resources[r.Type] += len(r.Instances)
+	}
+	return plan, nil
+}
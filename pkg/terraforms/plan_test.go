@@ -0,0 +1,61 @@
+package terraforms_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testState = `{
+  "version": 4,
+  "resources": [
+    {"mode": "data", "type": "google_client_config", "name": "default", "instances": [{}]},
+    {"mode": "managed", "type": "google_container_cluster", "name": "cluster", "instances": [{}]},
+    {"mode": "managed", "type": "google_service_account", "name": "sa", "instances": [{}, {}, {}]}
+  ]
+}`
+
+func TestStatePlanner(t *testing.T) {
+	ctx := context.Background()
+	ns := "jx"
+
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	_, err := w.Write([]byte(testState))
+	require.NoError(t, err, "failed to gzip state")
+	require.NoError(t, w.Close(), "failed to close gzip writer")
+
+	kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tfstate-default-tf-myrepo-pr456-myctx-1-state", Namespace: ns},
+		Data:       map[string][]byte{"tfstate": buf.Bytes()},
+	})
+	planner := &terraforms.StatePlanner{KubeClient: kubeClient}
+
+	plan, err := planner.PlanDestroy(ctx, ns, "tf-myrepo-pr456-myctx-1")
+	require.NoError(t, err, "failed to plan destroy")
+	require.NotNil(t, plan, "should have found a plan")
+	assert.Equal(t, map[string]int{"google_container_cluster": 1, "google_service_account": 3}, plan.Resources, "resources")
+	assert.Equal(t, 4, plan.Total(), "total")
+	assert.Equal(t, "google_container_cluster: 1, google_service_account: 3", plan.String(), "summary")
+
+	plan, err = planner.PlanDestroy(ctx, ns, "does-not-exist")
+	require.NoError(t, err, "failed to plan destroy for a resource without state")
+	assert.Nil(t, plan, "should not have a plan without state")
+}
+
+func TestParseStatePlanUncompressed(t *testing.T) {
+	plan, err := terraforms.ParseStatePlan([]byte(testState))
+	require.NoError(t, err, "failed to parse state")
+	assert.Equal(t, 4, plan.Total(), "total")
+
+	_, err = terraforms.ParseStatePlan([]byte("not json"))
+	require.Error(t, err, "should fail to parse invalid state")
+}
@@ -0,0 +1,88 @@
+package terraforms
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// PipelineRunResource the Tekton PipelineRuns which can reference the Terraform resources they consume
+var PipelineRunResource = schema.GroupVersionResource{
+	Group:    "tekton.dev",
+	Version:  "v1beta1",
+	Resource: "pipelineruns",
+}
+
+// ReferenceChecker finds the active resources, such as running Tekton PipelineRuns, which reference a Terraform
+// resource so that it is not deleted while it is still in use
+type ReferenceChecker interface {
+	// ActiveReferences returns the sorted names of the active resources referencing the given Terraform resource
+	ActiveReferences(ctx context.Context, ns, name string) ([]string, error)
+}
+
+// LabelReferenceChecker finds the resources in the same namespace which reference a Terraform resource via a label
+// whose value is the name of the Terraform resource. A resource is active until it has a completionTime or a
+// Succeeded condition which is True or False as used by Tekton
+type LabelReferenceChecker struct {
+	DynamicClient dynamic.Interface
+	// Resource the resource which references the Terraform resources which defaults to PipelineRunResource
+	Resource schema.GroupVersionResource
+	// Label the label key on the referencing resources which defaults to LabelTerraform
+	Label string
+}
+
+// ActiveReferences returns the names of the active resources with the label referencing the Terraform resource.
+// If the referencing resource is not installed in the cluster there are no references
+func (c *LabelReferenceChecker) ActiveReferences(ctx context.Context, ns, name string) ([]string, error) {
+	gvr := c.Resource
+	if gvr.Resource == "" {
+		gvr = PipelineRunResource
+	}
+	label := c.Label
+	if label == "" {
+		label = LabelTerraform
+	}
+	list, err := c.DynamicClient.Resource(gvr).Namespace(ns).List(ctx, metav1.ListOptions{
+		LabelSelector: label + "=" + name,
+	})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to list %s in namespace %s with label %s=%s", gvr.Resource, ns, label, name)
+	}
+	var answer []string
+	for i := range list.Items {
+		r := &list.Items[i]
+		if IsActive(r) {
+			answer = append(answer, r.GetName())
+		}
+	}
+	sort.Strings(answer)
+	return answer, nil
+}
+
+// IsActive returns true if the resource, such as a Tekton PipelineRun, has not completed yet
+func IsActive(r *unstructured.Unstructured) bool {
+	completionTime, _, _ := unstructured.NestedString(r.Object, "status", "completionTime")
+	if completionTime != "" {
+		return false
+	}
+	conditions, _, _ := unstructured.NestedSlice(r.Object, "status", "conditions")
+	for _, c := range conditions {
+		m, ok := c.(map[string]interface{})
+		if !ok || m["type"] != "Succeeded" {
+			continue
+		}
+		if m["status"] == "True" || m["status"] == "False" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package terraforms_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms/tftests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var pipelineRuns = []string{
+	`apiVersion: tekton.dev/v1beta1
+kind: PipelineRun
+metadata:
+  labels:
+    terraform: tf-myrepo-pr456-myctx-1
+  name: running
+  namespace: jx
+status:
+  conditions:
+  - type: Succeeded
+    status: Unknown
+`,
+	`apiVersion: tekton.dev/v1beta1
+kind: PipelineRun
+metadata:
+  labels:
+    terraform: tf-myrepo-pr456-myctx-1
+  name: pending
+  namespace: jx
+`,
+	`apiVersion: tekton.dev/v1beta1
+kind: PipelineRun
+metadata:
+  labels:
+    terraform: tf-myrepo-pr456-myctx-1
+  name: succeeded
+  namespace: jx
+status:
+  completionTime: "2024-01-02T15:04:05Z"
+  conditions:
+  - type: Succeeded
+    status: "True"
+`,
+	`apiVersion: tekton.dev/v1beta1
+kind: PipelineRun
+metadata:
+  labels:
+    terraform: tf-myrepo-pr456-myctx-1
+  name: failed
+  namespace: jx
+status:
+  conditions:
+  - type: Succeeded
+    status: "False"
+`,
+	`apiVersion: tekton.dev/v1beta1
+kind: PipelineRun
+metadata:
+  labels:
+    terraform: tf-myrepo-pr999-myctx-3
+  name: other
+  namespace: jx
+`,
+}
+
+func TestLabelReferenceChecker(t *testing.T) {
+	ctx := context.Background()
+	fn := func(idx int, u *unstructured.Unstructured) {}
+	dynClient := tftests.NewFakeDynClient(runtime.NewScheme(), tftests.ParseUnstructureds(t, fn, pipelineRuns)...)
+
+	checker := &terraforms.LabelReferenceChecker{DynamicClient: dynClient}
+
+	references, err := checker.ActiveReferences(ctx, "jx", "tf-myrepo-pr456-myctx-1")
+	require.NoError(t, err, "failed to find references")
+	assert.Equal(t, []string{"pending", "running"}, references, "active references")
+
+	references, err = checker.ActiveReferences(ctx, "jx", "tf-myrepo-pr456-myctx-2")
+	require.NoError(t, err, "failed to find references")
+	assert.Empty(t, references, "active references for an unreferenced resource")
+
+	checker.Label = "tf"
+	references, err = checker.ActiveReferences(ctx, "jx", "tf-myrepo-pr456-myctx-1")
+	require.NoError(t, err, "failed to find references")
+	assert.Empty(t, references, "active references with a different label")
+}
@@ -31,7 +31,8 @@ func ParseUnstructureds(t *testing.T, fn func(idx int, u *unstructured.Unstructu
 // NewFakeDynClient creates a new dynamic client with the external secrets
 func NewFakeDynClient(scheme *runtime.Scheme, dynObjects ...runtime.Object) *dynfake.FakeDynamicClient {
 	gvrToListKind := map[schema.GroupVersionResource]string{
-		terraforms.TerraformResource: "TerraformList",
+		terraforms.TerraformResource:   "TerraformList",
+		terraforms.PipelineRunResource: "PipelineRunList",
 	}
 	return dynfake.NewSimpleDynamicClientWithCustomListKinds(scheme, gvrToListKind, dynObjects...)
 }
@@ -0,0 +1,62 @@
+package terraforms
+
+import (
+	"context"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/kube/jobs"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// JobPollInterval the interval between checks of whether a Terraform Job has finished
+var JobPollInterval = 5 * time.Second
+
+// WaitForActiveTerraformJobs waits for any active Terraform Job for the given Terraform resource to finish.
+// If the Job has not finished within the timeout an error is returned for which IsWaitTimeout returns true
+func WaitForActiveTerraformJobs(ctx context.Context, kubeClient kubernetes.Interface, ns, name string, timeout time.Duration) error {
+	return WaitForActiveTerraformJobsWithOptions(ctx, kubeClient, ns, name, timeout, JobOptions{})
+}
+
+// WaitForActiveTerraformJobsWithOptions waits for all of the active Terraform Jobs for the given Terraform resource,
+// found using the JobOptions.JobLabel label if specified, to finish. If any Job has not finished within the timeout
+// an error is returned for which IsWaitTimeout returns true. If the given context is done first its error is returned
+// instead so that it is not mistaken for the Job not finishing in time
+func WaitForActiveTerraformJobsWithOptions(ctx context.Context, kubeClient kubernetes.Interface, ns, name string, timeout time.Duration, opts JobOptions) error {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	logged := map[string]bool{}
+	err := wait.PollImmediateUntilWithContext(waitCtx, JobPollInterval, func(ctx context.Context) (bool, error) {
+		jobList, err := ListTerraformJobsWithOptions(ctx, kubeClient, ns, name, opts)
+		if err != nil {
+			return false, err
+		}
+		finished := true
+		for i := range jobList {
+			job := &jobList[i]
+			if jobs.IsJobFinished(job) {
+				continue
+			}
+			finished = false
+			if !logged[job.Name] {
+				Logger(ctx).Infof("waiting for terraform Job %s in namespace %s to finish", info(job.Name), ns)
+				logged[job.Name] = true
+			}
+		}
+		return finished, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		if ctx.Err() != nil {
+			return errors.Wrapf(ctx.Err(), "stopped waiting for the Jobs of %s in namespace %s", name, ns)
+		}
+		return errors.Wrapf(err, "the Jobs of %s in namespace %s did not finish within %s", name, ns, timeout.String())
+	}
+	return err
+}
+
+// IsWaitTimeout returns true if the error is due to a Terraform Job not finishing in time
+func IsWaitTimeout(err error) bool {
+	return errors.Cause(err) == wait.ErrWaitTimeout
+}
@@ -0,0 +1,137 @@
+package terraforms_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x-plugins/jx-test/pkg/terraforms"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestWaitForActiveTerraformJobs(t *testing.T) {
+	terraforms.JobPollInterval = time.Millisecond
+
+	ctx := context.Background()
+	ns := "jx"
+	name := "tf-myrepo-pr456-myctx-1"
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		Status: batchv1.JobStatus{
+			Active: 1,
+		},
+	}
+	kubeClient := fake.NewSimpleClientset(job)
+
+	// lets complete the job after a few polls
+	polls := 0
+	kubeClient.PrependReactor("get", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		polls++
+		if polls < 3 {
+			return false, nil, nil
+		}
+		completed := job.DeepCopy()
+		completed.Status.Active = 0
+		completed.Status.Succeeded = 1
+		completed.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		completed.Status.Conditions = []batchv1.JobCondition{
+			{
+				Type:   batchv1.JobComplete,
+				Status: corev1.ConditionTrue,
+			},
+		}
+		return true, completed, nil
+	})
+
+	err := terraforms.WaitForActiveTerraformJobs(ctx, kubeClient, ns, name, time.Minute)
+	require.NoError(t, err, "failed to wait for Job")
+	assert.Equal(t, 3, polls, "number of polls")
+}
+
+func TestWaitForActiveTerraformJobsTimeout(t *testing.T) {
+	terraforms.JobPollInterval = time.Millisecond
+
+	ns := "jx"
+	name := "tf-myrepo-pr456-myctx-1"
+
+	kubeClient := fake.NewSimpleClientset(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		Status: batchv1.JobStatus{
+			Active: 1,
+		},
+	})
+
+	err := terraforms.WaitForActiveTerraformJobs(context.Background(), kubeClient, ns, name, 20*time.Millisecond)
+	require.Error(t, err, "should have timed out")
+	assert.True(t, terraforms.IsWaitTimeout(err), "should be a timeout error but got %s", err.Error())
+}
+
+func TestWaitForActiveTerraformJobsParentContextDone(t *testing.T) {
+	terraforms.JobPollInterval = time.Millisecond
+
+	ns := "jx"
+	name := "tf-myrepo-pr456-myctx-1"
+
+	kubeClient := fake.NewSimpleClientset(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		Status: batchv1.JobStatus{
+			Active: 1,
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := terraforms.WaitForActiveTerraformJobs(ctx, kubeClient, ns, name, time.Minute)
+	require.Error(t, err, "should stop waiting when the parent context is done")
+	assert.False(t, terraforms.IsWaitTimeout(err), "should not be a timeout error but got %s", err.Error())
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "should be a deadline exceeded error but got %s", err.Error())
+}
+
+func TestWaitForActiveTerraformJobsWithJobLabel(t *testing.T) {
+	terraforms.JobPollInterval = time.Millisecond
+
+	ns := "jx"
+	name := "tf-myrepo-pr456-myctx-1"
+
+	// the active Job is not named after the resource so is only found by its label
+	kubeClient := fake.NewSimpleClientset(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tf-apply-abc",
+			Namespace: ns,
+			Labels:    map[string]string{"terraform": name},
+		},
+		Status: batchv1.JobStatus{
+			Active: 1,
+		},
+	})
+
+	err := terraforms.WaitForActiveTerraformJobs(context.Background(), kubeClient, ns, name, 20*time.Millisecond)
+	require.NoError(t, err, "should not find the Job without the label")
+
+	err = terraforms.WaitForActiveTerraformJobsWithOptions(context.Background(), kubeClient, ns, name, 20*time.Millisecond, terraforms.JobOptions{JobLabel: "terraform"})
+	require.Error(t, err, "should have timed out waiting for the labelled Job")
+	assert.True(t, terraforms.IsWaitTimeout(err), "should be a timeout error but got %s", err.Error())
+}
+
+func TestWaitForActiveTerraformJobsMissingJob(t *testing.T) {
+	err := terraforms.WaitForActiveTerraformJobs(context.Background(), fake.NewSimpleClientset(), "jx", "missing", time.Second)
+	require.NoError(t, err, "should not wait if there is no Job")
+}